@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"io"
 	"os"
 	"os/signal"
@@ -9,8 +10,16 @@ import (
 	"log/slog"
 
 	"github.com/GintGld/fizteh-radio/internal/app"
+	"github.com/GintGld/fizteh-radio/internal/client/backend"
+	"github.com/GintGld/fizteh-radio/internal/client/s3"
+	client "github.com/GintGld/fizteh-radio/internal/client/storage"
 	"github.com/GintGld/fizteh-radio/internal/config"
+	"github.com/GintGld/fizteh-radio/internal/lib/logger/sl"
 	"github.com/GintGld/fizteh-radio/internal/lib/logger/slogpretty"
+	"github.com/GintGld/fizteh-radio/internal/lib/oidc"
+	"github.com/GintGld/fizteh-radio/internal/lib/throttle"
+	"github.com/GintGld/fizteh-radio/internal/lib/tracing"
+	"github.com/GintGld/fizteh-radio/internal/models"
 )
 
 const (
@@ -26,21 +35,94 @@ func main() {
 	log.Info("starting radio", slog.String("env", cfg.Env))
 	log.Debug("debug messages are enabled")
 
+	shutdownTracing, err := tracing.Setup(
+		context.Background(),
+		cfg.Tracing.Exporter,
+		cfg.Tracing.Endpoint,
+		cfg.Tracing.Insecure,
+		cfg.Tracing.SampleRatio,
+	)
+	if err != nil {
+		panic("failed to setup tracing. Error: " + err.Error())
+	}
+
+	// Background jobs are stopped on shutdown.
+	ctx, cancel := context.WithCancel(context.Background())
+
 	// TODO: send timeout and iddletimeout
 	httpApplication := app.New(
+		ctx,
 		log,
 		cfg.HttpServer.Address,
+		cfg.HttpServer.MetricsAddress,
 		cfg.StoragePath,
 		cfg.HttpServer.Timeout,
 		cfg.HttpServer.IddleTimeout,
+		cfg.HttpServer.ProxyHeader,
 		cfg.TokenTTL,
+		cfg.RefreshTokenTTL,
+		throttle.Policy{
+			FreeAttempts:    cfg.LoginThrottle.FreeAttempts,
+			BaseDelay:       cfg.LoginThrottle.BaseDelay,
+			MaxDelay:        cfg.LoginThrottle.MaxDelay,
+			LockoutAttempts: cfg.LoginThrottle.LockoutAttempts,
+			Lockout:         cfg.LoginThrottle.Lockout,
+		},
+		throttle.Policy{
+			FreeAttempts:    cfg.LoginThrottle.IPFreeAttempts,
+			BaseDelay:       cfg.LoginThrottle.BaseDelay,
+			MaxDelay:        cfg.LoginThrottle.MaxDelay,
+			LockoutAttempts: cfg.LoginThrottle.IPLockoutAttempts,
+			Lockout:         cfg.LoginThrottle.Lockout,
+		},
+		throttle.Policy{
+			FreeAttempts:    cfg.LoginThrottle.AccountFreeAttempts,
+			BaseDelay:       cfg.LoginThrottle.BaseDelay,
+			MaxDelay:        cfg.LoginThrottle.MaxDelay,
+			LockoutAttempts: cfg.LoginThrottle.AccountLockoutAttempts,
+			Lockout:         cfg.LoginThrottle.Lockout,
+		},
+		oidc.Config{
+			Issuer:       cfg.OIDC.Issuer,
+			ClientID:     cfg.OIDC.ClientID,
+			ClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+			RedirectURL:  cfg.OIDC.RedirectURL,
+			Scopes:       cfg.OIDC.Scopes,
+		},
+		cfg.OIDC.LoginClaim,
+		cfg.OIDC.AutoProvision,
+		roles(cfg.OIDC.DefaultRoles),
 		getSecret(),
 		getRootPass(),
 		cfg.HttpServer.MaxAnswerLength,
 		cfg.HttpServer.TmpDir,
-		cfg.Source.Addr,
-		cfg.Source.Timeout,
-		cfg.Source.RetryCount,
+		cfg.HttpServer.BodyLimitMB<<20,
+		cfg.Upload.TTL,
+		cfg.Upload.MaxSizeMB<<20,
+		backend.Config{
+			Type:       cfg.Source.Type,
+			Addr:       cfg.Source.Addr,
+			Timeout:    cfg.Source.Timeout,
+			RetryCount: cfg.Source.RetryCount,
+			TLS: client.TLS{
+				Enabled:    cfg.Source.TLS.Enabled,
+				CAFile:     cfg.Source.TLS.CAFile,
+				CertFile:   cfg.Source.TLS.CertFile,
+				KeyFile:    cfg.Source.TLS.KeyFile,
+				ServerName: cfg.Source.TLS.ServerName,
+			},
+			Token: os.Getenv("SOURCE_STORAGE_TOKEN"),
+			Dir:   cfg.Source.Path,
+			S3: s3.Config{
+				Endpoint:  cfg.Source.S3.Endpoint,
+				Region:    cfg.Source.S3.Region,
+				Bucket:    cfg.Source.S3.Bucket,
+				AccessKey: cfg.Source.S3.AccessKey,
+				SecretKey: os.Getenv("S3_SECRET_KEY"),
+			},
+		},
+		cfg.Reconcile.Interval,
+		cfg.Reconcile.Timeout,
 		cfg.Dash.ManifestPath,
 		cfg.Dash.ContentDir,
 		cfg.Dash.ChunkLength,
@@ -49,6 +131,10 @@ func main() {
 		cfg.Dash.ClientUpdateFreq,
 		cfg.Dash.DashUpdateFreq,
 		cfg.Dash.DashHorizon,
+		cfg.Dash.Prefetch,
+		cfg.Dash.CacheSizeMB<<20,
+		cfg.Dash.DeadAirWindow,
+		cfg.Dash.EmergencyTag,
 		cfg.Dash.DashOnStart,
 		cfg.DJ.DjOnStart,
 		cfg.DJ.DjCacheFile,
@@ -58,6 +144,17 @@ func main() {
 		cfg.Live.Source,
 		cfg.Live.Filters,
 		cfg.ListenerTimeout,
+		cfg.Health.MinContent,
+		cfg.Health.StallTimeout,
+		cfg.Health.DiskUsage,
+		cfg.Health.WatchInterval,
+		cfg.Alerts.Webhooks,
+		cfg.Alerts.TelegramAPI,
+		os.Getenv("TELEGRAM_BOT_TOKEN"),
+		cfg.Alerts.TelegramChats,
+		cfg.Alerts.Retries,
+		cfg.Alerts.Backoff,
+		cfg.Alerts.MaxBackoff,
 	)
 
 	// Run server
@@ -71,8 +168,12 @@ func main() {
 
 	<-stop
 
+	cancel()
 	httpApplication.Router.Stop()
 	httpApplication.Storage.Stop()
+	if err := shutdownTracing(context.Background()); err != nil {
+		log.Error("failed to flush spans", sl.Err(err))
+	}
 	log.Info("Gracefully stopped")
 }
 
@@ -115,6 +216,14 @@ func setupPrettySlog() *slog.Logger {
 	return slog.New(handler)
 }
 
+func roles(names []string) []models.Role {
+	res := make([]models.Role, 0, len(names))
+	for _, n := range names {
+		res = append(res, models.Role(n))
+	}
+	return res
+}
+
 func getSecret() []byte {
 	secret := os.Getenv("SECRET")
 
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+
+	reportSrv "github.com/GintGld/fizteh-radio/internal/service/report"
+	"github.com/GintGld/fizteh-radio/internal/storage/sqlite"
+)
+
+func main() {
+	var storagePath, period, format, out string
+
+	flag.StringVar(&storagePath, "storage-path", "", "path to storage")
+	flag.StringVar(&period, "period", "", "report period (2024, 2024Q1 or 2024-03)")
+	flag.StringVar(&format, "format", "csv", "report format (csv or xlsx)")
+	flag.StringVar(&out, "out", "", "output file (stdout by default)")
+	flag.Parse()
+
+	if storagePath == "" {
+		panic("storage-path is required")
+	}
+	if period == "" {
+		panic("period is required")
+	}
+	if format != "csv" && format != "xlsx" {
+		panic("unknown format")
+	}
+
+	start, stop, err := reportSrv.ParsePeriod(period, time.Local)
+	if err != nil {
+		panic(err)
+	}
+
+	storage, err := sqlite.New(storagePath)
+	if err != nil {
+		panic(err)
+	}
+	defer storage.Stop()
+
+	log := slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+	report, err := reportSrv.New(log, storage).Report(context.Background(), start, stop)
+	if err != nil {
+		panic(err)
+	}
+
+	var w io.Writer = os.Stdout
+	if out != "" {
+		file, err := os.Create(out)
+		if err != nil {
+			panic(err)
+		}
+		defer file.Close()
+		w = file
+	}
+
+	if format == "xlsx" {
+		err = reportSrv.WriteXLSX(w, report)
+	} else {
+		err = reportSrv.WriteCSV(w, report)
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Fprintf(os.Stderr, "report for %s: %d tracks\n", period, len(report.Rows))
+}
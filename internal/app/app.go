@@ -1,12 +1,17 @@
 package app
 
 import (
+	"context"
 	"log/slog"
 	"os"
 	"time"
 
 	routerApp "github.com/GintGld/fizteh-radio/internal/app/router"
+	"github.com/GintGld/fizteh-radio/internal/client/backend"
 	"github.com/GintGld/fizteh-radio/internal/lib/logger/sl"
+	"github.com/GintGld/fizteh-radio/internal/lib/oidc"
+	"github.com/GintGld/fizteh-radio/internal/lib/throttle"
+	"github.com/GintGld/fizteh-radio/internal/models"
 	"github.com/GintGld/fizteh-radio/internal/storage/sqlite"
 )
 
@@ -16,19 +21,33 @@ type App struct {
 }
 
 func New(
+	ctx context.Context,
 	log *slog.Logger,
 	address string,
+	metricsAddress string,
 	storagePath string,
 	timeout time.Duration,
 	idleTimeout time.Duration,
+	proxyHeader string,
 	tokenTTL time.Duration,
+	refreshTTL time.Duration,
+	loginPolicy throttle.Policy,
+	ipPolicy throttle.Policy,
+	accountPolicy throttle.Policy,
+	oidcConfig oidc.Config,
+	oidcLoginClaim string,
+	oidcAutoProvision bool,
+	oidcRoles []models.Role,
 	secret []byte,
 	rootPass []byte,
 	maxAnswerLength int,
 	tmpDir string,
-	sourceAddr string,
-	sourceTimeout time.Duration,
-	sourceRetryCount int,
+	bodyLimit int64,
+	uploadTTL time.Duration,
+	uploadMaxSize int64,
+	sourceConfig backend.Config,
+	reconcileInterval time.Duration,
+	reconcileTimeout time.Duration,
 	manPath string,
 	contentDir string,
 	chunkLength time.Duration,
@@ -37,6 +56,10 @@ func New(
 	clientUpdateFreq time.Duration,
 	dashUpdateFreq time.Duration,
 	dashHorizon time.Duration,
+	prefetch time.Duration,
+	cacheSize int64,
+	deadAirWindow time.Duration,
+	emergencyTag string,
 	dashOnStart bool,
 	djOnStart bool,
 	djCacheFile string,
@@ -46,6 +69,17 @@ func New(
 	liveSource string,
 	liveFilters map[string]string,
 	listenerTimeout time.Duration,
+	healthMinContent time.Duration,
+	healthStallTimeout time.Duration,
+	healthDiskUsage float64,
+	healthWatchInterval time.Duration,
+	alertWebhooks []string,
+	telegramAPI string,
+	telegramToken string,
+	telegramChats []string,
+	alertRetries int,
+	alertBackoff time.Duration,
+	alertMaxBackoff time.Duration,
 ) *App {
 	storage, err := sqlite.New(storagePath)
 	if err != nil {
@@ -54,19 +88,33 @@ func New(
 	}
 
 	routerApp := routerApp.New(
+		ctx,
 		log,
 		storage,
 		address,
+		metricsAddress,
 		timeout,
 		idleTimeout,
+		proxyHeader,
 		tokenTTL,
+		refreshTTL,
+		loginPolicy,
+		ipPolicy,
+		accountPolicy,
+		oidcConfig,
+		oidcLoginClaim,
+		oidcAutoProvision,
+		oidcRoles,
 		secret,
 		rootPass,
 		maxAnswerLength,
 		tmpDir,
-		sourceAddr,
-		sourceTimeout,
-		sourceRetryCount,
+		bodyLimit,
+		uploadTTL,
+		uploadMaxSize,
+		sourceConfig,
+		reconcileInterval,
+		reconcileTimeout,
 		manPath,
 		contentDir,
 		chunkLength,
@@ -75,6 +123,10 @@ func New(
 		clientUpdateFreq,
 		dashUpdateFreq,
 		dashHorizon,
+		prefetch,
+		cacheSize,
+		deadAirWindow,
+		emergencyTag,
 		dashOnStart,
 		djOnStart,
 		djCacheFile,
@@ -84,6 +136,17 @@ func New(
 		liveSource,
 		liveFilters,
 		listenerTimeout,
+		healthMinContent,
+		healthStallTimeout,
+		healthDiskUsage,
+		healthWatchInterval,
+		alertWebhooks,
+		telegramAPI,
+		telegramToken,
+		telegramChats,
+		alertRetries,
+		alertBackoff,
+		alertMaxBackoff,
 	)
 
 	return &App{
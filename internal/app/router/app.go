@@ -3,60 +3,104 @@ package router
 import (
 	"context"
 	"log/slog"
+	"net/http"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"golang.org/x/crypto/bcrypt"
 
+	"github.com/GintGld/fizteh-radio/internal/client/backend"
+	"github.com/GintGld/fizteh-radio/internal/lib/bodylimit"
+	"github.com/GintGld/fizteh-radio/internal/lib/caller"
+	"github.com/GintGld/fizteh-radio/internal/lib/events"
+	"github.com/GintGld/fizteh-radio/internal/lib/logger/sl"
+	"github.com/GintGld/fizteh-radio/internal/lib/metrics"
+	"github.com/GintGld/fizteh-radio/internal/lib/oidc"
+	"github.com/GintGld/fizteh-radio/internal/lib/throttle"
+	"github.com/GintGld/fizteh-radio/internal/lib/tracing"
 	"github.com/GintGld/fizteh-radio/internal/models"
 	"github.com/GintGld/fizteh-radio/internal/storage/sqlite"
 
-	storageCli "github.com/GintGld/fizteh-radio/internal/client/storage"
-
+	apiKeySrv "github.com/GintGld/fizteh-radio/internal/service/apikey"
+	asRunSrv "github.com/GintGld/fizteh-radio/internal/service/asrun"
+	auditSrv "github.com/GintGld/fizteh-radio/internal/service/audit"
 	authSrv "github.com/GintGld/fizteh-radio/internal/service/auth"
 	djSrv "github.com/GintGld/fizteh-radio/internal/service/autodj"
 	contentSrv "github.com/GintGld/fizteh-radio/internal/service/content"
 	dashSrv "github.com/GintGld/fizteh-radio/internal/service/dash"
+	edtSrv "github.com/GintGld/fizteh-radio/internal/service/editor"
+	healthSrv "github.com/GintGld/fizteh-radio/internal/service/health"
 	jwtSrv "github.com/GintGld/fizteh-radio/internal/service/jwt"
 	liveSrv "github.com/GintGld/fizteh-radio/internal/service/live"
 	manSrv "github.com/GintGld/fizteh-radio/internal/service/manifest"
 	mediaSrv "github.com/GintGld/fizteh-radio/internal/service/media"
+	notifierSrv "github.com/GintGld/fizteh-radio/internal/service/notifier"
+	oidcSrv "github.com/GintGld/fizteh-radio/internal/service/oidc"
+	reconcileSrv "github.com/GintGld/fizteh-radio/internal/service/reconcile"
+	reportSrv "github.com/GintGld/fizteh-radio/internal/service/report"
+	revocationSrv "github.com/GintGld/fizteh-radio/internal/service/revocation"
 	rootSrv "github.com/GintGld/fizteh-radio/internal/service/root"
 	schSrv "github.com/GintGld/fizteh-radio/internal/service/schedule"
 	srcSrv "github.com/GintGld/fizteh-radio/internal/service/source"
 	statSrv "github.com/GintGld/fizteh-radio/internal/service/stat"
+	uploadSrv "github.com/GintGld/fizteh-radio/internal/service/upload"
 
+	asRunCtr "github.com/GintGld/fizteh-radio/internal/controller/asrun"
 	authCtr "github.com/GintGld/fizteh-radio/internal/controller/auth"
 	dashCtr "github.com/GintGld/fizteh-radio/internal/controller/dash"
+	edtCtr "github.com/GintGld/fizteh-radio/internal/controller/editor"
+	healthCtr "github.com/GintGld/fizteh-radio/internal/controller/health"
 	jwtCtr "github.com/GintGld/fizteh-radio/internal/controller/jwt"
 	mediaCtr "github.com/GintGld/fizteh-radio/internal/controller/media"
+	reportCtr "github.com/GintGld/fizteh-radio/internal/controller/report"
 	rootCtr "github.com/GintGld/fizteh-radio/internal/controller/root"
 	schCtr "github.com/GintGld/fizteh-radio/internal/controller/schedule"
 	statCtr "github.com/GintGld/fizteh-radio/internal/controller/stat"
 )
 
 type App struct {
-	log     *slog.Logger
-	address string
-	app     *fiber.App
-	dash    *dashSrv.Dash
+	log            *slog.Logger
+	address        string
+	app            *fiber.App
+	metricsAddress string
+	metrics        *fiber.App
+	dash           *dashSrv.Dash
 }
 
 // New returns configured router.App
 func New(
+	ctx context.Context,
 	log *slog.Logger,
 	storage *sqlite.Storage,
 	address string,
+	metricsAddress string,
 	timeout time.Duration,
 	idleTimeout time.Duration,
+	proxyHeader string,
 	tokenTTL time.Duration,
+	refreshTTL time.Duration,
+	loginPolicy throttle.Policy,
+	ipPolicy throttle.Policy,
+	accountPolicy throttle.Policy,
+	oidcConfig oidc.Config,
+	oidcLoginClaim string,
+	oidcAutoProvision bool,
+	oidcRoles []models.Role,
 	secret []byte,
 	rootPass []byte,
 	maxAnswerLength int,
 	tmpDir string,
-	sourceAddr string,
-	sourceTimeout time.Duration,
-	sourceRetryCount int,
+	bodyLimit int64,
+	uploadTTL time.Duration,
+	uploadMaxSize int64,
+	sourceConfig backend.Config,
+	reconcileInterval time.Duration,
+	reconcileTimeout time.Duration,
 	manPath string,
 	contentDir string,
 	chunkLength time.Duration,
@@ -65,6 +109,10 @@ func New(
 	clientUpdateFreq time.Duration,
 	dashUpdateFreq time.Duration,
 	dashHorizon time.Duration,
+	prefetch time.Duration,
+	cacheSize int64,
+	deadAirWindow time.Duration,
+	emergencyTag string,
 	dashOnStart bool,
 	djOnStart bool,
 	djCacheFile string,
@@ -74,6 +122,17 @@ func New(
 	liveSource string,
 	liveFilters map[string]string,
 	listenerTimeout time.Duration,
+	healthMinContent time.Duration,
+	healthStallTimeout time.Duration,
+	healthDiskUsage float64,
+	healthWatchInterval time.Duration,
+	alertWebhooks []string,
+	telegramAPI string,
+	telegramToken string,
+	telegramChats []string,
+	alertRetries int,
+	alertBackoff time.Duration,
+	alertMaxBackoff time.Duration,
 ) *App {
 	// Create sevices
 	jwt := jwtSrv.New(secret)
@@ -83,30 +142,96 @@ func New(
 		panic("invalid root password")
 	}
 
-	store, err := storageCli.New(
-		context.TODO(),
-		log,
-		sourceAddr,
-		sourceTimeout,
-		sourceRetryCount,
-	)
+	store, err := backend.New(ctx, log, sourceConfig)
+	if err != nil {
+		panic("failed to init source storage: " + err.Error())
+	}
 
 	sch2dashChan := make(chan models.Segment, 1)
 	sch2djChan := make(chan struct{}, 1)
 	lib2djChan := make(chan struct{}, 1)
 
+	// Events for alerting
+	bus := events.New()
+
+	sinks := make([]notifierSrv.Sink, 0, len(alertWebhooks)+len(telegramChats))
+	for _, url := range alertWebhooks {
+		sinks = append(sinks, notifierSrv.NewWebhook(url, &http.Client{Timeout: timeout}))
+	}
+	if telegramToken != "" {
+		for _, chat := range telegramChats {
+			sinks = append(sinks, notifierSrv.NewTelegram(telegramAPI, telegramToken, chat, &http.Client{Timeout: timeout}))
+		}
+	}
+	notifier := notifierSrv.New(
+		log,
+		sinks,
+		alertRetries,
+		alertBackoff,
+		alertMaxBackoff,
+	)
+	bus.Subscribe(notifier.Notify)
+
+	// Revoked access tokens
+	revocation := revocationSrv.New(
+		log,
+		storage,
+		timeout,
+		tokenTTL,
+	)
+	// API keys of machine clients
+	keys := apiKeySrv.New(
+		log,
+		storage,
+		timeout,
+	)
 	// Authentication service
 	auth := authSrv.New(
 		log,
 		storage,
+		storage,
+		storage,
 		jwt,
+		revocation,
 		rootPassHash,
 		tokenTTL,
+		refreshTTL,
+		loginPolicy,
+		ipPolicy,
+		accountPolicy,
+	)
+	// External identity provider (SSO)
+	var provider oidcSrv.Provider
+	if oidcConfig.Issuer != "" {
+		provider = oidc.New(oidcConfig, &http.Client{Timeout: timeout})
+	}
+	sso := oidcSrv.New(
+		log,
+		provider,
+		storage,
+		auth,
+		oidcLoginClaim,
+		oidcAutoProvision,
+		oidcRoles,
 	)
 	// Root editor service
 	root := rootSrv.New(
 		log,
 		storage,
+		storage,
+		revocation,
+	)
+	// Audit of editor changes
+	audit := auditSrv.New(
+		log,
+		storage,
+	)
+	// Editor self-service
+	edt := edtSrv.New(
+		log,
+		storage,
+		revocation,
+		loginPolicy,
 	)
 	// Media library service
 	lib := mediaSrv.New(
@@ -120,6 +245,25 @@ func New(
 		log,
 		store,
 	)
+	// Integrity of sources
+	reconcile := reconcileSrv.New(
+		log,
+		reconcileTimeout,
+		tmpDir,
+		storage,
+		store,
+		bus,
+		lib2djChan,
+	)
+	// Resumable uploads of sources
+	upload := uploadSrv.New(
+		log,
+		filepath.Join(tmpDir, "uploads"),
+		uploadTTL,
+		uploadMaxSize,
+		src,
+		lib,
+	)
 	// Schedule service
 	sch := schSrv.New(
 		log,
@@ -130,10 +274,13 @@ func New(
 	)
 	// AutoDJ
 	dj := djSrv.New(
+		ctx,
 		log,
 		timeout,
 		lib,
 		sch,
+		storage,
+		bus,
 		djCacheFile,
 		sch2djChan,
 		lib2djChan,
@@ -143,6 +290,7 @@ func New(
 		log,
 		timeout,
 		sch,
+		bus,
 		liveDelay,
 		liveStep,
 		liveSourceType,
@@ -168,18 +316,33 @@ func New(
 		log,
 		contentDir,
 		chunkLength,
+		cacheSize,
 		lib,
 		src,
 	)
+	// As-run log
+	asRun := asRunSrv.New(
+		log,
+		storage,
+		lib,
+		live,
+	)
 	// Dash goroutine
 	dash := dashSrv.New(
 		log,
 		timeout,
 		dashUpdateFreq,
 		dashHorizon,
+		prefetch,
+		deadAirWindow,
+		emergencyTag,
 		man,
 		content,
 		sch,
+		asRun,
+		lib,
+		storage,
+		bus,
 		sch2dashChan,
 	)
 	// Stat
@@ -188,25 +351,79 @@ func New(
 		storage,
 		listenerTimeout,
 	)
+	metrics.Listeners(stat.ListenersNumber)
 
-	// Controller helper
-	jwtCtr := jwtCtr.New(secret)
+	// Airplay reports
+	report := reportSrv.New(
+		log,
+		storage,
+	)
 
-	// TODO: body message limit more accurate
+	// Health of components
+	health := healthSrv.New(
+		log,
+		timeout,
+		storage,
+		store,
+		dash,
+		dj,
+		man,
+		healthMinContent,
+		healthStallTimeout,
+		[]string{contentDir, tmpDir, filepath.Dir(manPath)},
+		healthDiskUsage,
+		bus,
+	)
 
-	// 300 MB limit for body message (~ 2.1 hours for .mp3 with 320 kbit/s)
+	// Controller helper
+	jwtCtr := jwtCtr.New(secret, revocation, keys)
+
+	// Bodies are streamed, so sources are sent
+	// to storage without saving them to disk.
+	//
+	// Radio port is reachable directly, so client ip
+	// is taken from proxy header only if request
+	// came from reverse proxy on the same host.
 	app := fiber.New(fiber.Config{
-		IdleTimeout: idleTimeout,
-		BodyLimit:   300 * 1024 * 1024,
+		IdleTimeout:                  idleTimeout,
+		ProxyHeader:                  proxyHeader,
+		EnableTrustedProxyCheck:      true,
+		TrustedProxies:               []string{"127.0.0.1", "::1"},
+		BodyLimit:                    int(bodyLimit),
+		StreamRequestBody:            true,
+		DisablePreParseMultipartForm: true,
+	})
+
+	// Streamed body isn't limited by server,
+	// body of unknown length is accepted
+	// only by routes uploading sources.
+	app.Use(bodylimit.Middleware(bodyLimit, func(c *fiber.Ctx) bool {
+		return (c.Method() == fiber.MethodPost && c.Path() == "/library/media") ||
+			(c.Method() == fiber.MethodPatch && strings.HasPrefix(c.Path(), "/library/upload/"))
+	}))
+
+	// Request id is returned in X-Request-ID header
+	// and saved in request context for logs.
+	app.Use(requestid.New())
+	app.Use(func(c *fiber.Ctx) error {
+		id, _ := c.Locals("requestid").(string)
+		c.SetUserContext(caller.WithRequestID(c.UserContext(), id))
+		return c.Next()
 	})
+	app.Use(metrics.Middleware())
+	app.Use(tracing.Middleware())
 
 	// Mount controllers to an app
-	app.Mount("/login", authCtr.New(timeout, auth))
-	app.Mount("/root", rootCtr.New(timeout, root, jwtCtr))
-	app.Mount("/library", mediaCtr.New(timeout, lib, src, jwtCtr, tmpDir))
-	app.Mount("/schedule", schCtr.New(timeout, sch, dj, live, jwtCtr))
+	app.Mount("/", healthCtr.New(health))
+	app.Mount("/login", authCtr.New(timeout, auth, sso, jwtCtr))
+	app.Mount("/root", rootCtr.New(timeout, root, keys, audit, jwtCtr))
+	app.Mount("/editor", edtCtr.New(timeout, edt, jwtCtr))
+	app.Mount("/library", mediaCtr.New(timeout, lib, src, upload, reconcile, audit, jwtCtr, tmpDir))
+	app.Mount("/schedule", schCtr.New(timeout, sch, dj, live, audit, jwtCtr))
 	app.Mount("/radio", dashCtr.New(manPath, contentDir, jwtCtr, dash))
 	app.Mount("/stat", statCtr.New(timeout, stat))
+	app.Mount("/asrun", asRunCtr.New(timeout, asRun, jwtCtr))
+	app.Mount("/report", reportCtr.New(timeout, report, jwtCtr))
 
 	// In debug mode there's no proxy that serves static files.
 	if log.Enabled(context.Background(), slog.LevelDebug) {
@@ -231,17 +448,31 @@ func New(
 	}
 
 	if dashOnStart {
-		go dash.Run(context.TODO())
+		go dash.Run(ctx)
 	}
 	if djOnStart {
-		go dj.Run(context.TODO())
+		go dj.Run(ctx)
+	}
+	go health.Watch(ctx, healthWatchInterval)
+	if reconcileInterval > 0 {
+		go reconcile.Run(ctx, reconcileInterval)
 	}
 
+	// Prometheus metrics aren't exposed
+	// with the api, they are served
+	// on a separate (loopback) address.
+	metricsApp := fiber.New(fiber.Config{
+		DisableStartupMessage: true,
+	})
+	metricsApp.Get("/metrics", adaptor.HTTPHandler(promhttp.Handler()))
+
 	return &App{
-		log:     log,
-		address: address,
-		app:     app,
-		dash:    dash,
+		log:            log,
+		address:        address,
+		app:            app,
+		metricsAddress: metricsAddress,
+		metrics:        metricsApp,
+		dash:           dash,
 	}
 }
 
@@ -252,10 +483,16 @@ func (a *App) MustRun() {
 }
 
 func (a *App) Run() error {
+	go func() {
+		if err := a.metrics.Listen(a.metricsAddress); err != nil {
+			a.log.Error("failed to serve metrics", sl.Err(err))
+		}
+	}()
 	return a.app.Listen(a.address)
 }
 
 func (a *App) Stop() {
 	go a.dash.Stop()
+	a.metrics.Shutdown()
 	a.app.Shutdown()
 }
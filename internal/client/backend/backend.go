@@ -0,0 +1,77 @@
+// Package backend selects storage
+// of media sources by configuration.
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/GintGld/fizteh-radio/internal/client/local"
+	"github.com/GintGld/fizteh-radio/internal/client/s3"
+	client "github.com/GintGld/fizteh-radio/internal/client/storage"
+)
+
+const (
+	TypeGRPC  = "grpc"
+	TypeLocal = "local"
+	TypeS3    = "s3"
+)
+
+var ErrUnknownType = errors.New("unknown source storage type")
+
+// Backend stores source files by integer ids.
+type Backend interface {
+	Upload(ctx context.Context, r io.Reader) (int, error)
+	Download(ctx context.Context, id int, dst string) error
+	Delete(ctx context.Context, id int) error
+	Ping(ctx context.Context) error
+}
+
+// Config of source storage, only
+// fields of chosen Type are used.
+type Config struct {
+	Type string
+
+	// gRPC FileService
+	Addr       string
+	Timeout    time.Duration
+	RetryCount int
+	TLS        client.TLS
+	Token      string
+
+	// Local directory
+	Dir string
+
+	// S3-compatible bucket
+	S3 s3.Config
+}
+
+// New returns backend of configured type.
+func New(ctx context.Context, log *slog.Logger, cfg Config) (Backend, error) {
+	const op = "backend.New"
+
+	var (
+		b   Backend
+		err error
+	)
+	switch cfg.Type {
+	case TypeGRPC, "":
+		b, err = client.New(ctx, log, cfg.Addr, cfg.Timeout, cfg.RetryCount, cfg.TLS, cfg.Token)
+	case TypeLocal:
+		b, err = local.New(cfg.Dir)
+	case TypeS3:
+		b = s3.New(cfg.S3, &http.Client{Timeout: cfg.Timeout})
+	default:
+		err = fmt.Errorf("%w: %q", ErrUnknownType, cfg.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return b, nil
+}
@@ -0,0 +1,169 @@
+// Package local keeps sources
+// in a directory of local filesystem.
+package local
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+var ErrNotFound = errors.New("source not found")
+
+// Storage saves every source to
+// a file named by its id.
+type Storage struct {
+	dir   string
+	mutex sync.Mutex
+	next  int
+}
+
+// New returns storage in given directory,
+// directory is created if it doesn't exist.
+func New(dir string) (*Storage, error) {
+	const op = "local.New"
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Ids continue after the largest saved one.
+	next := 1
+	for _, e := range entries {
+		id, err := strconv.Atoi(e.Name())
+		if err != nil || e.IsDir() {
+			continue
+		}
+		next = max(next, id+1)
+	}
+
+	return &Storage{
+		dir:  dir,
+		next: next,
+	}, nil
+}
+
+// Upload saves data and returns its id.
+func (s *Storage) Upload(ctx context.Context, r io.Reader) (int, error) {
+	const op = "local.Upload"
+
+	// Write to a temporary file first, so
+	// partially written source is never visible.
+	tmp, err := os.CreateTemp(s.dir, ".upload-*")
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := ctx.Err(); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	id := s.next
+	if err := os.Rename(tmp.Name(), s.path(id)); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	s.next++
+
+	return id, nil
+}
+
+// Download copies source to dst.
+func (s *Storage) Download(ctx context.Context, id int, dst string) error {
+	const op = "local.Download"
+
+	source, err := os.Open(s.path(id))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("%s: %w", op, ErrNotFound)
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer source.Close()
+
+	destination, err := os.Create(dst)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer destination.Close()
+
+	if _, err := io.Copy(destination, source); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Delete deletes source by its id.
+func (s *Storage) Delete(ctx context.Context, id int) error {
+	const op = "local.Delete"
+
+	if err := os.Remove(s.path(id)); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("%s: %w", op, ErrNotFound)
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// List returns ids of all saved sources.
+func (s *Storage) List(context.Context) ([]int, error) {
+	const op = "local.List"
+
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	ids := make([]int, 0, len(entries))
+	for _, e := range entries {
+		id, err := strconv.Atoi(e.Name())
+		if err != nil || e.IsDir() {
+			continue
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+// Ping checks that directory is available.
+func (s *Storage) Ping(context.Context) error {
+	const op = "local.Ping"
+
+	info, err := os.Stat(s.dir)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s: %s is not a directory", op, s.dir)
+	}
+
+	return nil
+}
+
+func (s *Storage) path(id int) string {
+	return filepath.Join(s.dir, strconv.Itoa(id))
+}
@@ -0,0 +1,46 @@
+package local
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStorage(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	s, err := New(dir)
+	require.NoError(t, err)
+	require.NoError(t, s.Ping(ctx))
+
+	id, err := s.Upload(ctx, strings.NewReader("sound"))
+	require.NoError(t, err)
+	assert.Equal(t, 1, id)
+
+	dst := filepath.Join(t.TempDir(), "source")
+	require.NoError(t, s.Download(ctx, id, dst))
+	data, err := os.ReadFile(dst)
+	require.NoError(t, err)
+	assert.Equal(t, "sound", string(data))
+
+	// Ids continue after restart.
+	s, err = New(dir)
+	require.NoError(t, err)
+	next, err := s.Upload(ctx, strings.NewReader("noise"))
+	require.NoError(t, err)
+	assert.Equal(t, 2, next)
+
+	ids, err := s.List(ctx)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []int{id, next}, ids)
+
+	require.NoError(t, s.Delete(ctx, id))
+	assert.ErrorIs(t, s.Download(ctx, id, dst), ErrNotFound)
+	assert.ErrorIs(t, s.Delete(ctx, id), ErrNotFound)
+}
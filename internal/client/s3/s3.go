@@ -0,0 +1,462 @@
+// Package s3 keeps sources in a bucket
+// of S3-compatible storage (AWS, MinIO, etc.).
+package s3
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/GintGld/fizteh-radio/internal/lib/metrics"
+)
+
+const (
+	// idAttempts is a number of random
+	// ids tried before upload fails.
+	idAttempts = 5
+	// partSize is a size of multipart upload part,
+	// S3 requires at least 5 MiB for all but the last.
+	partSize = 8 << 20
+	// abortTimeout limits abort of failed
+	// multipart upload.
+	abortTimeout = 30 * time.Second
+)
+
+var (
+	ErrNotFound = errors.New("source not found")
+	ErrIDTaken  = errors.New("failed to allocate source id")
+)
+
+// Config of S3 bucket, path-style
+// addressing is used (<endpoint>/<bucket>/<key>).
+type Config struct {
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+}
+
+// Storage saves every source to
+// an object named by its id.
+type Storage struct {
+	cfg    Config
+	client *http.Client
+}
+
+func New(cfg Config, client *http.Client) *Storage {
+	cfg.Endpoint = strings.TrimRight(cfg.Endpoint, "/")
+	return &Storage{
+		cfg:    cfg,
+		client: client,
+	}
+}
+
+// Upload saves data and returns its id.
+// Id is random, since S3 has no counters.
+//
+// Data of unknown size is sent by parts
+// (multipart upload) without buffering it whole.
+func (s *Storage) Upload(ctx context.Context, r io.Reader) (_ int, err error) {
+	const op = "s3.Upload"
+
+	defer countFailure("upload", &err)
+
+	id, err := s.freeID(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	key := strconv.Itoa(id)
+
+	if seeker, ok := r.(io.ReadSeeker); ok {
+		var size int64
+		if size, err = remaining(seeker); err == nil {
+			err = s.put(ctx, key, io.NopCloser(seeker), size)
+		}
+	} else {
+		err = s.putStream(ctx, key, r)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
+
+// freeID returns random id not used by any object.
+func (s *Storage) freeID(ctx context.Context) (int, error) {
+	for i := 0; i < idAttempts; i++ {
+		id := int(rand.Int31n(math.MaxInt32-1)) + 1
+
+		resp, err := s.do(ctx, http.MethodHead, strconv.Itoa(id), nil, 0)
+		if err != nil {
+			return 0, err
+		}
+		resp.Body.Close()
+		switch resp.StatusCode {
+		case http.StatusOK:
+			continue
+		case http.StatusNotFound:
+			return id, nil
+		default:
+			return 0, responseError(resp)
+		}
+	}
+
+	return 0, ErrIDTaken
+}
+
+// put uploads object of known size.
+func (s *Storage) put(ctx context.Context, key string, body io.Reader, size int64) error {
+	resp, err := s.do(ctx, http.MethodPut, key, body, size)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return responseError(resp)
+	}
+
+	return nil
+}
+
+// putStream uploads object of unknown size,
+// data fitting in one part is sent with a single put.
+func (s *Storage) putStream(ctx context.Context, key string, r io.Reader) (err error) {
+	buf := make([]byte, partSize)
+
+	n, err := io.ReadFull(r, buf)
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return s.put(ctx, key, bytes.NewReader(buf[:n]), int64(n))
+	}
+	if err != nil {
+		return err
+	}
+
+	uploadID, err := s.createMultipart(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			s.abortMultipart(key, uploadID)
+		}
+	}()
+
+	var complete completeMultipart
+	for number := 1; n > 0; number++ {
+		etag, err := s.putPart(ctx, key, uploadID, number, buf[:n])
+		if err != nil {
+			return err
+		}
+		complete.Parts = append(complete.Parts, part{number, etag})
+
+		n, err = io.ReadFull(r, buf)
+		if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return err
+		}
+	}
+
+	return s.completeMultipart(ctx, key, uploadID, complete)
+}
+
+type part struct {
+	PartNumber int
+	ETag       string
+}
+
+type completeMultipart struct {
+	XMLName xml.Name `xml:"CompleteMultipartUpload"`
+	Parts   []part   `xml:"Part"`
+}
+
+func (s *Storage) createMultipart(ctx context.Context, key string) (string, error) {
+	resp, err := s.do(ctx, http.MethodPost, key+"?uploads", nil, 0)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", responseError(resp)
+	}
+
+	var result struct {
+		UploadID string `xml:"UploadId"`
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", err
+	}
+
+	return result.UploadID, nil
+}
+
+func (s *Storage) putPart(ctx context.Context, key, uploadID string, number int, data []byte) (string, error) {
+	query := url.Values{
+		"partNumber": {strconv.Itoa(number)},
+		"uploadId":   {uploadID},
+	}
+	resp, err := s.do(ctx, http.MethodPut, key+"?"+query.Encode(), bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", responseError(resp)
+	}
+
+	return resp.Header.Get("ETag"), nil
+}
+
+func (s *Storage) completeMultipart(ctx context.Context, key, uploadID string, complete completeMultipart) error {
+	body, err := xml.Marshal(complete)
+	if err != nil {
+		return err
+	}
+
+	query := url.Values{"uploadId": {uploadID}}
+	resp, err := s.do(ctx, http.MethodPost, key+"?"+query.Encode(), bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return responseError(resp)
+	}
+
+	// Error may be reported with 200 status.
+	var result struct {
+		XMLName xml.Name
+		Code    string `xml:"Code"`
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return err
+	}
+	if result.XMLName.Local == "Error" {
+		return fmt.Errorf("failed to complete upload: %s", result.Code)
+	}
+
+	return nil
+}
+
+// abortMultipart deletes uploaded parts, it's
+// called with new context since upload's one
+// may be already cancelled.
+func (s *Storage) abortMultipart(key, uploadID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), abortTimeout)
+	defer cancel()
+
+	query := url.Values{"uploadId": {uploadID}}
+	if resp, err := s.do(ctx, http.MethodDelete, key+"?"+query.Encode(), nil, 0); err == nil {
+		resp.Body.Close()
+	}
+}
+
+// Download saves source to dst.
+func (s *Storage) Download(ctx context.Context, id int, dst string) (err error) {
+	const op = "s3.Download"
+
+	start := time.Now()
+	defer func() {
+		if err == nil {
+			metrics.SourceDownloadDuration.Observe(time.Since(start).Seconds())
+		}
+	}()
+	defer countFailure("download", &err)
+
+	resp, err := s.do(ctx, http.MethodGet, strconv.Itoa(id), nil, 0)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return fmt.Errorf("%s: %w", op, ErrNotFound)
+	default:
+		return fmt.Errorf("%s: %w", op, responseError(resp))
+	}
+
+	destination, err := os.Create(dst)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer destination.Close()
+
+	if _, err := io.Copy(destination, resp.Body); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Delete deletes source by its id.
+func (s *Storage) Delete(ctx context.Context, id int) (err error) {
+	const op = "s3.Delete"
+
+	defer countFailure("delete", &err)
+
+	resp, err := s.do(ctx, http.MethodDelete, strconv.Itoa(id), nil, 0)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %w", op, responseError(resp))
+	}
+
+	return nil
+}
+
+// List returns ids of all saved sources,
+// objects not named by id are skipped.
+func (s *Storage) List(ctx context.Context) ([]int, error) {
+	const op = "s3.List"
+
+	ids := make([]int, 0)
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+
+		page, err := s.listPage(ctx, query)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		for _, object := range page.Contents {
+			if id, err := strconv.Atoi(object.Key); err == nil {
+				ids = append(ids, id)
+			}
+		}
+
+		if !page.IsTruncated {
+			return ids, nil
+		}
+		token = page.NextContinuationToken
+	}
+}
+
+type listResult struct {
+	IsTruncated           bool
+	NextContinuationToken string
+	Contents              []struct {
+		Key string
+	}
+}
+
+// listPage returns single page of ListObjectsV2.
+func (s *Storage) listPage(ctx context.Context, query url.Values) (listResult, error) {
+	resp, err := s.do(ctx, http.MethodGet, "?"+query.Encode(), nil, 0)
+	if err != nil {
+		return listResult{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return listResult{}, responseError(resp)
+	}
+
+	var page listResult
+	if err := xml.NewDecoder(resp.Body).Decode(&page); err != nil {
+		return listResult{}, err
+	}
+
+	return page, nil
+}
+
+// Ping checks that bucket is accessible.
+func (s *Storage) Ping(ctx context.Context) error {
+	const op = "s3.Ping"
+
+	resp, err := s.do(ctx, http.MethodHead, "", nil, 0)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %w", op, responseError(resp))
+	}
+
+	return nil
+}
+
+// do sends signed request to the object by key
+// (to the bucket if key is empty).
+func (s *Storage) do(ctx context.Context, method, key string, body io.Reader, size int64) (*http.Response, error) {
+	url := s.cfg.Endpoint + "/" + s.cfg.Bucket
+	if key != "" {
+		url += "/" + key
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, err
+	}
+
+	// Payload isn't hashed to avoid
+	// reading large sources twice.
+	payloadHash := emptyHash
+	if body != nil {
+		req.ContentLength = size
+		payloadHash = unsignedSum
+	}
+	sign(req, s.cfg.AccessKey, s.cfg.SecretKey, s.cfg.Region, payloadHash, time.Now())
+
+	return s.client.Do(req)
+}
+
+// remaining returns number of bytes
+// left in reader after current position.
+func remaining(seeker io.Seeker) (int64, error) {
+	current, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+	end, err := seeker.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := seeker.Seek(current, io.SeekStart); err != nil {
+		return 0, err
+	}
+	return end - current, nil
+}
+
+// responseError returns error with S3 error code
+// from response body if there's any.
+func responseError(resp *http.Response) error {
+	var body struct {
+		Code string `xml:"Code"`
+	}
+	if err := xml.NewDecoder(io.LimitReader(resp.Body, 4096)).Decode(&body); err == nil && body.Code != "" {
+		return fmt.Errorf("unexpected status %s: %s", resp.Status, body.Code)
+	}
+	return fmt.Errorf("unexpected status %s", resp.Status)
+}
+
+// countFailure counts failed call
+// of the source storage.
+func countFailure(op string, err *error) {
+	if *err != nil {
+		metrics.SourceFailures.WithLabelValues(op).Inc()
+	}
+}
@@ -0,0 +1,80 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+var ErrInsecureToken = errors.New("token can't be sent without tls")
+
+// TLS of connection to the storage.
+//
+// Server is verified by CA (system pool
+// if CAFile is empty), client certificate
+// is sent if it's set (mTLS).
+type TLS struct {
+	Enabled  bool
+	CAFile   string
+	CertFile string
+	KeyFile  string
+	// ServerName overrides name expected in
+	// server certificate, e.g. if storage is
+	// addressed by ip.
+	ServerName string
+}
+
+// transportCredentials returns credentials of connection,
+// plain text is used if TLS is disabled.
+func transportCredentials(cfg TLS) (credentials.TransportCredentials, error) {
+	if !cfg.Enabled {
+		return insecure.NewCredentials(), nil
+	}
+
+	conf := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: cfg.ServerName,
+	}
+
+	if cfg.CAFile != "" {
+		ca, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificates in %s", cfg.CAFile)
+		}
+		conf.RootCAs = pool
+	}
+
+	if cfg.CertFile != "" || cfg.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		conf.Certificates = []tls.Certificate{cert}
+	}
+
+	return credentials.NewTLS(conf), nil
+}
+
+// tokenCredentials adds bearer token
+// to metadata of every call.
+type tokenCredentials string
+
+func (t tokenCredentials) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + string(t)}, nil
+}
+
+// RequireTransportSecurity forbids
+// sending token in plain text.
+func (tokenCredentials) RequireTransportSecurity() bool {
+	return true
+}
@@ -10,13 +10,15 @@ import (
 	"time"
 
 	"github.com/GintGld/fizteh-radio/internal/lib/logger/sl"
+	"github.com/GintGld/fizteh-radio/internal/lib/metrics"
 
 	ssov1 "github.com/GintGld/fizteh-radio-proto/gen/go/storage"
 	grpclog "github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/logging"
 	grpcretry "github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/retry"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/connectivity"
 )
 
 // TODO wrap streaming into io.Reader, io.Writer correctly
@@ -27,19 +29,36 @@ const (
 )
 
 type Client struct {
-	log *slog.Logger
-	api ssov1.FileServiceClient
+	log  *slog.Logger
+	conn *grpc.ClientConn
+	api  ssov1.FileServiceClient
 }
 
+// New returns client of the storage at addr.
+//
+// Connection is secured by tlsConfig, token
+// (if set) is sent with every call and requires TLS.
 func New(
 	ctx context.Context,
 	log *slog.Logger,
 	addr string,
 	timeout time.Duration,
 	retriesCount int,
+	tlsConfig TLS,
+	token string,
 ) (*Client, error) {
 	const op = "Client.New"
 
+	creds, err := transportCredentials(tlsConfig)
+	if err != nil {
+		log.Error("failed to load tls credentials", sl.Err(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if token != "" && !tlsConfig.Enabled {
+		return nil, fmt.Errorf("%s: %w", op, ErrInsecureToken)
+	}
+
 	retryOpts := []grpcretry.CallOption{
 		grpcretry.WithCodes(codes.NotFound, codes.Aborted, codes.DeadlineExceeded),
 		grpcretry.WithMax(uint(retriesCount)),
@@ -50,29 +69,61 @@ func New(
 		grpclog.WithLogOnEvents(grpclog.PayloadReceived, grpclog.PayloadSent),
 	}
 
-	// TODO options for secure
-	cc, err := grpc.NewClient(addr,
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	opts := []grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
 		grpc.WithChainUnaryInterceptor(
 			grpclog.UnaryClientInterceptor(InterceptorLogger(log), logOpts...),
 			grpcretry.UnaryClientInterceptor(retryOpts...),
 		),
-	)
+		// Spans for every call, trace context
+		// is propagated to the storage.
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
+	}
+	if token != "" {
+		opts = append(opts, grpc.WithPerRPCCredentials(tokenCredentials(token)))
+	}
+
+	cc, err := grpc.NewClient(addr, opts...)
 	if err != nil {
 		log.Error("failed to create dial connect", sl.Err(err))
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
 	return &Client{
-		log: log,
-		api: ssov1.NewFileServiceClient(cc),
+		log:  log,
+		conn: cc,
+		api:  ssov1.NewFileServiceClient(cc),
 	}, nil
 }
 
+// Ping checks connection to the storage.
+// Idle connection is connected first.
+func (c *Client) Ping(ctx context.Context) error {
+	const op = "Client.Ping"
+
+	for {
+		state := c.conn.GetState()
+		switch state {
+		case connectivity.Ready:
+			return nil
+		case connectivity.Idle:
+			c.conn.Connect()
+		case connectivity.TransientFailure, connectivity.Shutdown:
+			return fmt.Errorf("%s: connection state %s", op, state)
+		}
+
+		if !c.conn.WaitForStateChange(ctx, state) {
+			return fmt.Errorf("%s: %w", op, ctx.Err())
+		}
+	}
+}
+
 // Upload sends data to gRPC.
-func (c *Client) Upload(ctx context.Context, r io.Reader) (int, error) {
+func (c *Client) Upload(ctx context.Context, r io.Reader) (_ int, err error) {
 	const op = "Client.Upload"
 
+	defer countFailure("upload", &err)
+
 	// Open upload stream.
 	stream, err := c.api.Upload(ctx)
 	if err != nil {
@@ -104,9 +155,17 @@ func (c *Client) Upload(ctx context.Context, r io.Reader) (int, error) {
 }
 
 // Download recieves data from gRPC.
-func (c *Client) Download(ctx context.Context, id int, dst string) error {
+func (c *Client) Download(ctx context.Context, id int, dst string) (err error) {
 	const op = "Client.Download"
 
+	start := time.Now()
+	defer func() {
+		if err == nil {
+			metrics.SourceDownloadDuration.Observe(time.Since(start).Seconds())
+		}
+	}()
+	defer countFailure("download", &err)
+
 	// Open download stream.
 	stream, err := c.api.Download(ctx, &ssov1.DownloadRequest{FileId: int32(id)})
 	if err != nil {
@@ -144,9 +203,11 @@ func (c *Client) Download(ctx context.Context, id int, dst string) error {
 }
 
 // Delete deletes files by its id.
-func (c *Client) Delete(ctx context.Context, id int) error {
+func (c *Client) Delete(ctx context.Context, id int) (err error) {
 	const op = "Client.Delete"
 
+	defer countFailure("delete", &err)
+
 	resp, err := c.api.Delete(ctx, &ssov1.DeleteRequest{FileId: int32(id)})
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
@@ -164,3 +225,11 @@ func InterceptorLogger(l *slog.Logger) grpclog.Logger {
 		l.Log(ctx, slog.Level(lvl), msg, fields...)
 	})
 }
+
+// countFailure counts failed call
+// of the source storage.
+func countFailure(op string, err *error) {
+	if *err != nil {
+		metrics.SourceFailures.WithLabelValues(op).Inc()
+	}
+}
@@ -0,0 +1,307 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log/slog"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	ssov1 "github.com/GintGld/fizteh-radio-proto/gen/go/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const testToken = "secret"
+
+// fileServer is in-memory FileService
+// accepting only calls with testToken.
+type fileServer struct {
+	ssov1.UnimplementedFileServiceServer
+
+	mutex sync.Mutex
+	files map[int32][]byte
+}
+
+func (s *fileServer) Upload(stream grpc.ClientStreamingServer[ssov1.UploadRequest, ssov1.UploadResponse]) error {
+	var data []byte
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		data = append(data, req.GetChunk()...)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	id := int32(len(s.files) + 1)
+	s.files[id] = data
+
+	return stream.SendAndClose(&ssov1.UploadResponse{FileId: id, Size: int32(len(data))})
+}
+
+func (s *fileServer) Download(req *ssov1.DownloadRequest, stream grpc.ServerStreamingServer[ssov1.DownloadResponse]) error {
+	s.mutex.Lock()
+	data, ok := s.files[req.GetFileId()]
+	s.mutex.Unlock()
+	if !ok {
+		return status.Error(codes.NotFound, "file not found")
+	}
+	return stream.Send(&ssov1.DownloadResponse{Chunk: data})
+}
+
+func (s *fileServer) Delete(_ context.Context, req *ssov1.DeleteRequest) (*ssov1.DeleteResponse, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	_, ok := s.files[req.GetFileId()]
+	delete(s.files, req.GetFileId())
+	return &ssov1.DeleteResponse{Success: ok}, nil
+}
+
+func authorize(ctx context.Context) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if auth := md.Get("authorization"); len(auth) != 1 || auth[0] != "Bearer "+testToken {
+		return status.Error(codes.Unauthenticated, "invalid token")
+	}
+	return nil
+}
+
+// pki is a CA with certificates
+// of server and client.
+type pki struct {
+	caFile, certFile, keyFile string
+	pool                      *x509.CertPool
+	server                    tls.Certificate
+}
+
+func newPKI(t *testing.T) pki {
+	t.Helper()
+	dir := t.TempDir()
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+	ca, err := x509.ParseCertificate(caDER)
+	require.NoError(t, err)
+
+	issue := func(serial int64, usage x509.ExtKeyUsage) ([]byte, *ecdsa.PrivateKey) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: "localhost"},
+			DNSNames:     []string{"localhost"},
+			IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+		require.NoError(t, err)
+		return der, key
+	}
+
+	writePEM := func(name, block string, der []byte) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: block, Bytes: der}), 0600))
+		return path
+	}
+	keyDER := func(key *ecdsa.PrivateKey) []byte {
+		der, err := x509.MarshalECPrivateKey(key)
+		require.NoError(t, err)
+		return der
+	}
+
+	serverDER, serverKey := issue(2, x509.ExtKeyUsageServerAuth)
+	server, err := tls.X509KeyPair(
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: serverDER}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER(serverKey)}),
+	)
+	require.NoError(t, err)
+
+	clientDER, clientKey := issue(3, x509.ExtKeyUsageClientAuth)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+
+	return pki{
+		caFile:   writePEM("ca.pem", "CERTIFICATE", caDER),
+		certFile: writePEM("client.pem", "CERTIFICATE", clientDER),
+		keyFile:  writePEM("client-key.pem", "EC PRIVATE KEY", keyDER(clientKey)),
+		pool:     pool,
+		server:   server,
+	}
+}
+
+// startServer starts storage requiring
+// client certificate signed by p.
+func startServer(t *testing.T, p pki) (string, *fileServer) {
+	t.Helper()
+
+	creds := credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{p.server},
+		ClientCAs:    p.pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	})
+	srv := grpc.NewServer(
+		grpc.Creds(creds),
+		grpc.UnaryInterceptor(func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			if err := authorize(ctx); err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+		grpc.StreamInterceptor(func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			if err := authorize(ss.Context()); err != nil {
+				return err
+			}
+			return handler(srv, ss)
+		}),
+	)
+	files := &fileServer{files: make(map[int32][]byte)}
+	ssov1.RegisterFileServiceServer(srv, files)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	return lis.Addr().String(), files
+}
+
+func newTestClient(t *testing.T, addr string, tlsConfig TLS, token string) *Client {
+	t.Helper()
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	c, err := New(context.Background(), log, addr, time.Second, 1, tlsConfig, token)
+	require.NoError(t, err)
+	t.Cleanup(func() { c.conn.Close() })
+	return c
+}
+
+func TestMutualTLS(t *testing.T) {
+	p := newPKI(t)
+	addr, files := startServer(t, p)
+
+	c := newTestClient(t, addr, TLS{
+		Enabled:  true,
+		CAFile:   p.caFile,
+		CertFile: p.certFile,
+		KeyFile:  p.keyFile,
+	}, testToken)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	require.NoError(t, c.Ping(ctx))
+
+	id, err := c.Upload(ctx, bytes.NewReader([]byte("sound")))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("sound"), files.files[int32(id)])
+
+	dst := filepath.Join(t.TempDir(), "source")
+	require.NoError(t, c.Download(ctx, id, dst))
+	data, err := os.ReadFile(dst)
+	require.NoError(t, err)
+	assert.Equal(t, "sound", string(data))
+
+	require.NoError(t, c.Delete(ctx, id))
+	assert.Empty(t, files.files)
+}
+
+func TestTLSRejected(t *testing.T) {
+	p := newPKI(t)
+	other := newPKI(t)
+	addr, _ := startServer(t, p)
+
+	tests := []struct {
+		name  string
+		tls   TLS
+		token string
+		code  codes.Code
+	}{
+		{
+			name:  "no client certificate",
+			tls:   TLS{Enabled: true, CAFile: p.caFile},
+			token: testToken,
+			code:  codes.Unavailable,
+		},
+		{
+			name:  "unknown server",
+			tls:   TLS{Enabled: true, CAFile: other.caFile, CertFile: p.certFile, KeyFile: p.keyFile},
+			token: testToken,
+			code:  codes.Unavailable,
+		},
+		{
+			name:  "unknown client",
+			tls:   TLS{Enabled: true, CAFile: p.caFile, CertFile: other.certFile, KeyFile: other.keyFile},
+			token: testToken,
+			code:  codes.Unavailable,
+		},
+		{
+			name:  "invalid token",
+			tls:   TLS{Enabled: true, CAFile: p.caFile, CertFile: p.certFile, KeyFile: p.keyFile},
+			token: "other",
+			code:  codes.Unauthenticated,
+		},
+		{
+			name: "no token",
+			tls:  TLS{Enabled: true, CAFile: p.caFile, CertFile: p.certFile, KeyFile: p.keyFile},
+			code: codes.Unauthenticated,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(t, addr, tt.tls, tt.token)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			err := c.Delete(ctx, 1)
+			require.Error(t, err)
+			assert.Equal(t, tt.code, status.Code(err))
+		})
+	}
+}
+
+func TestTokenRequiresTLS(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	_, err := New(context.Background(), log, "localhost:0", time.Second, 1, TLS{}, testToken)
+	assert.ErrorIs(t, err, ErrInsecureToken)
+
+	_, err = New(context.Background(), log, "localhost:0", time.Second, 1, TLS{Enabled: true, CAFile: "missing.pem"}, "")
+	assert.Error(t, err)
+}
@@ -13,12 +13,20 @@ type Config struct {
 	LogPath         string        `yaml:"log_path" env-default:""`
 	StoragePath     string        `yaml:"storage_path" env-required:"true"`
 	TokenTTL        time.Duration `yaml:"token_ttl" env-default:"1h"`
+	RefreshTokenTTL time.Duration `yaml:"refresh_token_ttl" env-default:"720h"`
+	LoginThrottle   LoginThrottle `yaml:"login_throttle"`
+	OIDC            OIDC          `yaml:"oidc"`
 	ListenerTimeout time.Duration `yaml:"listener_timeout" env-default:"2s"`
 	HttpServer      HTTPServer    `yaml:"http_server"`
 	Source          SourceStorage `yaml:"source_storage"`
+	Upload          Upload        `yaml:"upload"`
+	Reconcile       Reconcile     `yaml:"reconcile"`
 	Dash            Dash          `yaml:"dash"`
 	DJ              DJ            `yaml:"dj"`
 	Live            Live          `yaml:"live"`
+	Health          Health        `yaml:"health"`
+	Alerts          Alerts        `yaml:"alerts"`
+	Tracing         Tracing       `yaml:"tracing"`
 }
 
 type HTTPServer struct {
@@ -27,6 +35,63 @@ type HTTPServer struct {
 	IddleTimeout    time.Duration `yaml:"idle_timeout" env-default:"60s"`
 	MaxAnswerLength int           `yaml:"max-answer-length" env-default:"100"`
 	TmpDir          string        `yaml:"tmp_dir" env-default:"./tmp"`
+	ProxyHeader     string        `yaml:"proxy_header" env-default:""`
+	// 300 MB is ~ 2.1 hours for .mp3 with 320 kbit/s,
+	// larger sources are sent by resumable upload.
+	BodyLimitMB int64 `yaml:"body_limit_mb" env-default:"300"`
+	// Prometheus metrics are served
+	// separately from the api.
+	MetricsAddress string `yaml:"metrics_address" env-default:"127.0.0.1:9091"`
+}
+
+// Upload keeps unfinished resumable
+// uploads in tmp dir for TTL.
+type Upload struct {
+	TTL       time.Duration `yaml:"ttl" env-default:"24h"`
+	MaxSizeMB int64         `yaml:"max_size_mb" env-default:"2048"`
+}
+
+// Reconcile checks every Interval that sources
+// of library media are downloadable and intact,
+// zero Interval disables it. Timeout limits
+// download of single source.
+//
+// Every check downloads the whole library from
+// source storage, so Interval must leave room
+// for the traffic and time it takes.
+type Reconcile struct {
+	Interval time.Duration `yaml:"interval" env-default:"24h"`
+	Timeout  time.Duration `yaml:"timeout" env-default:"5m"`
+}
+
+// Failed logins are counted per login from the ip
+// (lockout_attempts), per ip (ip_lockout_attempts)
+// and per login from any ip (account_lockout_attempts).
+type LoginThrottle struct {
+	FreeAttempts      int           `yaml:"free_attempts" env-default:"3"`
+	BaseDelay         time.Duration `yaml:"base_delay" env-default:"1s"`
+	MaxDelay          time.Duration `yaml:"max_delay" env-default:"1m"`
+	LockoutAttempts   int           `yaml:"lockout_attempts" env-default:"10"`
+	Lockout           time.Duration `yaml:"lockout" env-default:"15m"`
+	IPFreeAttempts    int           `yaml:"ip_free_attempts" env-default:"10"`
+	IPLockoutAttempts int           `yaml:"ip_lockout_attempts" env-default:"50"`
+	// Higher than lockout_attempts, since anyone
+	// can lock known login out this way.
+	AccountFreeAttempts    int `yaml:"account_free_attempts" env-default:"20"`
+	AccountLockoutAttempts int `yaml:"account_lockout_attempts" env-default:"100"`
+}
+
+// OIDC login is enabled if issuer is set.
+// Client secret is taken from OIDC_CLIENT_SECRET.
+// Identities of existing editors are linked by root.
+type OIDC struct {
+	Issuer        string   `yaml:"issuer" env-default:""`
+	ClientID      string   `yaml:"client_id" env-default:""`
+	RedirectURL   string   `yaml:"redirect_url" env-default:""`
+	Scopes        []string `yaml:"scopes" env-default:"openid,profile,email"`
+	LoginClaim    string   `yaml:"login_claim" env-default:"preferred_username"`
+	AutoProvision bool     `yaml:"auto_provision" env-default:"false"`
+	DefaultRoles  []string `yaml:"default_roles" env-default:"viewer"`
 }
 
 type Dash struct {
@@ -39,17 +104,55 @@ type Dash struct {
 	ClientUpdateFreq time.Duration `yaml:"client_update_freq" env-default:"10s"`
 	DashUpdateFreq   time.Duration `yaml:"dash_update_freq" env-default:"20s"`
 	DashHorizon      time.Duration `yaml:"dash_horizon" env-default:"5m"`
+	// Sources of segments within Prefetch after
+	// horizon are downloaded in advance, 0 disables it.
+	Prefetch time.Duration `yaml:"prefetch" env-default:"10m"`
+	// Size of source cache in megabytes.
+	CacheSizeMB int64 `yaml:"cache_size_mb" env-default:"2048"`
+	// Uncovered time within window is filled
+	// with media having emergency tag, 0 disables it.
+	DeadAirWindow time.Duration `yaml:"dead_air_window" env-default:"30s"`
+	EmergencyTag  string        `yaml:"emergency_tag" env-default:"emergency"`
 }
 
+// SourceStorage keeps media files:
+// "grpc" is external FileService at Addr,
+// "local" is directory Path, "s3" is a bucket
+// of S3-compatible storage, its secret key is
+// taken from S3_SECRET_KEY.
 type SourceStorage struct {
-	Addr       string        `yaml:"addr" env-required:"true"`
+	Type       string        `yaml:"type" env-default:"grpc"`
+	Addr       string        `yaml:"addr" env-default:""`
 	Timeout    time.Duration `yaml:"timeout" env-default:"30s"`
 	RetryCount int           `yaml:"retry" env-default:"5"`
+	Path       string        `yaml:"path" env-default:"./storage/source"`
+	TLS        SourceTLS     `yaml:"tls"`
+	S3         S3            `yaml:"s3"`
+}
+
+// SourceTLS secures connection to gRPC storage,
+// client certificate is sent if set (mTLS).
+// Token is taken from SOURCE_STORAGE_TOKEN.
+type SourceTLS struct {
+	Enabled    bool   `yaml:"enabled" env-default:"false"`
+	CAFile     string `yaml:"ca_file" env-default:""`
+	CertFile   string `yaml:"cert_file" env-default:""`
+	KeyFile    string `yaml:"key_file" env-default:""`
+	ServerName string `yaml:"server_name" env-default:""`
+}
+
+type S3 struct {
+	Endpoint  string `yaml:"endpoint" env-default:""`
+	Region    string `yaml:"region" env-default:"us-east-1"`
+	Bucket    string `yaml:"bucket" env-default:""`
+	AccessKey string `yaml:"access_key" env-default:""`
 }
 
 type DJ struct {
-	DjOnStart   bool   `yaml:"dj_on_start" env-default:"false"`
-	DjCacheFile string `yaml:"cache_file" env-required:"true"`
+	DjOnStart bool `yaml:"dj_on_start" env-default:"false"`
+	// Legacy config file, imported once
+	// if there's no config in storage.
+	DjCacheFile string `yaml:"cache_file" env-default:""`
 }
 
 type Live struct {
@@ -60,6 +163,42 @@ type Live struct {
 	Filters      map[string]string `yaml:"filters"`
 }
 
+// Health sets thresholds of /healthz and /readyz.
+type Health struct {
+	// Manifest must have at least
+	// MinContent of future content.
+	MinContent time.Duration `yaml:"min_content" env-default:"10s"`
+	// Loop is stalled if it missed
+	// its tick by StallTimeout.
+	StallTimeout time.Duration `yaml:"stall_timeout" env-default:"1m"`
+	// Disk usage (fraction) alert threshold.
+	DiskUsage float64 `yaml:"disk_usage" env-default:"0.9"`
+	// Period of checks raising alerts.
+	WatchInterval time.Duration `yaml:"watch_interval" env-default:"1m"`
+}
+
+// Alerts are posted to webhooks as json and
+// sent to telegram chats by bot, bot token
+// is taken from TELEGRAM_BOT_TOKEN.
+type Alerts struct {
+	Webhooks      []string      `yaml:"webhooks"`
+	TelegramAPI   string        `yaml:"telegram_api" env-default:"https://api.telegram.org"`
+	TelegramChats []string      `yaml:"telegram_chats"`
+	Retries       int           `yaml:"retries" env-default:"5"`
+	Backoff       time.Duration `yaml:"backoff" env-default:"1s"`
+	MaxBackoff    time.Duration `yaml:"max_backoff" env-default:"1m"`
+}
+
+// Tracing exports OpenTelemetry spans:
+// "otlp" to collector at Endpoint via gRPC,
+// "stdout" to standard output, empty disables it.
+type Tracing struct {
+	Exporter    string  `yaml:"exporter" env-default:""`
+	Endpoint    string  `yaml:"endpoint" env-default:"localhost:4317"`
+	Insecure    bool    `yaml:"insecure" env-default:"true"`
+	SampleRatio float64 `yaml:"sample_ratio" env-default:"1"`
+}
+
 func MustLoad() *Config {
 	configPath := fetchConfigPath()
 	if configPath == "" {
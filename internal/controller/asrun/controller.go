@@ -0,0 +1,161 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	jwtController "github.com/GintGld/fizteh-radio/internal/controller/jwt"
+	"github.com/GintGld/fizteh-radio/internal/models"
+)
+
+type asRunController struct {
+	timeout time.Duration
+	asRun   AsRun
+}
+
+type AsRun interface {
+	Entries(ctx context.Context, start, stop time.Time) ([]models.AsRunEntry, error)
+	Incidents(ctx context.Context, start, stop time.Time) ([]models.Incident, error)
+}
+
+func New(
+	timeout time.Duration,
+	asRun AsRun,
+	jwtC *jwtController.JWT,
+) *fiber.App {
+	asRunCtr := asRunController{
+		timeout: timeout,
+		asRun:   asRun,
+	}
+
+	app := fiber.New()
+
+	app.Use(jwtC.AuthRequired(), jwtC.Permission(models.PermReports))
+
+	app.Get("/", asRunCtr.entries)
+	app.Get("/incidents", asRunCtr.incidents)
+
+	return app
+}
+
+// entries returns as-run log
+// in json (default) or csv format.
+func (asRunCtr *asRunController) entries(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.UserContext(), asRunCtr.timeout)
+	defer cancel()
+
+	// Default values for cut
+	start := time.Unix(0, 0)
+	stop := time.Date(2100, 1, 1, 0, 0, 0, 0, time.Local)
+
+	if unix := c.QueryInt("start"); unix != 0 {
+		start = time.Unix(int64(unix), 0)
+	}
+	if unix := c.QueryInt("stop"); unix != 0 {
+		stop = time.Unix(int64(unix), 0)
+	}
+
+	if start.After(stop) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid start value",
+		})
+	}
+
+	format := c.Query("format", "json")
+	if format != "json" && format != "csv" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid format",
+		})
+	}
+
+	res, err := asRunCtr.asRun.Entries(ctx, start, stop)
+	if err != nil {
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+
+	if format == "json" {
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"entries": res,
+		})
+	}
+
+	body, err := asRunCSV(res)
+	if err != nil {
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+
+	c.Set(fiber.HeaderContentType, "text/csv")
+	c.Attachment("asrun.csv")
+
+	return c.Status(fiber.StatusOK).Send(body)
+}
+
+// incidents returns playout incidents.
+func (asRunCtr *asRunController) incidents(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.UserContext(), asRunCtr.timeout)
+	defer cancel()
+
+	// Default values for cut
+	start := time.Unix(0, 0)
+	stop := time.Date(2100, 1, 1, 0, 0, 0, 0, time.Local)
+
+	if unix := c.QueryInt("start"); unix != 0 {
+		start = time.Unix(int64(unix), 0)
+	}
+	if unix := c.QueryInt("stop"); unix != 0 {
+		stop = time.Unix(int64(unix), 0)
+	}
+
+	if start.After(stop) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid start value",
+		})
+	}
+
+	res, err := asRunCtr.asRun.Incidents(ctx, start, stop)
+	if err != nil {
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"incidents": res,
+	})
+}
+
+// asRunCSV returns as-run log in csv format.
+func asRunCSV(entries []models.AsRunEntry) ([]byte, error) {
+	var buf bytes.Buffer
+
+	w := csv.NewWriter(&buf)
+	if err := w.Write([]string{
+		"segment_id", "media_id", "live_id", "name", "author",
+		"planned_start", "actual_start", "duration_sec", "source",
+	}); err != nil {
+		return nil, err
+	}
+
+	for _, e := range entries {
+		if err := w.Write([]string{
+			strconv.FormatInt(e.SegmentID, 10),
+			strconv.FormatInt(e.MediaID, 10),
+			strconv.FormatInt(e.LiveID, 10),
+			e.Name,
+			e.Author,
+			e.PlannedStart.Format(time.RFC3339),
+			e.ActualStart.Format(time.RFC3339),
+			strconv.FormatFloat(e.Duration.Seconds(), 'f', 3, 64),
+			e.Source,
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	w.Flush()
+
+	return buf.Bytes(), w.Error()
+}
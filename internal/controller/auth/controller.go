@@ -3,30 +3,40 @@ package auth
 import (
 	"context"
 	"errors"
+	"math"
+	"strconv"
 	"time"
 
+	jwtController "github.com/GintGld/fizteh-radio/internal/controller/jwt"
 	"github.com/GintGld/fizteh-radio/internal/models"
 	"github.com/GintGld/fizteh-radio/internal/service"
+	oidcSrv "github.com/GintGld/fizteh-radio/internal/service/oidc"
 	"github.com/gofiber/fiber/v2"
 )
 
-// TODO: make refresh toleks for better user experience
-
 // New returns an fiber.App that will
 // authorize editors (including root)
 // and return JWT
 func New(
 	timeout time.Duration,
 	a Auth,
+	sso SSO,
+	jwtC *jwtController.JWT,
 ) *fiber.App {
 	authCtr := authController{
 		timeout: timeout,
 		srv:     a,
+		sso:     sso,
 	}
 
 	app := fiber.New()
 
 	app.Post("/", authCtr.login)
+	app.Post("/refresh", authCtr.refresh)
+	app.Post("/logout", jwtC.AuthRequired(), authCtr.logout)
+
+	app.Get("/oidc", authCtr.oidcStart)
+	app.Get("/oidc/callback", authCtr.oidcCallback)
 
 	return app
 }
@@ -34,15 +44,31 @@ func New(
 type authController struct {
 	timeout time.Duration
 	srv     Auth
+	sso     SSO
 }
 
 type Auth interface {
-	Login(ctx context.Context, login string, password string) (string, error)
+	Login(ctx context.Context, login string, password string, ip string) (models.TokenPair, error)
+	Refresh(ctx context.Context, refresh string) (models.TokenPair, error)
+	Logout(ctx context.Context, tokenId string, expires time.Time, refresh string) error
+}
+
+type SSO interface {
+	Start(ctx context.Context) (string, string, error)
+	Callback(ctx context.Context, session, state, code, ip string) (models.TokenPair, error)
+}
+
+// oidcCookie keeps started sso login
+// in the browser until callback.
+const oidcCookie = "oidc_session"
+
+type refreshForm struct {
+	Refresh string `json:"refresh"`
 }
 
 // login
 func (authCtr *authController) login(c *fiber.Ctx) error {
-	ctx, cancel := context.WithTimeout(context.Background(), authCtr.timeout)
+	ctx, cancel := context.WithTimeout(c.UserContext(), authCtr.timeout)
 	defer cancel()
 
 	form := new(models.EditorIn)
@@ -63,8 +89,15 @@ func (authCtr *authController) login(c *fiber.Ctx) error {
 		})
 	}
 
-	token, err := authCtr.srv.Login(ctx, form.Login, form.Pass)
+	tokens, err := authCtr.srv.Login(ctx, form.Login, form.Pass, c.IP())
 	if err != nil {
+		var retryErr *service.RetryAfterError
+		if errors.As(err, &retryErr) {
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(retryErr.After.Seconds()))))
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+				"error": "too many attempts",
+			})
+		}
 		if errors.Is(err, service.ErrInvalidCredentials) {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 				"error": "invalid credentials",
@@ -74,7 +107,163 @@ func (authCtr *authController) login(c *fiber.Ctx) error {
 		return c.SendStatus(fiber.StatusInternalServerError)
 	}
 
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"token": token,
+	return c.Status(fiber.StatusOK).JSON(tokens)
+}
+
+// refresh exchanges refresh token for a new pair
+func (authCtr *authController) refresh(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.UserContext(), authCtr.timeout)
+	defer cancel()
+
+	var form refreshForm
+
+	if err := c.BodyParser(&form); err != nil {
+		return fiber.ErrBadRequest
+	}
+
+	if form.Refresh == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "refresh token required",
+		})
+	}
+
+	tokens, err := authCtr.srv.Refresh(ctx, form.Refresh)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidToken) || errors.Is(err, service.ErrTimeoutToken) {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "invalid refresh token",
+			})
+		}
+
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(tokens)
+}
+
+// logout revokes access token and
+// refresh token given in body
+func (authCtr *authController) logout(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.UserContext(), authCtr.timeout)
+	defer cancel()
+
+	var form refreshForm
+
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&form); err != nil {
+			return fiber.ErrBadRequest
+		}
+	}
+
+	id, expires, ok := jwtController.Session(c)
+	if !ok || id == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "token has no id",
+		})
+	}
+
+	if err := authCtr.srv.Logout(ctx, id, expires, form.Refresh); err != nil {
+		if errors.Is(err, service.ErrInvalidToken) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "invalid refresh token",
+			})
+		}
+
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// oidcStart redirects editor to
+// identity provider for login
+func (authCtr *authController) oidcStart(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.UserContext(), authCtr.timeout)
+	defer cancel()
+
+	url, session, err := authCtr.sso.Start(ctx)
+	if err != nil {
+		if errors.Is(err, service.ErrOIDCDisabled) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "sso login is disabled",
+			})
+		}
+
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+
+	// Lax cookie is sent on redirect back
+	// from provider, but not with requests
+	// from other sites.
+	c.Cookie(&fiber.Cookie{
+		Name:     oidcCookie,
+		Value:    session,
+		Path:     "/",
+		MaxAge:   int(oidcSrv.StateTTL.Seconds()),
+		Secure:   true,
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+
+	return c.Redirect(url, fiber.StatusFound)
+}
+
+// oidcCallback finishes login with
+// identity provider and returns JWT
+func (authCtr *authController) oidcCallback(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.UserContext(), authCtr.timeout)
+	defer cancel()
+
+	if e := c.Query("error"); e != "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "sso login failed: " + e,
+		})
+	}
+
+	state, code := c.Query("state"), c.Query("code")
+	if state == "" || code == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "state and code required",
+		})
+	}
+
+	session := c.Cookies(oidcCookie)
+
+	// Session is single use.
+	c.Cookie(&fiber.Cookie{
+		Name:     oidcCookie,
+		Path:     "/",
+		Expires:  time.Unix(0, 0),
+		Secure:   true,
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
 	})
+
+	tokens, err := authCtr.sso.Callback(ctx, session, state, code, c.IP())
+	if err != nil {
+		if errors.Is(err, service.ErrOIDCDisabled) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "sso login is disabled",
+			})
+		}
+		if errors.Is(err, service.ErrInvalidState) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "invalid state",
+			})
+		}
+		if errors.Is(err, service.ErrInvalidCredentials) {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "sso login failed",
+			})
+		}
+		if errors.Is(err, service.ErrUnknownIdentity) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "unknown editor",
+			})
+		}
+
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(tokens)
 }
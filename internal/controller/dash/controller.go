@@ -2,10 +2,8 @@ package controller
 
 import (
 	"context"
-	"strings"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/golang-jwt/jwt/v5"
 
 	jwtController "github.com/GintGld/fizteh-radio/internal/controller/jwt"
 	"github.com/GintGld/fizteh-radio/internal/models"
@@ -21,14 +19,17 @@ func New(
 ) *fiber.App {
 	app := fiber.New()
 
-	app.Get("/start", rootAccess, func(c *fiber.Ctx) error {
-		go dash.Run(context.TODO())
+	// token validity -> permission -> handling request
+	access := []fiber.Handler{jwtCtr.AuthRequired(), jwtCtr.Permission(models.PermRadio)}
+
+	app.Get("/start", append(access, func(c *fiber.Ctx) error {
+		go dash.Run(context.WithoutCancel(c.UserContext()))
 		return c.SendStatus(fiber.StatusOK)
-	})
-	app.Get("/stop", rootAccess, func(c *fiber.Ctx) error {
+	})...)
+	app.Get("/stop", append(access, func(c *fiber.Ctx) error {
 		go dash.Stop()
 		return c.SendStatus(fiber.StatusOK)
-	})
+	})...)
 
 	return app
 }
@@ -37,34 +38,3 @@ type DashService interface {
 	Run(context.Context) error
 	Stop()
 }
-
-// rootAccess check if the logged user is root,
-// but doesn't check validity, because only jwtWare
-// has access to the secret
-func rootAccess(c *fiber.Ctx) error {
-	auth := c.Get(fiber.HeaderAuthorization)
-
-	jwtSplitted := strings.Split(auth, " ")
-	if len(jwtSplitted) != 2 || jwtSplitted[0] != "Bearer" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "invalid JWT",
-		})
-	}
-
-	token := jwtSplitted[1]
-	claims := jwt.MapClaims{}
-	_, _, err := jwt.NewParser().ParseUnverified(token, claims)
-	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "invalid JWT",
-		})
-	}
-
-	if claims["login"] != models.RootLogin {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "available for root only",
-		})
-	}
-
-	return c.Next()
-}
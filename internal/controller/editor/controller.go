@@ -0,0 +1,151 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	jwtController "github.com/GintGld/fizteh-radio/internal/controller/jwt"
+	"github.com/GintGld/fizteh-radio/internal/models"
+	"github.com/GintGld/fizteh-radio/internal/service"
+)
+
+type editorController struct {
+	timeout time.Duration
+	srv     Editor
+}
+
+type Editor interface {
+	Me(ctx context.Context) (models.EditorOut, error)
+	UpdateProfile(ctx context.Context, profile models.EditorProfile) error
+	ChangePassword(ctx context.Context, current, pass string) error
+}
+
+// New returns fiber app that lets
+// logged editor manage own account.
+func New(
+	timeout time.Duration,
+	srv Editor,
+	jwtC *jwtController.JWT,
+) *fiber.App {
+	edtCtr := editorController{
+		timeout: timeout,
+		srv:     srv,
+	}
+
+	app := fiber.New()
+
+	app.Use(jwtC.AuthRequired())
+
+	app.Get("/me", edtCtr.me)
+	app.Put("/me", edtCtr.updateProfile)
+	app.Put("/me/pass", edtCtr.changePassword)
+
+	return app
+}
+
+// me returns account of the logged editor
+func (edtCtr *editorController) me(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.UserContext(), edtCtr.timeout)
+	defer cancel()
+
+	out, err := edtCtr.srv.Me(ctx)
+	if err != nil {
+		if errors.Is(err, service.ErrEditorNotFound) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "editor not found",
+			})
+		}
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"editor": out,
+	})
+}
+
+// updateProfile updates profile of the logged editor
+func (edtCtr *editorController) updateProfile(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.UserContext(), edtCtr.timeout)
+	defer cancel()
+
+	var profile models.EditorProfile
+
+	if err := c.BodyParser(&profile); err != nil {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+
+	if err := edtCtr.srv.UpdateProfile(ctx, profile); err != nil {
+		if errors.Is(err, service.ErrRootReadOnly) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "root account is read-only",
+			})
+		}
+		if errors.Is(err, service.ErrEditorNotFound) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "editor not found",
+			})
+		}
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// changePassword changes password of the logged editor
+func (edtCtr *editorController) changePassword(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.UserContext(), edtCtr.timeout)
+	defer cancel()
+
+	var form struct {
+		Current string `json:"current"`
+		New     string `json:"new"`
+	}
+
+	if err := c.BodyParser(&form); err != nil {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+
+	if form.New == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "password can't be empty",
+		})
+	}
+
+	if err := edtCtr.srv.ChangePassword(ctx, form.Current, form.New); err != nil {
+		if errors.Is(err, service.ErrRootReadOnly) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "root account is read-only",
+			})
+		}
+		if errors.Is(err, service.ErrPasswordTooShort) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "password is too short",
+			})
+		}
+		var retryErr *service.RetryAfterError
+		if errors.As(err, &retryErr) {
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(retryErr.After.Seconds()))))
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+				"error": "too many attempts",
+			})
+		}
+		if errors.Is(err, service.ErrInvalidCredentials) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "invalid credentials",
+			})
+		}
+		if errors.Is(err, service.ErrEditorNotFound) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "editor not found",
+			})
+		}
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
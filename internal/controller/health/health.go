@@ -0,0 +1,52 @@
+package controller
+
+import (
+	"context"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/GintGld/fizteh-radio/internal/models"
+)
+
+type healthController struct {
+	health Health
+}
+
+type Health interface {
+	Live(ctx context.Context) models.Health
+	Ready(ctx context.Context) models.Health
+}
+
+func New(
+	health Health,
+) *fiber.App {
+	healthCtr := healthController{
+		health: health,
+	}
+
+	app := fiber.New()
+
+	app.Get("/healthz", healthCtr.live)
+	app.Get("/readyz", healthCtr.ready)
+
+	return app
+}
+
+// live reports if process is not wedged.
+func (healthCtr *healthController) live(c *fiber.Ctx) error {
+	return respond(c, healthCtr.health.Live(c.UserContext()))
+}
+
+// ready reports if radio is able to stream.
+func (healthCtr *healthController) ready(c *fiber.Ctx) error {
+	return respond(c, healthCtr.health.Ready(c.UserContext()))
+}
+
+func respond(c *fiber.Ctx, h models.Health) error {
+	status := fiber.StatusOK
+	if h.Status == models.HealthFail {
+		status = fiber.StatusServiceUnavailable
+	}
+
+	return c.Status(status).JSON(h)
+}
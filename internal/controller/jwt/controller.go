@@ -1,19 +1,73 @@
 package jwtController
 
 import (
+	"slices"
+	"time"
+
 	jwtware "github.com/gofiber/contrib/jwt"
 	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/GintGld/fizteh-radio/internal/lib/caller"
+	"github.com/GintGld/fizteh-radio/internal/models"
 )
 
+// APIKeyHeader is a header used by machine
+// clients instead of Bearer token.
+const APIKeyHeader = "X-API-Key"
+
+// apiKeyLocal is a key of verified
+// api key in request locals.
+const apiKeyLocal = "apikey"
+
 type JWT struct {
-	secret []byte
+	secret  []byte
+	revoker Revoker
+	keys    KeyChecker
 }
 
-func New(secret []byte) *JWT {
-	return &JWT{secret: secret}
+type Revoker interface {
+	Revoked(id string, editorId int64, issued time.Time) bool
 }
 
+type KeyChecker interface {
+	Key(secret string) (models.APIKey, bool)
+}
+
+func New(secret []byte, revoker Revoker, keys KeyChecker) *JWT {
+	return &JWT{
+		secret:  secret,
+		revoker: revoker,
+		keys:    keys,
+	}
+}
+
+// AuthRequired allows request with valid
+// Bearer token or api key.
 func (jwtController *JWT) AuthRequired() func(*fiber.Ctx) error {
+	tokenAuth := jwtController.tokenAuth()
+
+	return func(c *fiber.Ctx) error {
+		secret := c.Get(APIKeyHeader)
+		if secret == "" {
+			return tokenAuth(c)
+		}
+
+		key, ok := jwtController.keys.Key(secret)
+		if !ok {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "authentication error",
+			})
+		}
+
+		c.Locals(apiKeyLocal, key)
+		c.SetUserContext(caller.WithAPIKey(c.UserContext(), key))
+
+		return c.Next()
+	}
+}
+
+func (jwtController *JWT) tokenAuth() func(*fiber.Ctx) error {
 	return jwtware.New(jwtware.Config{
 		SigningKey: jwtware.SigningKey{Key: jwtController.secret},
 		ErrorHandler: func(c *fiber.Ctx, err error) error {
@@ -21,5 +75,125 @@ func (jwtController *JWT) AuthRequired() func(*fiber.Ctx) error {
 				"error": "authentication error",
 			})
 		},
+		SuccessHandler: func(c *fiber.Ctx) error {
+			editor, _ := Editor(c)
+			id, _, _ := Session(c)
+			if jwtController.revoker.Revoked(id, editor.ID, issued(c)) {
+				return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+					"error": "token revoked",
+				})
+			}
+
+			c.SetUserContext(caller.WithEditor(c.UserContext(), editor.ID, editor.Login, editor.Roles...))
+
+			return c.Next()
+		},
 	})
 }
+
+// Permission allows request only if token
+// owner has given permission.
+// Must be used after AuthRequired.
+func (jwtController *JWT) Permission(perm models.Permission) func(*fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		if key, ok := APIKey(c); ok {
+			if !slices.Contains(key.Permissions, perm) {
+				return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+					"error": "permission denied",
+				})
+			}
+			return c.Next()
+		}
+
+		editor, ok := Editor(c)
+		if !ok {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "authentication error",
+			})
+		}
+
+		if !models.HasPermission(editor.Roles, perm) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "permission denied",
+			})
+		}
+
+		return c.Next()
+	}
+}
+
+// Editor returns owner of the token
+// verified by AuthRequired.
+func Editor(c *fiber.Ctx) (models.Editor, bool) {
+	claims, ok := tokenClaims(c)
+	if !ok {
+		return models.Editor{}, false
+	}
+
+	var editor models.Editor
+
+	if uid, ok := claims["uid"].(float64); ok {
+		editor.ID = int64(uid)
+	}
+	editor.Login, _ = claims["login"].(string)
+
+	roles, _ := claims["roles"].([]any)
+	for _, r := range roles {
+		if role, ok := r.(string); ok {
+			editor.Roles = append(editor.Roles, models.Role(role))
+		}
+	}
+
+	return editor, true
+}
+
+// APIKey returns api key
+// verified by AuthRequired.
+func APIKey(c *fiber.Ctx) (models.APIKey, bool) {
+	key, ok := c.Locals(apiKeyLocal).(models.APIKey)
+	return key, ok
+}
+
+// Session returns id and expiration time
+// of the token verified by AuthRequired.
+func Session(c *fiber.Ctx) (string, time.Time, bool) {
+	claims, ok := tokenClaims(c)
+	if !ok {
+		return "", time.Time{}, false
+	}
+
+	id, _ := claims["jti"].(string)
+
+	exp, err := claims.GetExpirationTime()
+	if err != nil || exp == nil {
+		return id, time.Time{}, true
+	}
+
+	return id, exp.Time, true
+}
+
+// issued returns issue time of the token.
+// Tokens without it are considered issued at epoch.
+func issued(c *fiber.Ctx) time.Time {
+	claims, ok := tokenClaims(c)
+	if !ok {
+		return time.Time{}
+	}
+
+	iat, err := claims.GetIssuedAt()
+	if err != nil || iat == nil {
+		return time.Time{}
+	}
+
+	return iat.Time
+}
+
+func tokenClaims(c *fiber.Ctx) (jwt.MapClaims, bool) {
+	token, ok := c.Locals("user").(*jwt.Token)
+	if !ok {
+		return nil, false
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	return claims, ok
+}
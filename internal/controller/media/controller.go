@@ -1,9 +1,13 @@
 package controller
 
 import (
+	"bufio"
 	"context"
 	"encoding/json"
 	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
 	"os"
 	"strconv"
 	"strings"
@@ -13,24 +17,43 @@ import (
 	"github.com/gofiber/fiber/v2"
 
 	jwtController "github.com/GintGld/fizteh-radio/internal/controller/jwt"
+	"github.com/GintGld/fizteh-radio/internal/lib/bodylimit"
 	"github.com/GintGld/fizteh-radio/internal/models"
 	"github.com/GintGld/fizteh-radio/internal/service"
 )
 
 // TODO: check if controller really delete tmp files
 
+const (
+	// maxMediaInfo limits "media" part of form.
+	maxMediaInfo = 1 << 20
+	// mimeDetectSize is a number of bytes
+	// used to recognize source type.
+	mimeDetectSize = 3072
+
+	headerUploadOffset = "Upload-Offset"
+	headerUploadLength = "Upload-Length"
+	contentTypeOffset  = "application/offset+octet-stream"
+)
+
 func New(
 	timeout time.Duration,
 	srvMedia Media,
 	srvSrc Source,
+	srvUpload Upload,
+	srvReconcile Reconciler,
+	audit Auditor,
 	jwtC *jwtController.JWT,
 	tmpDir string,
 ) *fiber.App {
 	mediaCtr := mediaController{
-		timeout:  timeout,
-		srvMedia: srvMedia,
-		srvSrc:   srvSrc,
-		tmpDir:   tmpDir,
+		timeout:      timeout,
+		srvMedia:     srvMedia,
+		srvSrc:       srvSrc,
+		srvUpload:    srvUpload,
+		srvReconcile: srvReconcile,
+		audit:        audit,
+		tmpDir:       tmpDir,
 	}
 
 	app := fiber.New(fiber.Config{
@@ -39,31 +62,47 @@ func New(
 
 	app.Use(jwtC.AuthRequired())
 
+	read := jwtC.Permission(models.PermLibraryRead)
+	write := jwtC.Permission(models.PermLibraryWrite)
+
 	// Media
-	app.Get("/media", mediaCtr.searchMedia)
-	app.Post("/media", mediaCtr.newMedia)
-	app.Put("/media", mediaCtr.updateMedia)
-	app.Get("/media/:id", mediaCtr.media)
-	app.Get("/source/:id", mediaCtr.source)
-	app.Delete("/media/:id", mediaCtr.deleteMedia)
+	app.Get("/media", read, mediaCtr.searchMedia)
+	app.Post("/media", write, mediaCtr.newMedia)
+	app.Put("/media", write, mediaCtr.updateMedia)
+	app.Get("/media/:id", read, mediaCtr.media)
+	app.Get("/source/:id", read, mediaCtr.source)
+	app.Delete("/media/:id", write, mediaCtr.deleteMedia)
+
+	// Resumable uploads
+	app.Post("/upload", write, mediaCtr.newUpload)
+	app.Head("/upload/:id", write, mediaCtr.uploadOffset)
+	app.Patch("/upload/:id", write, mediaCtr.appendUpload)
+	app.Delete("/upload/:id", write, mediaCtr.deleteUpload)
+
+	// Integrity of sources
+	app.Get("/reconcile", read, mediaCtr.reconcileReport)
+	app.Post("/reconcile", write, mediaCtr.reconcile)
 
 	// Tags
-	app.Get("/tag/types", mediaCtr.tagTypes)
-	app.Get("/tag", mediaCtr.allTags)
-	app.Post("/tag", mediaCtr.newTag)
-	app.Put("/tag", mediaCtr.updateTag)
-	app.Get("/tag/:id", mediaCtr.tag)
-	app.Delete("/tag/:id", mediaCtr.deleteTag)
-	app.Post("/tag/multi/:id", mediaCtr.multiTag)
+	app.Get("/tag/types", read, mediaCtr.tagTypes)
+	app.Get("/tag", read, mediaCtr.allTags)
+	app.Post("/tag", write, mediaCtr.newTag)
+	app.Put("/tag", write, mediaCtr.updateTag)
+	app.Get("/tag/:id", read, mediaCtr.tag)
+	app.Delete("/tag/:id", write, mediaCtr.deleteTag)
+	app.Post("/tag/multi/:id", write, mediaCtr.multiTag)
 
 	return app
 }
 
 type mediaController struct {
-	timeout  time.Duration
-	srvMedia Media
-	srvSrc   Source
-	tmpDir   string
+	timeout      time.Duration
+	srvMedia     Media
+	srvSrc       Source
+	srvUpload    Upload
+	srvReconcile Reconciler
+	audit        Auditor
+	tmpDir       string
 }
 
 type Media interface {
@@ -85,11 +124,35 @@ type Media interface {
 }
 
 type Source interface {
-	UploadSource(ctx context.Context, path string, media *models.Media) error
-	LoadSource(ctx context.Context, destDir string, media models.Media) (string, error)
+	UploadSource(ctx context.Context, r io.Reader, media *models.Media) error
+	LoadSource(ctx context.Context, dst string, media models.Media) error
 	DeleteSource(ctx context.Context, media models.Media) error
 }
 
+type Upload interface {
+	Create(ctx context.Context, length int64, media models.Media) (models.Upload, error)
+	Upload(ctx context.Context, id string) (models.Upload, error)
+	Append(ctx context.Context, id string, offset int64, r io.Reader) (models.Upload, error)
+	Delete(ctx context.Context, id string) error
+}
+
+type Reconciler interface {
+	Start(ctx context.Context) error
+	Report() (models.ReconcileReport, bool)
+}
+
+type Auditor interface {
+	Record(ctx context.Context, action, entity string, id int64, before, after any)
+}
+
+// record saves change made by the request owner.
+func (mediaCtr *mediaController) record(c *fiber.Ctx, action, entity string, id int64, before, after any) {
+	ctx, cancel := context.WithTimeout(c.UserContext(), mediaCtr.timeout)
+	defer cancel()
+
+	mediaCtr.audit.Record(ctx, action, entity, id, before, after)
+}
+
 // TODO: add support for AAC, WAV
 
 // TODO: add PUT method for source
@@ -109,7 +172,7 @@ func (mediaCtr *mediaController) searchMedia(c *fiber.Ctx) error {
 		MaxRespLen: c.QueryInt("res_len"),
 	}
 
-	lib, err := mediaCtr.srvMedia.SearchMedia(context.TODO(), filter)
+	lib, err := mediaCtr.srvMedia.SearchMedia(c.UserContext(), filter)
 	if err != nil {
 		return c.SendStatus(fiber.StatusInternalServerError)
 	}
@@ -119,118 +182,388 @@ func (mediaCtr *mediaController) searchMedia(c *fiber.Ctx) error {
 	})
 }
 
-// newMedia saves sended file and creates media
+// newMedia streams sent file to storage and creates media.
+// Body isn't saved, so "media" and "source" parts
+// are read in the order they're sent.
 func (mediaCtr *mediaController) newMedia(c *fiber.Ctx) error {
-	ctx, cancel := context.WithTimeout(context.Background(), mediaCtr.timeout)
-	defer cancel()
-
-	payload := c.FormValue("media")
-	if payload == "" {
+	_, params, err := mime.ParseMediaType(c.Get(fiber.HeaderContentType))
+	if err != nil || params["boundary"] == "" {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "no media information",
+			"error": "invalid multipart form",
 		})
 	}
+	reader := multipart.NewReader(bodylimit.Body(c), params["boundary"])
+
+	var (
+		media    *models.Media
+		source   models.Media // source id, duration and checksum
+		uploaded bool
+		created  bool
+	)
+	// Source without media is useless.
+	defer func() {
+		if uploaded && !created {
+			ctx, cancel := context.WithTimeout(context.Background(), mediaCtr.timeout)
+			defer cancel()
+			mediaCtr.srvSrc.DeleteSource(ctx, source)
+		}
+	}()
 
-	var media models.Media
-	if err := json.Unmarshal([]byte(payload), &media); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "invalid media information",
-		})
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if bodylimit.Exceeded(c) {
+			return bodyTooLarge(c)
+		}
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "invalid multipart form",
+			})
+		}
+
+		switch part.FormName() {
+		case "media":
+			payload, err := io.ReadAll(io.LimitReader(part, maxMediaInfo))
+			if bodylimit.Exceeded(c) {
+				return bodyTooLarge(c)
+			}
+			if err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error": "invalid multipart form",
+				})
+			}
+			media = &models.Media{}
+			if err := json.Unmarshal(payload, media); err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error": "invalid media information",
+				})
+			}
+			if msg := validateNewMedia(*media); msg != "" {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error": msg,
+				})
+			}
+		case "source":
+			if uploaded {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error": "multiple sources",
+				})
+			}
+
+			r, msg, err := audioReader(part)
+			if bodylimit.Exceeded(c) {
+				return bodyTooLarge(c)
+			}
+			if err != nil {
+				return c.SendStatus(fiber.StatusInternalServerError)
+			}
+			if msg != "" {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error": msg,
+				})
+			}
+
+			// Upload lasts as long as client sends
+			// data, so request timeout isn't applied.
+			// TODO: enhance error statuses
+			if err := mediaCtr.srvSrc.UploadSource(c.UserContext(), r, &source); err != nil {
+				if bodylimit.Exceeded(c) {
+					return bodyTooLarge(c)
+				}
+				return c.SendStatus(fiber.StatusInternalServerError)
+			}
+			uploaded = true
+		}
 	}
 
-	if media.Name == nil {
+	if media == nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "name required",
+			"error": "no media information",
 		})
 	}
-	if media.Author == nil {
+	if !uploaded {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "author required",
+			"error": "invalid file",
 		})
 	}
-	if media.ID != nil {
+	media.SourceID = source.SourceID
+	media.Duration = source.Duration
+	media.Checksum = source.Checksum
+
+	ctx, cancel := context.WithTimeout(c.UserContext(), mediaCtr.timeout)
+	defer cancel()
+
+	id, err := mediaCtr.srvMedia.NewMedia(ctx, *media)
+	if err != nil {
+		if errors.Is(err, service.ErrTagNotFound) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "tag not found",
+			})
+		}
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+	created = true
+
+	media.ID = &id
+	mediaCtr.record(c, models.AuditCreate, models.AuditMedia, id, nil, *media)
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"id": id,
+	})
+}
+
+// newUpload starts resumable upload of source,
+// media is created when all data is received.
+func (mediaCtr *mediaController) newUpload(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.UserContext(), mediaCtr.timeout)
+	defer cancel()
+
+	var request struct {
+		Media  models.Media `json:"media"`
+		Length int64        `json:"length"`
+	}
+
+	if err := c.BodyParser(&request); err != nil {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+
+	if msg := validateNewMedia(request.Media); msg != "" {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "unexpected id",
+			"error": msg,
 		})
 	}
-	if media.Duration != nil {
+	if request.Length <= 0 {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "unexpected duration",
+			"error": "invalid length",
 		})
 	}
 
-	file, err := c.FormFile("source")
+	upload, err := mediaCtr.srvUpload.Create(ctx, request.Length, request.Media)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "invalid file",
+		if errors.Is(err, service.ErrUploadTooLarge) {
+			return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{
+				"error": "upload too large",
+			})
+		}
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"upload": upload,
+	})
+}
+
+// uploadOffset reports number of received bytes,
+// upload is resumed from it.
+func (mediaCtr *mediaController) uploadOffset(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.UserContext(), mediaCtr.timeout)
+	defer cancel()
+
+	upload, err := mediaCtr.srvUpload.Upload(ctx, c.Params("id"))
+	if err != nil {
+		if errors.Is(err, service.ErrUploadNotFound) {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	c.Set(headerUploadOffset, strconv.FormatInt(upload.Offset, 10))
+	c.Set(headerUploadLength, strconv.FormatInt(upload.Length, 10))
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// appendUpload writes request body to upload
+// starting at Upload-Offset. Media id is returned
+// after the last chunk, new offset otherwise.
+func (mediaCtr *mediaController) appendUpload(c *fiber.Ctx) error {
+	if c.Get(fiber.HeaderContentType) != contentTypeOffset {
+		return c.Status(fiber.StatusUnsupportedMediaType).JSON(fiber.Map{
+			"error": "content-type must be " + contentTypeOffset,
 		})
 	}
 
-	fileType := file.Header.Get("Content-Type")
-	if fileType == "" {
+	offset, err := strconv.ParseInt(c.Get(headerUploadOffset), 10, 64)
+	if err != nil || offset < 0 {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "content-type not found",
+			"error": "invalid upload offset",
 		})
 	}
 
-	// recognize MIME-type (allow only auido.mpeg == .mp3)
-	if fileType != "application/octet-stream" && fileType != "audio/mpeg" {
+	id := c.Params("id")
+
+	ctx, cancel := context.WithTimeout(c.UserContext(), mediaCtr.timeout)
+	upload, err := mediaCtr.srvUpload.Upload(ctx, id)
+	cancel()
+	if err != nil {
+		if errors.Is(err, service.ErrUploadNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "upload not found",
+			})
+		}
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+	if length := int64(c.Request().Header.ContentLength()); length > 0 && offset+length > upload.Length {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "unsupported mime-type",
+			"error": "chunk exceeds upload length",
 		})
-	} else if fileType == "application/octet-stream" {
-		reader, err := file.Open()
-		if err != nil {
-			return c.SendStatus(fiber.StatusInternalServerError)
+	}
+
+	// Chunk lasts as long as client sends
+	// data, so request timeout isn't applied.
+	upload, err = mediaCtr.srvUpload.Append(c.UserContext(), id, offset, bodylimit.Body(c))
+	if err != nil {
+		if errors.Is(err, service.ErrUploadNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "upload not found",
+			})
 		}
-		mimeType, err := mimetype.DetectReader(reader)
-		if err != nil {
-			return c.SendStatus(fiber.StatusInternalServerError)
+		if errors.Is(err, service.ErrUploadOffset) {
+			c.Set(headerUploadOffset, strconv.FormatInt(upload.Offset, 10))
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "offset mismatch",
+			})
 		}
-		if !mimeType.Is("audio/mpeg") {
+		if errors.Is(err, service.ErrUploadLocked) {
+			return c.Status(fiber.StatusLocked).JSON(fiber.Map{
+				"error": "upload is in progress",
+			})
+		}
+		if errors.Is(err, service.ErrTagNotFound) {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "unsupported mime-type",
+				"error": "tag not found",
 			})
 		}
+		if bodylimit.Exceeded(c) {
+			c.Set(headerUploadOffset, strconv.FormatInt(upload.Offset, 10))
+			return bodyTooLarge(c)
+		}
+		return c.SendStatus(fiber.StatusInternalServerError)
 	}
 
-	tmpFile, err := os.CreateTemp(mediaCtr.tmpDir, "*.mp3")
-	if err != nil {
-		return c.SendStatus(fiber.StatusInternalServerError)
+	if upload.MediaID == nil {
+		c.Set(headerUploadOffset, strconv.FormatInt(upload.Offset, 10))
+		return c.SendStatus(fiber.StatusNoContent)
 	}
-	tmpFileName := tmpFile.Name()
-	defer tmpFile.Close()
 
-	if err := c.SaveFile(file, tmpFileName); err != nil {
+	upload.Media.ID = upload.MediaID
+	mediaCtr.record(c, models.AuditCreate, models.AuditMedia, *upload.MediaID, nil, upload.Media)
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"id": *upload.MediaID,
+	})
+}
+
+// deleteUpload cancels upload.
+func (mediaCtr *mediaController) deleteUpload(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.UserContext(), mediaCtr.timeout)
+	defer cancel()
+
+	if err := mediaCtr.srvUpload.Delete(ctx, c.Params("id")); err != nil {
+		if errors.Is(err, service.ErrUploadNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "upload not found",
+			})
+		}
+		if errors.Is(err, service.ErrUploadLocked) {
+			return c.Status(fiber.StatusLocked).JSON(fiber.Map{
+				"error": "upload is in progress",
+			})
+		}
 		return c.SendStatus(fiber.StatusInternalServerError)
 	}
-	defer os.Remove(tmpFileName)
 
-	// TODO: move this code to goroutine
+	return c.SendStatus(fiber.StatusNoContent)
+}
 
-	// TODO: enhance error statuses
-	if err := mediaCtr.srvSrc.UploadSource(ctx, tmpFileName, &media); err != nil {
-		return c.SendStatus(fiber.StatusInternalServerError)
+// reconcileReport returns report
+// of the last integrity check.
+func (mediaCtr *mediaController) reconcileReport(c *fiber.Ctx) error {
+	report, ok := mediaCtr.srvReconcile.Report()
+	if !ok {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "library wasn't checked yet",
+		})
 	}
 
-	id, err := mediaCtr.srvMedia.NewMedia(ctx, media)
-	if err != nil {
-		if errors.Is(err, service.ErrTagNotFound) {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "tag not found",
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"report": report,
+	})
+}
+
+// reconcile starts integrity check,
+// report is available when it's finished.
+func (mediaCtr *mediaController) reconcile(c *fiber.Ctx) error {
+	if err := mediaCtr.srvReconcile.Start(c.UserContext()); err != nil {
+		if errors.Is(err, service.ErrReconcileRunning) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "check is running",
 			})
 		}
 		return c.SendStatus(fiber.StatusInternalServerError)
 	}
 
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"id": id,
+	return c.SendStatus(fiber.StatusAccepted)
+}
+
+// validateNewMedia returns error message
+// if media can't be created, empty otherwise.
+func validateNewMedia(media models.Media) string {
+	switch {
+	case media.Name == nil:
+		return "name required"
+	case media.Author == nil:
+		return "author required"
+	case media.ID != nil:
+		return "unexpected id"
+	case media.Duration != nil:
+		return "unexpected duration"
+	}
+	return ""
+}
+
+// audioReader checks that part contains mp3 and returns
+// its reader, message is set if type is unsupported.
+func audioReader(part *multipart.Part) (io.Reader, string, error) {
+	fileType := part.Header.Get(fiber.HeaderContentType)
+	if fileType == "" {
+		return nil, "content-type not found", nil
+	}
+
+	// recognize MIME-type (allow only auido.mpeg == .mp3)
+	if fileType != "application/octet-stream" && fileType != "audio/mpeg" {
+		return nil, "unsupported mime-type", nil
+	}
+
+	r := bufio.NewReaderSize(part, mimeDetectSize)
+	if fileType == "application/octet-stream" {
+		// Head is peeked, so it's sent to storage too.
+		head, err := r.Peek(mimeDetectSize)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, "", err
+		}
+		if !mimetype.Detect(head).Is("audio/mpeg") {
+			return nil, "unsupported mime-type", nil
+		}
+	}
+
+	return r, "", nil
+}
+
+// bodyTooLarge responds that request
+// body exceeds server limit.
+func bodyTooLarge(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{
+		"error": "body too large",
 	})
 }
 
 // updateMedia updates media information
 func (mediaCtr *mediaController) updateMedia(c *fiber.Ctx) error {
-	ctx, cancel := context.WithTimeout(context.Background(), mediaCtr.timeout)
+	ctx, cancel := context.WithTimeout(c.UserContext(), mediaCtr.timeout)
 	defer cancel()
 
 	var request struct {
@@ -257,6 +590,10 @@ func (mediaCtr *mediaController) updateMedia(c *fiber.Ctx) error {
 		})
 	}
 
+	// snapshot for audit, errors are
+	// handled by update itself
+	before, _ := mediaCtr.srvMedia.Media(ctx, *request.Media.ID)
+
 	if err := mediaCtr.srvMedia.UpdateMedia(ctx, request.Media); err != nil {
 		if errors.Is(err, service.ErrMediaNotFound) {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
@@ -271,12 +608,14 @@ func (mediaCtr *mediaController) updateMedia(c *fiber.Ctx) error {
 		return c.SendStatus(fiber.StatusInternalServerError)
 	}
 
+	mediaCtr.record(c, models.AuditUpdate, models.AuditMedia, *request.Media.ID, before, request.Media)
+
 	return c.SendStatus(fiber.StatusOK)
 }
 
 // multiTag add tag to media list
 func (mediaCtr *mediaController) multiTag(c *fiber.Ctx) error {
-	ctx, cancel := context.WithTimeout(context.Background(), mediaCtr.timeout)
+	ctx, cancel := context.WithTimeout(c.UserContext(), mediaCtr.timeout)
 	defer cancel()
 
 	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
@@ -319,12 +658,17 @@ func (mediaCtr *mediaController) multiTag(c *fiber.Ctx) error {
 		return c.SendStatus(fiber.StatusBadRequest)
 	}
 
+	mediaCtr.record(c, models.AuditUpdate, models.AuditTag, id, nil, fiber.Map{
+		"tag":   tag,
+		"media": request.Ids,
+	})
+
 	return c.SendStatus(fiber.StatusOK)
 }
 
 // media return json with media by id
 func (mediaCtr *mediaController) media(c *fiber.Ctx) error {
-	ctx, cancel := context.WithTimeout(context.Background(), mediaCtr.timeout)
+	ctx, cancel := context.WithTimeout(c.UserContext(), mediaCtr.timeout)
 	defer cancel()
 
 	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
@@ -352,7 +696,7 @@ func (mediaCtr *mediaController) media(c *fiber.Ctx) error {
 // source returns source file
 // corresponding to media
 func (mediaCtr *mediaController) source(c *fiber.Ctx) error {
-	ctx, cancel := context.WithTimeout(context.Background(), mediaCtr.timeout)
+	ctx, cancel := context.WithTimeout(c.UserContext(), mediaCtr.timeout)
 	defer cancel()
 
 	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
@@ -372,18 +716,26 @@ func (mediaCtr *mediaController) source(c *fiber.Ctx) error {
 		return c.SendStatus(fiber.StatusInternalServerError)
 	}
 
-	// TODO: enhance error statuses
-	sourceFile, err := mediaCtr.srvSrc.LoadSource(ctx, mediaCtr.tmpDir, media)
+	sourceFile, err := os.CreateTemp(mediaCtr.tmpDir, "*.mp3")
 	if err != nil {
-		c.SendStatus(fiber.StatusInternalServerError)
+		return c.SendStatus(fiber.StatusInternalServerError)
 	}
+	sourceFile.Close()
+	// File is opened by SendFile,
+	// so it can be removed after.
+	defer os.Remove(sourceFile.Name())
 
-	return c.Status(fiber.StatusOK).SendFile(sourceFile)
+	// TODO: enhance error statuses
+	if err := mediaCtr.srvSrc.LoadSource(ctx, sourceFile.Name(), media); err != nil {
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+
+	return c.Status(fiber.StatusOK).SendFile(sourceFile.Name())
 }
 
 // deleteEditor deletes editor
 func (mediaCtr *mediaController) deleteMedia(c *fiber.Ctx) error {
-	ctx, cancel := context.WithTimeout(context.Background(), mediaCtr.timeout)
+	ctx, cancel := context.WithTimeout(c.UserContext(), mediaCtr.timeout)
 	defer cancel()
 
 	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
@@ -413,11 +765,13 @@ func (mediaCtr *mediaController) deleteMedia(c *fiber.Ctx) error {
 		return c.SendStatus(fiber.StatusInternalServerError)
 	}
 
+	mediaCtr.record(c, models.AuditDelete, models.AuditMedia, id, media, nil)
+
 	return c.SendStatus(fiber.StatusOK)
 }
 
 func (mediaCtr *mediaController) tagTypes(c *fiber.Ctx) error {
-	ctx, cancel := context.WithTimeout(context.Background(), mediaCtr.timeout)
+	ctx, cancel := context.WithTimeout(c.UserContext(), mediaCtr.timeout)
 	defer cancel()
 
 	tags, err := mediaCtr.srvMedia.TagTypes(ctx)
@@ -432,7 +786,7 @@ func (mediaCtr *mediaController) tagTypes(c *fiber.Ctx) error {
 
 // allTags returns all registered tags.
 func (mediaCtr *mediaController) allTags(c *fiber.Ctx) error {
-	ctx, cancel := context.WithTimeout(context.Background(), mediaCtr.timeout)
+	ctx, cancel := context.WithTimeout(c.UserContext(), mediaCtr.timeout)
 	defer cancel()
 
 	tags, err := mediaCtr.srvMedia.AllTags(ctx)
@@ -447,7 +801,7 @@ func (mediaCtr *mediaController) allTags(c *fiber.Ctx) error {
 
 // newTag create new tag.
 func (mediaCtr *mediaController) newTag(c *fiber.Ctx) error {
-	ctx, cancel := context.WithTimeout(context.Background(), mediaCtr.timeout)
+	ctx, cancel := context.WithTimeout(c.UserContext(), mediaCtr.timeout)
 	defer cancel()
 
 	var request struct {
@@ -479,6 +833,9 @@ func (mediaCtr *mediaController) newTag(c *fiber.Ctx) error {
 		return c.SendStatus(fiber.StatusInternalServerError)
 	}
 
+	request.Tag.ID = id
+	mediaCtr.record(c, models.AuditCreate, models.AuditTag, id, nil, request.Tag)
+
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
 		"id": id,
 	})
@@ -486,7 +843,7 @@ func (mediaCtr *mediaController) newTag(c *fiber.Ctx) error {
 
 // tag returns tag by its id.
 func (mediaCtr *mediaController) tag(c *fiber.Ctx) error {
-	ctx, cancel := context.WithTimeout(context.Background(), mediaCtr.timeout)
+	ctx, cancel := context.WithTimeout(c.UserContext(), mediaCtr.timeout)
 	defer cancel()
 
 	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
@@ -518,7 +875,7 @@ func (mediaCtr *mediaController) tag(c *fiber.Ctx) error {
 
 // updateTag updates tag.
 func (mediaCtr *mediaController) updateTag(c *fiber.Ctx) error {
-	ctx, cancel := context.WithTimeout(context.Background(), mediaCtr.timeout)
+	ctx, cancel := context.WithTimeout(c.UserContext(), mediaCtr.timeout)
 	defer cancel()
 
 	var request struct {
@@ -535,6 +892,8 @@ func (mediaCtr *mediaController) updateTag(c *fiber.Ctx) error {
 		})
 	}
 
+	before, _ := mediaCtr.srvMedia.Tag(ctx, request.Tag.ID)
+
 	if err := mediaCtr.srvMedia.UpdateTag(ctx, request.Tag); err != nil {
 		if errors.Is(err, service.ErrTagTypeInvalid) {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
@@ -549,12 +908,14 @@ func (mediaCtr *mediaController) updateTag(c *fiber.Ctx) error {
 		return c.SendStatus(fiber.StatusInternalServerError)
 	}
 
+	mediaCtr.record(c, models.AuditUpdate, models.AuditTag, request.Tag.ID, before, request.Tag)
+
 	return c.SendStatus(fiber.StatusOK)
 }
 
 // deleteTag deletes tag by its id
 func (mediaCtr *mediaController) deleteTag(c *fiber.Ctx) error {
-	ctx, cancel := context.WithTimeout(context.Background(), mediaCtr.timeout)
+	ctx, cancel := context.WithTimeout(c.UserContext(), mediaCtr.timeout)
 	defer cancel()
 
 	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
@@ -564,6 +925,8 @@ func (mediaCtr *mediaController) deleteTag(c *fiber.Ctx) error {
 		})
 	}
 
+	before, _ := mediaCtr.srvMedia.Tag(ctx, id)
+
 	if err := mediaCtr.srvMedia.DeleteTag(ctx, id); err != nil {
 		if errors.Is(err, service.ErrTagNotFound) {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
@@ -573,5 +936,7 @@ func (mediaCtr *mediaController) deleteTag(c *fiber.Ctx) error {
 		return c.SendStatus(fiber.StatusInternalServerError)
 	}
 
+	mediaCtr.record(c, models.AuditDelete, models.AuditTag, id, before, nil)
+
 	return c.SendStatus(fiber.StatusOK)
 }
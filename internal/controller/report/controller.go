@@ -0,0 +1,102 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	jwtController "github.com/GintGld/fizteh-radio/internal/controller/jwt"
+	"github.com/GintGld/fizteh-radio/internal/models"
+	reportSrv "github.com/GintGld/fizteh-radio/internal/service/report"
+)
+
+type reportController struct {
+	timeout time.Duration
+	report  Report
+}
+
+type Report interface {
+	Report(ctx context.Context, start, stop time.Time) (models.Report, error)
+}
+
+func New(
+	timeout time.Duration,
+	report Report,
+	jwtC *jwtController.JWT,
+) *fiber.App {
+	reportCtr := reportController{
+		timeout: timeout,
+		report:  report,
+	}
+
+	app := fiber.New()
+
+	app.Use(jwtC.AuthRequired(), jwtC.Permission(models.PermReports))
+
+	app.Get("/", reportCtr.getReport)
+
+	return app
+}
+
+// getReport returns airplay report for period
+// given by "period" or "start" and "stop" parameters
+// in json (default), csv or xlsx format.
+func (reportCtr *reportController) getReport(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.UserContext(), reportCtr.timeout)
+	defer cancel()
+
+	var start, stop time.Time
+
+	if period := c.Query("period"); period != "" {
+		var err error
+		start, stop, err = reportSrv.ParsePeriod(period, time.Local)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "invalid period",
+			})
+		}
+	} else {
+		start = time.Unix(int64(c.QueryInt("start")), 0)
+		stop = time.Unix(int64(c.QueryInt("stop")), 0)
+		if !start.Before(stop) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "invalid start value",
+			})
+		}
+	}
+
+	format := c.Query("format", "json")
+	if format != "json" && format != "csv" && format != "xlsx" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid format",
+		})
+	}
+
+	report, err := reportCtr.report.Report(ctx, start, stop)
+	if err != nil {
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+
+	var body bytes.Buffer
+
+	switch format {
+	case "csv":
+		if err := reportSrv.WriteCSV(&body, report); err != nil {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+		c.Set(fiber.HeaderContentType, "text/csv")
+		c.Attachment("report.csv")
+	case "xlsx":
+		if err := reportSrv.WriteXLSX(&body, report); err != nil {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+		c.Set(fiber.HeaderContentType, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+		c.Attachment("report.xlsx")
+	default:
+		return c.Status(fiber.StatusOK).JSON(report)
+	}
+
+	return c.Status(fiber.StatusOK).Send(body.Bytes())
+}
@@ -4,41 +4,51 @@ import (
 	"context"
 	"errors"
 	"strconv"
-	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/golang-jwt/jwt/v5"
 
 	jwtController "github.com/GintGld/fizteh-radio/internal/controller/jwt"
 	"github.com/GintGld/fizteh-radio/internal/models"
 	"github.com/GintGld/fizteh-radio/internal/service"
 )
 
-// TODO: move access check to special controller
-// TODO: /editor PUT (change editor info instead of create/delete)
-
 // New returns fiber app that will
 // handle requests special for root
 func New(
 	timeout time.Duration,
 	rootSrv Root,
+	keys APIKeys,
+	audit Audit,
 	jwtC *jwtController.JWT,
 ) *fiber.App {
 	rootCtr := rootController{
 		timeout: timeout,
 		srv:     rootSrv,
+		keys:    keys,
+		audit:   audit,
 	}
 
 	app := fiber.New()
 
-	// token validity -> root access -> handling request
-	app.Use(jwtC.AuthRequired(), rootCtr.rootAccess)
+	// token validity -> permission -> handling request
+	app.Use(jwtC.AuthRequired(), jwtC.Permission(models.PermEditors))
 
 	app.Get("/editors", rootCtr.allEditors)
 	app.Post("/editors", rootCtr.newEditor)
 	app.Get("/editor/:id", rootCtr.editor)
+	app.Put("/editor/:id", rootCtr.updateEditor)
 	app.Delete("/editor/:id", rootCtr.deleteEditor)
+	app.Put("/editor/:id/pass", rootCtr.resetPassword)
+	app.Put("/editor/:id/roles", rootCtr.setEditorRoles)
+	app.Put("/editor/:id/identity", rootCtr.linkIdentity)
+
+	app.Get("/logins", rootCtr.loginAttempts)
+	app.Get("/audit", rootCtr.auditEntries)
+
+	app.Get("/apikeys", rootCtr.apiKeys)
+	app.Post("/apikeys", rootCtr.newAPIKey)
+	app.Delete("/apikey/:id", rootCtr.revokeAPIKey)
 
 	return app
 }
@@ -46,6 +56,19 @@ func New(
 type rootController struct {
 	timeout time.Duration
 	srv     Root
+	keys    APIKeys
+	audit   Audit
+}
+
+type APIKeys interface {
+	Create(ctx context.Context, form models.APIKeyIn) (models.APIKey, string, error)
+	Keys(ctx context.Context) ([]models.APIKey, error)
+	Revoke(ctx context.Context, id int64) error
+}
+
+type Audit interface {
+	Record(ctx context.Context, action, entity string, id int64, before, after any)
+	Entries(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error)
 }
 
 type Root interface {
@@ -53,42 +76,16 @@ type Root interface {
 	AllEditors(ctx context.Context) ([]models.EditorOut, error)
 	Editor(ctx context.Context, id int64) (models.EditorOut, error)
 	DeleteEditor(ctx context.Context, id int64) error
-}
-
-// rootAccess check if the logged user is root,
-// but doesn't check validity, because only jwtWare
-// has access to the secret
-func (rootCtr *rootController) rootAccess(c *fiber.Ctx) error {
-	auth := c.Get(fiber.HeaderAuthorization)
-
-	jwtSplitted := strings.Split(auth, " ")
-	if len(jwtSplitted) != 2 || jwtSplitted[0] != "Bearer" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "invalid JWT",
-		})
-	}
-
-	token := jwtSplitted[1]
-	claims := jwt.MapClaims{}
-	_, _, err := jwt.NewParser().ParseUnverified(token, claims)
-	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "invalid JWT",
-		})
-	}
-
-	if claims["login"] != models.RootLogin {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "available for root only",
-		})
-	}
-
-	return c.Next()
+	SetEditorRoles(ctx context.Context, id int64, roles []models.Role) error
+	UpdateEditor(ctx context.Context, id int64, profile models.EditorProfile) error
+	ResetPassword(ctx context.Context, id int64, pass string) error
+	LinkIdentity(ctx context.Context, id int64, identity models.Identity) error
+	LoginAttempts(ctx context.Context, start, stop time.Time, login string) ([]models.LoginAttempt, error)
 }
 
 // allEditors return json with all editors
 func (rootCtr *rootController) allEditors(c *fiber.Ctx) error {
-	ctx, cancel := context.WithTimeout(context.Background(), rootCtr.timeout)
+	ctx, cancel := context.WithTimeout(c.UserContext(), rootCtr.timeout)
 	defer cancel()
 
 	editors, err := rootCtr.srv.AllEditors(ctx)
@@ -103,7 +100,7 @@ func (rootCtr *rootController) allEditors(c *fiber.Ctx) error {
 
 // editor return json with editor by id
 func (rootCtr *rootController) editor(c *fiber.Ctx) error {
-	ctx, cancel := context.WithTimeout(context.Background(), rootCtr.timeout)
+	ctx, cancel := context.WithTimeout(c.UserContext(), rootCtr.timeout)
 	defer cancel()
 
 	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
@@ -124,16 +121,13 @@ func (rootCtr *rootController) editor(c *fiber.Ctx) error {
 	}
 
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"editor": models.EditorOut{
-			ID:    editor.ID,
-			Login: editor.Login,
-		},
+		"editor": editor,
 	})
 }
 
 // newEditor creates new editor
 func (rootCtr *rootController) newEditor(c *fiber.Ctx) error {
-	ctx, cancel := context.WithTimeout(context.Background(), rootCtr.timeout)
+	ctx, cancel := context.WithTimeout(c.UserContext(), rootCtr.timeout)
 	defer cancel()
 
 	var form models.EditorIn
@@ -160,9 +154,22 @@ func (rootCtr *rootController) newEditor(c *fiber.Ctx) error {
 				"error": "editor exists",
 			})
 		}
+		if errors.Is(err, service.ErrInvalidRole) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "invalid role",
+			})
+		}
 		return c.SendStatus(fiber.StatusInternalServerError)
 	}
 
+	rootCtr.record(c, models.AuditCreate, models.AuditEditor, id, nil, models.EditorOut{
+		ID:          id,
+		Login:       form.Login,
+		Roles:       form.Roles,
+		DisplayName: form.DisplayName,
+		Contact:     form.Contact,
+	})
+
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
 		"id": id,
 	})
@@ -170,7 +177,7 @@ func (rootCtr *rootController) newEditor(c *fiber.Ctx) error {
 
 // deleteEditor deletes editor
 func (rootCtr *rootController) deleteEditor(c *fiber.Ctx) error {
-	ctx, cancel := context.WithTimeout(context.Background(), rootCtr.timeout)
+	ctx, cancel := context.WithTimeout(c.UserContext(), rootCtr.timeout)
 	defer cancel()
 
 	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
@@ -180,6 +187,8 @@ func (rootCtr *rootController) deleteEditor(c *fiber.Ctx) error {
 		})
 	}
 
+	before, _ := rootCtr.srv.Editor(ctx, id)
+
 	err = rootCtr.srv.DeleteEditor(ctx, id)
 	if err != nil {
 		if errors.Is(err, service.ErrEditorNotFound) {
@@ -190,5 +199,336 @@ func (rootCtr *rootController) deleteEditor(c *fiber.Ctx) error {
 		return c.SendStatus(fiber.StatusInternalServerError)
 	}
 
+	rootCtr.record(c, models.AuditDelete, models.AuditEditor, id, before, nil)
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// setEditorRoles replaces editor roles
+func (rootCtr *rootController) setEditorRoles(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.UserContext(), rootCtr.timeout)
+	defer cancel()
+
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "bad id",
+		})
+	}
+
+	var form struct {
+		Roles []models.Role `json:"roles"`
+	}
+
+	if err := c.BodyParser(&form); err != nil {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+
+	before, _ := rootCtr.srv.Editor(ctx, id)
+
+	if err := rootCtr.srv.SetEditorRoles(ctx, id, form.Roles); err != nil {
+		if errors.Is(err, service.ErrEditorNotFound) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "editor not found",
+			})
+		}
+		if errors.Is(err, service.ErrInvalidRole) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "invalid role",
+			})
+		}
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+
+	after := before
+	after.Roles = form.Roles
+	rootCtr.record(c, models.AuditUpdate, models.AuditEditor, id, before, after)
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// linkIdentity links external
+// identity to editor
+func (rootCtr *rootController) linkIdentity(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.UserContext(), rootCtr.timeout)
+	defer cancel()
+
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "bad id",
+		})
+	}
+
+	var identity models.Identity
+
+	if err := c.BodyParser(&identity); err != nil {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+
+	if identity.Issuer == "" || identity.Subject == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "issuer and subject required",
+		})
+	}
+
+	if err := rootCtr.srv.LinkIdentity(ctx, id, identity); err != nil {
+		if errors.Is(err, service.ErrEditorNotFound) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "editor not found",
+			})
+		}
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+
+	rootCtr.record(c, models.AuditUpdate, models.AuditEditor, id, nil, identity)
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// updateEditor updates editor profile
+func (rootCtr *rootController) updateEditor(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.UserContext(), rootCtr.timeout)
+	defer cancel()
+
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "bad id",
+		})
+	}
+
+	var profile models.EditorProfile
+
+	if err := c.BodyParser(&profile); err != nil {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+
+	before, _ := rootCtr.srv.Editor(ctx, id)
+
+	if err := rootCtr.srv.UpdateEditor(ctx, id, profile); err != nil {
+		if errors.Is(err, service.ErrEditorNotFound) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "editor not found",
+			})
+		}
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+
+	after := before
+	after.DisplayName = profile.DisplayName
+	after.Contact = profile.Contact
+	rootCtr.record(c, models.AuditUpdate, models.AuditEditor, id, before, after)
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// resetPassword sets new editor password
+func (rootCtr *rootController) resetPassword(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.UserContext(), rootCtr.timeout)
+	defer cancel()
+
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "bad id",
+		})
+	}
+
+	var form struct {
+		Pass string `json:"pass"`
+	}
+
+	if err := c.BodyParser(&form); err != nil {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+
+	if form.Pass == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "password can't be empty",
+		})
+	}
+
+	if err := rootCtr.srv.ResetPassword(ctx, id, form.Pass); err != nil {
+		if errors.Is(err, service.ErrEditorNotFound) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "editor not found",
+			})
+		}
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+
+	// password itself is never recorded
+	rootCtr.record(c, models.AuditUpdate, models.AuditEditor, id, nil, fiber.Map{
+		"password": "reset",
+	})
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// loginAttempts returns login audit
+func (rootCtr *rootController) loginAttempts(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.UserContext(), rootCtr.timeout)
+	defer cancel()
+
+	// Default values for cut
+	start := time.Unix(0, 0)
+	stop := time.Date(2100, 1, 1, 0, 0, 0, 0, time.Local)
+
+	if unix := c.QueryInt("start"); unix != 0 {
+		start = time.Unix(int64(unix), 0)
+	}
+	if unix := c.QueryInt("stop"); unix != 0 {
+		stop = time.Unix(int64(unix), 0)
+	}
+
+	if start.After(stop) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid start value",
+		})
+	}
+
+	attempts, err := rootCtr.srv.LoginAttempts(ctx, start, stop, c.Query("login"))
+	if err != nil {
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"attempts": attempts,
+	})
+}
+
+// auditEntries returns audit log
+// filtered by query criteria
+func (rootCtr *rootController) auditEntries(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.UserContext(), rootCtr.timeout)
+	defer cancel()
+
+	filter := models.AuditFilter{
+		Login:    c.Query("login"),
+		Entity:   c.Query("entity"),
+		EntityID: int64(c.QueryInt("entity_id")),
+		Limit:    c.QueryInt("limit", 100),
+	}
+
+	if unix := c.QueryInt("start"); unix != 0 {
+		filter.Start = time.Unix(int64(unix), 0)
+	}
+	if unix := c.QueryInt("stop"); unix != 0 {
+		filter.Stop = time.Unix(int64(unix), 0)
+	}
+
+	if !filter.Start.IsZero() && !filter.Stop.IsZero() && filter.Start.After(filter.Stop) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid start value",
+		})
+	}
+
+	entries, err := rootCtr.audit.Entries(ctx, filter)
+	if err != nil {
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"entries": entries,
+	})
+}
+
+// apiKeys returns all api keys
+func (rootCtr *rootController) apiKeys(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.UserContext(), rootCtr.timeout)
+	defer cancel()
+
+	keys, err := rootCtr.keys.Keys(ctx)
+	if err != nil {
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"keys": keys,
+	})
+}
+
+// newAPIKey creates api key.
+// Key is shown only in this response.
+func (rootCtr *rootController) newAPIKey(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.UserContext(), rootCtr.timeout)
+	defer cancel()
+
+	var form models.APIKeyIn
+
+	if err := c.BodyParser(&form); err != nil {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+
+	if form.Name == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "name can't be empty",
+		})
+	}
+
+	key, secret, err := rootCtr.keys.Create(ctx, form)
+	if err != nil {
+		if errors.Is(err, service.ErrAPIKeyExists) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "api key exists",
+			})
+		}
+		if errors.Is(err, service.ErrInvalidPermission) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "invalid permission",
+			})
+		}
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+
+	rootCtr.record(c, models.AuditCreate, models.AuditAPIKey, key.ID, nil, key)
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"id":  key.ID,
+		"key": secret,
+	})
+}
+
+// revokeAPIKey deletes api key
+func (rootCtr *rootController) revokeAPIKey(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.UserContext(), rootCtr.timeout)
+	defer cancel()
+
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "bad id",
+		})
+	}
+
+	var before any
+	if keys, err := rootCtr.keys.Keys(ctx); err == nil {
+		for _, key := range keys {
+			if key.ID == id {
+				before = key
+			}
+		}
+	}
+
+	if err := rootCtr.keys.Revoke(ctx, id); err != nil {
+		if errors.Is(err, service.ErrAPIKeyNotFound) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "api key not found",
+			})
+		}
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+
+	rootCtr.record(c, models.AuditDelete, models.AuditAPIKey, id, before, nil)
+
 	return c.SendStatus(fiber.StatusOK)
 }
+
+// record saves change made by the request owner.
+func (rootCtr *rootController) record(c *fiber.Ctx, action, entity string, id int64, before, after any) {
+	ctx, cancel := context.WithTimeout(c.UserContext(), rootCtr.timeout)
+	defer cancel()
+
+	rootCtr.audit.Record(ctx, action, entity, id, before, after)
+}
@@ -18,6 +18,7 @@ type scheduleController struct {
 	schSrv  Schedule
 	dj      DJ
 	live    Live
+	audit   Auditor
 }
 
 type Schedule interface {
@@ -43,11 +44,16 @@ type Live interface {
 	Stop()
 }
 
+type Auditor interface {
+	Record(ctx context.Context, action, entity string, id int64, before, after any)
+}
+
 func New(
 	timeout time.Duration,
 	schSrv Schedule,
 	dj DJ,
 	live Live,
+	audit Auditor,
 	jwtC *jwtController.JWT,
 ) *fiber.App {
 	schCtr := scheduleController{
@@ -55,28 +61,34 @@ func New(
 		schSrv:  schSrv,
 		dj:      dj,
 		live:    live,
+		audit:   audit,
 	}
 
 	app := fiber.New()
 
 	app.Use(jwtC.AuthRequired())
 
-	app.Get("/", schCtr.scheduleCut)
-	app.Post("/", schCtr.newSegment)
-	app.Get("/:id", schCtr.segment)
-	app.Delete("/:id", schCtr.deleteSegment)
-	app.Delete("/", schCtr.clearSchedule)
+	read := jwtC.Permission(models.PermScheduleRead)
+	write := jwtC.Permission(models.PermScheduleWrite)
+	djCtl := jwtC.Permission(models.PermAutoDJ)
+	liveCtl := jwtC.Permission(models.PermLive)
+
+	app.Get("/", read, schCtr.scheduleCut)
+	app.Post("/", write, schCtr.newSegment)
+	app.Get("/:id", read, schCtr.segment)
+	app.Delete("/:id", write, schCtr.deleteSegment)
+	app.Delete("/", write, schCtr.clearSchedule)
 
-	app.Get("/dj/config", schCtr.getDJConfig)
-	app.Post("/dj/config", schCtr.setDJConfig)
-	app.Get("/dj/start", schCtr.startDJ)
-	app.Get("dj/status", schCtr.isPlaying)
-	app.Get("/dj/stop", schCtr.stopDJ)
+	app.Get("/dj/config", read, schCtr.getDJConfig)
+	app.Post("/dj/config", djCtl, schCtr.setDJConfig)
+	app.Get("/dj/start", djCtl, schCtr.startDJ)
+	app.Get("dj/status", read, schCtr.isPlaying)
+	app.Get("/dj/stop", djCtl, schCtr.stopDJ)
 
-	app.Get("/lives", schCtr.lives)
-	app.Post("/live/start", schCtr.startLive)
-	app.Get("/live/info", schCtr.liveInfo)
-	app.Get("/live/stop", schCtr.stopLive)
+	app.Get("/lives", read, schCtr.lives)
+	app.Post("/live/start", liveCtl, schCtr.startLive)
+	app.Get("/live/info", read, schCtr.liveInfo)
+	app.Get("/live/stop", liveCtl, schCtr.stopLive)
 
 	return app
 }
@@ -84,7 +96,7 @@ func New(
 // scheduleCut returns segments intersecting given interval
 // if
 func (schCtr *scheduleController) scheduleCut(c *fiber.Ctx) error {
-	ctx, cancel := context.WithTimeout(context.Background(), schCtr.timeout)
+	ctx, cancel := context.WithTimeout(c.UserContext(), schCtr.timeout)
 	defer cancel()
 
 	// Default values for cut
@@ -117,7 +129,7 @@ func (schCtr *scheduleController) scheduleCut(c *fiber.Ctx) error {
 // live returns all registered live streams
 // stopping after given time point.
 func (schCtr *scheduleController) lives(c *fiber.Ctx) error {
-	ctx, cancel := context.WithTimeout(context.Background(), schCtr.timeout)
+	ctx, cancel := context.WithTimeout(c.UserContext(), schCtr.timeout)
 	defer cancel()
 
 	start := time.Date(0, 0, 0, 0, 0, 0, 0, time.Local)
@@ -137,7 +149,7 @@ func (schCtr *scheduleController) lives(c *fiber.Ctx) error {
 
 // newSegment registers new segment
 func (schCtr *scheduleController) newSegment(c *fiber.Ctx) error {
-	ctx, cancel := context.WithTimeout(context.Background(), schCtr.timeout)
+	ctx, cancel := context.WithTimeout(c.UserContext(), schCtr.timeout)
 	defer cancel()
 
 	type request struct {
@@ -150,6 +162,11 @@ func (schCtr *scheduleController) newSegment(c *fiber.Ctx) error {
 		return c.SendStatus(fiber.StatusBadRequest)
 	}
 
+	// Tempo and fade out are set only by scheduler,
+	// unchecked values would break playback.
+	form.Segment.Tempo = 0
+	form.Segment.FadeOut = false
+
 	if form.Segment.MediaID == nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": "media not defined",
@@ -183,6 +200,11 @@ func (schCtr *scheduleController) newSegment(c *fiber.Ctx) error {
 				"error": "live segment can't have media id",
 			})
 		}
+		if errors.Is(err, service.ErrSegmentNotOwned) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "segment is owned by another editor",
+			})
+		}
 		if errors.Is(err, service.ErrCutOutOfBounds) {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 				"error": "cut out of bounds",
@@ -201,6 +223,9 @@ func (schCtr *scheduleController) newSegment(c *fiber.Ctx) error {
 		return c.SendStatus(fiber.StatusInternalServerError)
 	}
 
+	form.Segment.ID = &id
+	schCtr.record(c, models.AuditCreate, models.AuditSegment, id, nil, form.Segment)
+
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
 		"id": id,
 	})
@@ -208,7 +233,7 @@ func (schCtr *scheduleController) newSegment(c *fiber.Ctx) error {
 
 // segment returns segment by id
 func (schCtr *scheduleController) segment(c *fiber.Ctx) error {
-	ctx, cancel := context.WithTimeout(context.Background(), schCtr.timeout)
+	ctx, cancel := context.WithTimeout(c.UserContext(), schCtr.timeout)
 	defer cancel()
 
 	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
@@ -235,7 +260,7 @@ func (schCtr *scheduleController) segment(c *fiber.Ctx) error {
 
 // deleteSegment deletes segment by id
 func (schCtr *scheduleController) deleteSegment(c *fiber.Ctx) error {
-	ctx, cancel := context.WithTimeout(context.Background(), schCtr.timeout)
+	ctx, cancel := context.WithTimeout(c.UserContext(), schCtr.timeout)
 	defer cancel()
 
 	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
@@ -245,21 +270,30 @@ func (schCtr *scheduleController) deleteSegment(c *fiber.Ctx) error {
 		})
 	}
 
+	before, _ := schCtr.schSrv.Segment(ctx, id)
+
 	if err := schCtr.schSrv.DeleteSegment(ctx, id); err != nil {
 		if errors.Is(err, service.ErrSegmentNotFound) {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 				"error": "segment not found",
 			})
 		}
+		if errors.Is(err, service.ErrSegmentNotOwned) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "segment is owned by another editor",
+			})
+		}
 		return c.SendStatus(fiber.StatusInternalServerError)
 	}
 
+	schCtr.record(c, models.AuditDelete, models.AuditSegment, id, before, nil)
+
 	return c.SendStatus(fiber.StatusOK)
 }
 
 // clearSchedule clear schedule from given timestamp
 func (schCtr *scheduleController) clearSchedule(c *fiber.Ctx) error {
-	ctx, cancel := context.WithTimeout(context.Background(), schCtr.timeout)
+	ctx, cancel := context.WithTimeout(c.UserContext(), schCtr.timeout)
 	defer cancel()
 
 	fromInt := c.QueryInt("from", -1)
@@ -271,10 +305,14 @@ func (schCtr *scheduleController) clearSchedule(c *fiber.Ctx) error {
 
 	from := time.Unix(int64(fromInt), 0)
 
+	before, _ := schCtr.schSrv.ScheduleCut(ctx, from, time.Date(2100, 1, 1, 0, 0, 0, 0, time.Local))
+
 	if err := schCtr.schSrv.ClearSchedule(ctx, from); err != nil {
 		return c.SendStatus(fiber.StatusInternalServerError)
 	}
 
+	schCtr.record(c, models.AuditDelete, models.AuditSchedule, 0, before, nil)
+
 	return c.SendStatus(fiber.StatusOK)
 }
 
@@ -296,14 +334,20 @@ func (schCtr *scheduleController) setDJConfig(c *fiber.Ctx) error {
 		return c.SendStatus(fiber.StatusBadRequest)
 	}
 
+	before := schCtr.dj.Config()
+
 	schCtr.dj.SetConfig(request.Conf)
 
+	schCtr.record(c, models.AuditUpdate, models.AuditDJConfig, 0, before, request.Conf)
+
 	return c.SendStatus(fiber.StatusOK)
 }
 
 // startDJ start autodj.
 func (schCtr *scheduleController) startDJ(c *fiber.Ctx) error {
-	go schCtr.dj.Run(context.TODO())
+	go schCtr.dj.Run(context.WithoutCancel(c.UserContext()))
+
+	schCtr.record(c, models.AuditStart, models.AuditAutoDJ, 0, nil, nil)
 
 	return c.SendStatus(fiber.StatusOK)
 }
@@ -320,6 +364,8 @@ func (schCtr *scheduleController) isPlaying(c *fiber.Ctx) error {
 func (schCtr *scheduleController) stopDJ(c *fiber.Ctx) error {
 	go schCtr.dj.Stop()
 
+	schCtr.record(c, models.AuditStop, models.AuditAutoDJ, 0, nil, nil)
+
 	return c.SendStatus(fiber.StatusOK)
 }
 
@@ -343,7 +389,9 @@ func (schCtr *scheduleController) startLive(c *fiber.Ctx) error {
 		request.Live.Start = time.Now()
 	}
 
-	go schCtr.live.Run(context.TODO(), request.Live)
+	go schCtr.live.Run(context.WithoutCancel(c.UserContext()), request.Live)
+
+	schCtr.record(c, models.AuditStart, models.AuditLive, 0, nil, request.Live)
 
 	return c.SendStatus(fiber.StatusOK)
 }
@@ -360,7 +408,19 @@ func (schCtr *scheduleController) liveInfo(c *fiber.Ctx) error {
 
 // stopLive stops live.
 func (schCtr *scheduleController) stopLive(c *fiber.Ctx) error {
+	before := schCtr.live.Info()
+
 	schCtr.live.Stop()
 
+	schCtr.record(c, models.AuditStop, models.AuditLive, before.ID, before, nil)
+
 	return c.SendStatus(fiber.StatusOK)
 }
+
+// record saves change made by the request owner.
+func (schCtr *scheduleController) record(c *fiber.Ctx, action, entity string, id int64, before, after any) {
+	ctx, cancel := context.WithTimeout(c.UserContext(), schCtr.timeout)
+	defer cancel()
+
+	schCtr.audit.Record(ctx, action, entity, id, before, after)
+}
@@ -74,7 +74,7 @@ func (statCtr *statController) listenersNumber(c *fiber.Ctx) error {
 }
 
 func (statCtr *statController) listeners(c *fiber.Ctx) error {
-	ctx, cancel := context.WithTimeout(context.Background(), statCtr.timeout)
+	ctx, cancel := context.WithTimeout(c.UserContext(), statCtr.timeout)
 	defer cancel()
 
 	// Default values for cut
@@ -0,0 +1,100 @@
+// Package bodylimit limits size of request
+// bodies streamed by server.
+package bodylimit
+
+import (
+	"bytes"
+	"errors"
+	"io"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ErrTooLarge is returned by body reader
+// when body exceeds the limit.
+var ErrTooLarge = errors.New("request body too large")
+
+// Keys of the limit and of the
+// exceeding mark in request locals.
+const (
+	limitLocal    = "bodylimit"
+	exceededLocal = "bodylimit.exceeded"
+)
+
+// Middleware rejects requests with body larger than limit.
+//
+// Server doesn't limit streamed bodies, so bodies of unknown
+// length (chunked) are rejected, except requests reported
+// by streamed. Handlers of such requests must read body
+// with Body, which fails when limit is exceeded.
+func Middleware(limit int64, streamed func(c *fiber.Ctx) bool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		length := int64(c.Request().Header.ContentLength())
+		if length > limit {
+			// Unread body must not be
+			// parsed as the next request.
+			c.Context().SetConnectionClose()
+			return c.SendStatus(fiber.StatusRequestEntityTooLarge)
+		}
+		if length < 0 && c.Context().RequestBodyStream() != nil && !streamed(c) {
+			c.Context().SetConnectionClose()
+			return c.SendStatus(fiber.StatusLengthRequired)
+		}
+
+		c.Locals(limitLocal, limit)
+
+		return c.Next()
+	}
+}
+
+// Body returns reader of request body, body isn't
+// buffered if server streams it. Reader returns
+// ErrTooLarge if body exceeds limit of the Middleware.
+// Use Exceeded if error may be lost by consumer.
+func Body(c *fiber.Ctx) io.Reader {
+	r := c.Context().RequestBodyStream()
+	if r == nil {
+		return bytes.NewReader(c.Body())
+	}
+
+	limit, ok := c.Locals(limitLocal).(int64)
+	if !ok {
+		return r
+	}
+
+	return &reader{r: r, left: limit, c: c}
+}
+
+// reader fails when more
+// than left bytes are read.
+type reader struct {
+	r    io.Reader
+	left int64
+	c    *fiber.Ctx
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	if r.left < 0 {
+		return 0, ErrTooLarge
+	}
+	// One extra byte tells if
+	// body ends exactly at limit.
+	if int64(len(p)) > r.left+1 {
+		p = p[:r.left+1]
+	}
+	n, err := r.r.Read(p)
+	r.left -= int64(n)
+	if r.left < 0 {
+		r.c.Locals(exceededLocal, true)
+		r.c.Context().SetConnectionClose()
+		return 0, ErrTooLarge
+	}
+	return n, err
+}
+
+// Exceeded reports if body read
+// with Body exceeded the limit.
+func Exceeded(c *fiber.Ctx) bool {
+	exceeded, _ := c.Locals(exceededLocal).(bool)
+	return exceeded
+}
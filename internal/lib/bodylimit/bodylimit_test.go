@@ -0,0 +1,62 @@
+package bodylimit_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/GintGld/fizteh-radio/internal/lib/bodylimit"
+)
+
+func TestMiddleware(t *testing.T) {
+	app := fiber.New(fiber.Config{StreamRequestBody: true})
+	app.Use(bodylimit.Middleware(10, func(c *fiber.Ctx) bool {
+		return c.Path() == "/stream"
+	}))
+	app.Post("/stream", func(c *fiber.Ctx) error {
+		body, err := io.ReadAll(bodylimit.Body(c))
+		if errors.Is(err, bodylimit.ErrTooLarge) {
+			return c.SendStatus(fiber.StatusRequestEntityTooLarge)
+		}
+		if err != nil {
+			return c.SendStatus(fiber.StatusBadRequest)
+		}
+		return c.SendString(string(body))
+	})
+	app.Post("/buffer", func(c *fiber.Ctx) error {
+		return c.Send(c.Body())
+	})
+
+	for _, tt := range []struct {
+		desc    string
+		path    string
+		body    string
+		chunked bool
+		status  int
+	}{
+		{"fixed length", "/buffer", "0123456789", false, fiber.StatusOK},
+		{"fixed length too large", "/buffer", "0123456789a", false, fiber.StatusRequestEntityTooLarge},
+		{"chunked not streamed", "/buffer", "0123456789", true, fiber.StatusLengthRequired},
+		{"chunked streamed", "/stream", "0123456789", true, fiber.StatusOK},
+		{"chunked streamed too large", "/stream", strings.Repeat("0123456789", 100), true, fiber.StatusRequestEntityTooLarge},
+	} {
+		t.Run(tt.desc, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			if tt.chunked {
+				req.ContentLength = -1
+				req.TransferEncoding = []string{"chunked"}
+			}
+
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			assert.Equal(t, tt.status, resp.StatusCode)
+		})
+	}
+}
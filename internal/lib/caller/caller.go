@@ -0,0 +1,111 @@
+// Package caller carries identity of the
+// request initiator through context.
+package caller
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+
+	"github.com/GintGld/fizteh-radio/internal/models"
+)
+
+// System is a login used for actions
+// not initiated by any editor
+// (autodj, dash, background jobs).
+const System = "system"
+
+// Caller describes who made the request.
+type Caller struct {
+	ID          int64
+	Login       string
+	Roles       []models.Role
+	Permissions []models.Permission
+	RequestID   string
+}
+
+type ctxKey struct{}
+
+// With returns copy of the context carrying caller.
+func With(ctx context.Context, c Caller) context.Context {
+	return context.WithValue(ctx, ctxKey{}, c)
+}
+
+// WithRequestID returns copy of the context
+// with request id set, keeping the caller.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	c, _ := From(ctx)
+	c.RequestID = id
+	return With(ctx, c)
+}
+
+// WithEditor returns copy of the context
+// with editor set, keeping the request id.
+func WithEditor(ctx context.Context, id int64, login string, roles ...models.Role) context.Context {
+	c, _ := From(ctx)
+	c.ID = id
+	c.Login = login
+	c.Roles = roles
+	return With(ctx, c)
+}
+
+// WithAPIKey returns copy of the context
+// with api key set, keeping the request id.
+//
+// Keys get ids below models.RootID,
+// so they don't match editors or root.
+func WithAPIKey(ctx context.Context, key models.APIKey) context.Context {
+	c, _ := From(ctx)
+	c.ID = models.RootID - key.ID
+	c.Login = "apikey:" + key.Name
+	c.Roles = nil
+	c.Permissions = key.Permissions
+	return With(ctx, c)
+}
+
+// From returns caller saved in context.
+func From(ctx context.Context) (Caller, bool) {
+	c, ok := ctx.Value(ctxKey{}).(Caller)
+	return c, ok
+}
+
+// Login returns login of the caller
+// or System if there is no editor in context.
+func Login(ctx context.Context) string {
+	if c, ok := From(ctx); ok && c.Login != "" {
+		return c.Login
+	}
+	return System
+}
+
+// ID returns id of the editor or api key
+// in context, 0 if request is made by system.
+func ID(ctx context.Context) int64 {
+	c, _ := From(ctx)
+	return c.ID
+}
+
+// Owns reports if caller may modify object
+// created by editor (or api key) with given id.
+// Objects created by system are shared, callers
+// with models.PermOverride (e.g. root)
+// may modify any object.
+func Owns(ctx context.Context, owner int64) bool {
+	c, _ := From(ctx)
+	if owner == 0 || c.ID == 0 || c.ID == owner {
+		return true
+	}
+	return models.HasPermission(c.Roles, models.PermOverride) ||
+		slices.Contains(c.Permissions, models.PermOverride)
+}
+
+// Attr returns log attribute describing caller.
+func Attr(ctx context.Context) slog.Attr {
+	c, _ := From(ctx)
+
+	return slog.Group("caller",
+		slog.Int64("id", c.ID),
+		slog.String("login", Login(ctx)),
+		slog.String("request_id", c.RequestID),
+	)
+}
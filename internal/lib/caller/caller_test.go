@@ -0,0 +1,48 @@
+package caller
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/GintGld/fizteh-radio/internal/models"
+)
+
+func TestCaller(t *testing.T) {
+	ctx := context.Background()
+
+	assert.Equal(t, System, Login(ctx))
+
+	ctx = WithRequestID(ctx, "req")
+	ctx = WithEditor(ctx, 3, "editor")
+
+	c, ok := From(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, Caller{ID: 3, Login: "editor", RequestID: "req"}, c)
+	assert.Equal(t, "editor", Login(ctx))
+}
+
+func TestOwns(t *testing.T) {
+	ctx := context.Background()
+	assert.True(t, Owns(ctx, 3))
+
+	editor := WithEditor(ctx, 3, "editor")
+	assert.True(t, Owns(editor, 3))
+	assert.True(t, Owns(editor, 0))
+	assert.False(t, Owns(editor, 4))
+
+	root := WithEditor(ctx, models.RootID, models.RootLogin, models.RoleRoot)
+	assert.True(t, Owns(root, 4))
+
+	// Api key owns only objects it created.
+	key := WithAPIKey(ctx, models.APIKey{ID: 1, Name: "bot"})
+	assert.Less(t, ID(key), models.RootID)
+	assert.True(t, Owns(key, ID(key)))
+	assert.True(t, Owns(key, 0))
+	assert.False(t, Owns(key, 3))
+	assert.False(t, Owns(key, models.RootID))
+
+	override := WithAPIKey(ctx, models.APIKey{ID: 2, Name: "sync", Permissions: []models.Permission{models.PermOverride}})
+	assert.True(t, Owns(override, 3))
+}
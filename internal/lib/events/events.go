@@ -0,0 +1,45 @@
+// Package events delivers events
+// raised by services to subscribers
+// (e.g. alert notifiers).
+package events
+
+import (
+	"sync"
+
+	"github.com/GintGld/fizteh-radio/internal/models"
+)
+
+// Handler handles published event.
+type Handler func(models.Event)
+
+// Bus is a publish-subscribe bus.
+// Zero value is ready to use.
+type Bus struct {
+	mutex    sync.RWMutex
+	handlers []Handler
+}
+
+func New() *Bus {
+	return &Bus{}
+}
+
+// Subscribe adds handler getting
+// every event published after the call.
+func (b *Bus) Subscribe(h Handler) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.handlers = append(b.handlers, h)
+}
+
+// Publish sends event to subscribers.
+// Handlers are called in separate goroutines,
+// so slow subscriber doesn't block publisher.
+func (b *Bus) Publish(e models.Event) {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	for _, h := range b.handlers {
+		go h(e)
+	}
+}
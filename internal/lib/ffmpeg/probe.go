@@ -0,0 +1,94 @@
+package ffmpeg
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/GintGld/fizteh-radio/internal/lib/utils/writer"
+)
+
+var ErrNoPackets = errors.New("no audio packets")
+
+// ProbeDuration reads audio from r and returns its duration.
+//
+// Packets are read till the end of stream, so duration
+// is known even for non-seekable input without
+// duration in headers (e.g. request body).
+func ProbeDuration(ctx context.Context, r io.Reader) (time.Duration, error) {
+	cmd := exec.CommandContext(ctx,
+		"ffprobe",            //							call ffprobe
+		"-loglevel", "error", //							set loglevel
+		"-select_streams", "a:0", //						skip cover art
+		"-show_entries", "packet=pts_time,duration_time", //	timing of every packet
+		"-of", "csv=p=0", //								one packet per line
+		"-i", "pipe:0", //									read from stdin
+	)
+	cmd.Stdin = r
+
+	errorWriter := writer.New()
+	cmd.Stderr = errorWriter
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return 0, err
+	}
+	if err := cmd.Start(); err != nil {
+		return 0, err
+	}
+
+	duration, parseErr := packetsEnd(stdout)
+	// Output must be read till the end
+	// for ffprobe to exit.
+	io.Copy(io.Discard, stdout)
+
+	if err := cmd.Wait(); err != nil {
+		return 0, fmt.Errorf("%w: %s", err, strings.TrimSpace(errorWriter.String()))
+	}
+	if parseErr != nil {
+		return 0, parseErr
+	}
+
+	return duration, nil
+}
+
+// packetsEnd returns end of the last packet
+// from lines "pts_time,duration_time".
+func packetsEnd(r io.Reader) (time.Duration, error) {
+	var (
+		end   float64
+		found bool
+	)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Split(strings.TrimSpace(scanner.Text()), ",")
+
+		// Packets without timestamp are "N/A".
+		pts, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil {
+			continue
+		}
+		var duration float64
+		if len(fields) > 1 {
+			duration, _ = strconv.ParseFloat(fields[1], 64)
+		}
+
+		end = max(end, pts+duration)
+		found = true
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	if !found {
+		return 0, ErrNoPackets
+	}
+
+	return time.Duration(end * float64(time.Second)).Round(time.Microsecond), nil
+}
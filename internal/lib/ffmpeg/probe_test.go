@@ -0,0 +1,21 @@
+package ffmpeg
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPacketsEnd(t *testing.T) {
+	out := "N/A,0.026122\n0.000000,0.026122\n0.026122,0.026122\n\n179.983673,0.026122\n"
+
+	d, err := packetsEnd(strings.NewReader(out))
+	require.NoError(t, err)
+	assert.Equal(t, 180009795*time.Microsecond, d)
+
+	_, err = packetsEnd(strings.NewReader("N/A,N/A\n"))
+	assert.ErrorIs(t, err, ErrNoPackets)
+}
@@ -0,0 +1,71 @@
+// Package heartbeat tracks liveness
+// of long running loops.
+package heartbeat
+
+import (
+	"sync"
+	"time"
+)
+
+// Heartbeat is beaten by a loop on each
+// iteration, zero value is a stopped loop.
+type Heartbeat struct {
+	mutex   sync.Mutex
+	running bool
+	last    time.Time
+	next    time.Time
+}
+
+// Status is a state of the loop.
+type Status struct {
+	Running bool
+	// Time of the last beat.
+	Last time.Time
+	// Time the next beat is expected.
+	Next time.Time
+}
+
+// Start marks loop as running.
+func (h *Heartbeat) Start() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	now := time.Now()
+	h.running = true
+	h.last, h.next = now, now
+}
+
+// Stop marks loop as stopped.
+func (h *Heartbeat) Stop() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.running = false
+}
+
+// Beat tells that loop is alive and
+// expects the next beat within given duration.
+func (h *Heartbeat) Beat(next time.Duration) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	now := time.Now()
+	h.last, h.next = now, now.Add(next)
+}
+
+func (h *Heartbeat) Status() Status {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	return Status{
+		Running: h.running,
+		Last:    h.last,
+		Next:    h.next,
+	}
+}
+
+// Stalled reports if the loop is running
+// but missed expected beat by more than grace.
+func (s Status) Stalled(now time.Time, grace time.Duration) bool {
+	return s.Running && now.After(s.Next.Add(grace))
+}
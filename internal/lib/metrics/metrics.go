@@ -0,0 +1,159 @@
+// Package metrics contains prometheus
+// collectors of the radio and http middleware
+// measuring requests per route.
+package metrics
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "radio"
+
+var (
+	// Dash content generation.
+	ChunksGenerated = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "chunks_generated_total",
+		Help:      "Number of generated dash chunks.",
+	})
+	FFmpegDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "ffmpeg_duration_seconds",
+		Help:      "Duration of ffmpeg generating dash files of a segment.",
+		Buckets:   []float64{.1, .25, .5, 1, 2.5, 5, 10, 30, 60},
+	})
+
+	// Source storage.
+	SourceDownloadDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "source_download_duration_seconds",
+		Help:      "Duration of source file downloads.",
+		Buckets:   []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+	})
+	SourceFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "source_failures_total",
+		Help:      "Number of failed source storage calls.",
+	}, []string{"op"})
+	SourceCacheHits = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "source_cache_hits_total",
+		Help:      "Number of sources found in local cache.",
+	})
+	SourceCacheMisses = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "source_cache_misses_total",
+		Help:      "Number of sources downloaded to local cache.",
+	})
+
+	// Dash manifest.
+	ManifestUpdates = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "manifest_updates_total",
+		Help:      "Number of manifest schedule updates.",
+	})
+
+	// AutoDJ.
+	AutoDJSegments = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "autodj_segments_total",
+		Help:      "Number of segments added by autodj.",
+	})
+	AutoDJFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "autodj_failures_total",
+		Help:      "Number of segments autodj failed to add.",
+	})
+
+	// Live.
+	LiveStarts = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "live_ffmpeg_starts_total",
+		Help:      "Number of live ffmpeg process (re)starts.",
+	})
+	LiveFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "live_ffmpeg_failures_total",
+		Help:      "Number of live ffmpeg processes exited with error.",
+	})
+
+	// HTTP.
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "Number of http requests.",
+	}, []string{"method", "route", "status"})
+	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Duration of http requests.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+)
+
+// Listeners registers gauge of current
+// listeners number returned by f.
+func Listeners(f func() int) {
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "listeners",
+		Help:      "Current number of listeners.",
+	}, func() float64 {
+		return float64(f())
+	})
+}
+
+// Middleware measures requests per route.
+//
+// Route is a path template (e.g. "/library/media/:id"),
+// requests that matched no route are counted as "unmatched".
+func Middleware() fiber.Handler {
+	var (
+		once   sync.Once
+		routes map[string]struct{}
+	)
+
+	return func(c *fiber.Ctx) error {
+		// Routes are known only after app has started.
+		once.Do(func() {
+			routes = make(map[string]struct{})
+			for _, r := range c.App().GetRoutes(true) {
+				routes[r.Method+" "+r.Path] = struct{}{}
+			}
+		})
+
+		start := time.Now()
+
+		err := c.Next()
+
+		status := c.Response().StatusCode()
+		if err != nil {
+			// Error is written to response
+			// later by fiber's error handler.
+			status = fiber.StatusInternalServerError
+			if e, ok := err.(*fiber.Error); ok {
+				status = e.Code
+			}
+		}
+
+		// Last matched route may be a middleware
+		// if request matched no handler.
+		route := "unmatched"
+		if _, ok := routes[c.Method()+" "+c.Route().Path]; ok {
+			route = c.Route().Path
+		}
+
+		httpRequests.WithLabelValues(c.Method(), route, strconv.Itoa(status)).Inc()
+		httpDuration.WithLabelValues(c.Method(), route).Observe(time.Since(start).Seconds())
+
+		return err
+	}
+}
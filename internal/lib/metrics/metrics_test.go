@@ -0,0 +1,36 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMiddleware(t *testing.T) {
+	sub := fiber.New()
+	sub.Use(func(c *fiber.Ctx) error { return c.Next() })
+	sub.Get("/media/:id", func(c *fiber.Ctx) error {
+		return c.SendString(c.Params("id"))
+	})
+	sub.Get("/fail", func(c *fiber.Ctx) error {
+		return fiber.ErrTeapot
+	})
+
+	app := fiber.New()
+	app.Use(Middleware())
+	app.Mount("/library", sub)
+
+	for _, path := range []string{"/library/media/1", "/library/media/2", "/library/fail", "/library/unknown"} {
+		resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, path, nil))
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(httpRequests.WithLabelValues("GET", "/library/media/:id", "200")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(httpRequests.WithLabelValues("GET", "/library/fail", "418")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(httpRequests.WithLabelValues("GET", "unmatched", "404")))
+}
@@ -0,0 +1,268 @@
+// Package oidc is a minimal OpenID Connect client:
+// provider discovery, authorization code flow
+// with PKCE and id token verification.
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MicahParks/keyfunc/v2"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrDiscovery    = errors.New("provider discovery failed")
+	ErrExchange     = errors.New("code exchange failed")
+	ErrInvalidToken = errors.New("invalid id token")
+)
+
+// Config describes client registered at provider.
+type Config struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+}
+
+// Provider is an OpenID provider.
+// Its metadata and keys are fetched
+// on first use and then cached.
+type Provider struct {
+	cfg    Config
+	client *http.Client
+
+	mutex sync.Mutex
+	meta  *metadata
+	jwks  *keyfunc.JWKS
+}
+
+type metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Claims are claims of verified id token.
+type Claims struct {
+	Issuer  string
+	Subject string
+	// All claims of the token,
+	// used to get login, email, name etc.
+	Raw map[string]any
+}
+
+// String returns string claim
+// or empty string if there's no such claim.
+func (c Claims) String(name string) string {
+	s, _ := c.Raw[name].(string)
+	return s
+}
+
+// New returns provider, http.DefaultClient
+// is used if client is nil.
+func New(cfg Config, client *http.Client) *Provider {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid"}
+	}
+
+	return &Provider{
+		cfg:    cfg,
+		client: client,
+	}
+}
+
+// AuthURL returns url editor is redirected to
+// for authentication at provider.
+func (p *Provider) AuthURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	meta, _, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	q := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.cfg.ClientID},
+		"redirect_uri":          {p.cfg.RedirectURL},
+		"scope":                 {strings.Join(p.cfg.Scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {Challenge(verifier)},
+		"code_challenge_method": {"S256"},
+	}
+
+	sep := "?"
+	if strings.Contains(meta.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+
+	return meta.AuthorizationEndpoint + sep + q.Encode(), nil
+}
+
+// Exchange exchanges authorization code for id token
+// and returns its claims if token is valid
+// and issued for given nonce.
+func (p *Provider) Exchange(ctx context.Context, code, verifier, nonce string) (Claims, error) {
+	meta, jwks, err := p.discover(ctx)
+	if err != nil {
+		return Claims{}, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.cfg.RedirectURL},
+		"client_id":     {p.cfg.ClientID},
+		"code_verifier": {verifier},
+	}
+	if p.cfg.ClientSecret != "" {
+		form.Set("client_secret", p.cfg.ClientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, meta.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return Claims{}, fmt.Errorf("%w: %w", ErrExchange, err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return Claims{}, fmt.Errorf("%w: %w", ErrExchange, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return Claims{}, fmt.Errorf("%w: %w", ErrExchange, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return Claims{}, fmt.Errorf("%w: status %d: %s", ErrExchange, resp.StatusCode, body)
+	}
+
+	var tokens struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.Unmarshal(body, &tokens); err != nil {
+		return Claims{}, fmt.Errorf("%w: %w", ErrExchange, err)
+	}
+	if tokens.IDToken == "" {
+		return Claims{}, fmt.Errorf("%w: no id token in response", ErrExchange)
+	}
+
+	return p.verify(tokens.IDToken, meta.Issuer, jwks, nonce)
+}
+
+// verify checks id token signature,
+// issuer, audience, expiration and nonce.
+func (p *Provider) verify(raw, issuer string, jwks *keyfunc.JWKS, nonce string) (Claims, error) {
+	claims := jwt.MapClaims{}
+
+	if _, err := jwt.ParseWithClaims(raw, claims, jwks.Keyfunc,
+		jwt.WithIssuer(issuer),
+		jwt.WithAudience(p.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512", "PS256", "EdDSA"}),
+	); err != nil {
+		return Claims{}, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+
+	if n, _ := claims["nonce"].(string); n != nonce {
+		return Claims{}, fmt.Errorf("%w: nonce mismatch", ErrInvalidToken)
+	}
+
+	sub, _ := claims.GetSubject()
+	if sub == "" {
+		return Claims{}, fmt.Errorf("%w: no subject", ErrInvalidToken)
+	}
+
+	return Claims{
+		Issuer:  issuer,
+		Subject: sub,
+		Raw:     claims,
+	}, nil
+}
+
+// discover fetches provider metadata and keys.
+func (p *Provider) discover(ctx context.Context) (*metadata, *keyfunc.JWKS, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.meta != nil {
+		return p.meta, p.jwks, nil
+	}
+
+	issuer := strings.TrimSuffix(p.cfg.Issuer, "/")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrDiscovery, err)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrDiscovery, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("%w: status %d", ErrDiscovery, resp.StatusCode)
+	}
+
+	var meta metadata
+	if err := json.NewDecoder(resp.Body).Decode(&meta); err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrDiscovery, err)
+	}
+	if strings.TrimSuffix(meta.Issuer, "/") != issuer {
+		return nil, nil, fmt.Errorf("%w: issuer mismatch %q", ErrDiscovery, meta.Issuer)
+	}
+	if meta.AuthorizationEndpoint == "" || meta.TokenEndpoint == "" || meta.JWKSURI == "" {
+		return nil, nil, fmt.Errorf("%w: incomplete metadata", ErrDiscovery)
+	}
+
+	jwks, err := keyfunc.Get(meta.JWKSURI, keyfunc.Options{
+		Client:            p.client,
+		RefreshUnknownKID: true,
+		RefreshRateLimit:  5 * time.Minute,
+		RefreshTimeout:    10 * time.Second,
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrDiscovery, err)
+	}
+
+	p.meta, p.jwks = &meta, jwks
+
+	return p.meta, p.jwks, nil
+}
+
+// NewVerifier returns random PKCE code verifier.
+func NewVerifier() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Challenge returns S256 PKCE code challenge.
+func Challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,126 @@
+package oidc_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/GintGld/fizteh-radio/internal/lib/oidc"
+	"github.com/GintGld/fizteh-radio/internal/lib/oidc/oidctest"
+)
+
+const redirectURL = "http://radio.test/login/oidc/callback"
+
+// authorize follows auth url and returns
+// code and state given to redirect url.
+func authorize(t *testing.T, authURL string) (string, string) {
+	client := &http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	resp, err := client.Get(authURL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+
+	loc, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+
+	return loc.Query().Get("code"), loc.Query().Get("state")
+}
+
+func TestCodeFlow(t *testing.T) {
+	srv := oidctest.NewServer("radio")
+	defer srv.Close()
+
+	srv.SetClaims(map[string]any{
+		"sub":                "42",
+		"preferred_username": "ivanov.ii",
+	})
+
+	p := oidc.New(oidc.Config{
+		Issuer:      srv.URL,
+		ClientID:    "radio",
+		RedirectURL: redirectURL,
+		Scopes:      []string{"openid", "profile"},
+	}, nil)
+	ctx := context.Background()
+
+	verifier, err := oidc.NewVerifier()
+	require.NoError(t, err)
+
+	authURL, err := p.AuthURL(ctx, "state", "nonce", verifier)
+	require.NoError(t, err)
+
+	code, state := authorize(t, authURL)
+	assert.Equal(t, "state", state)
+
+	claims, err := p.Exchange(ctx, code, verifier, "nonce")
+	require.NoError(t, err)
+	assert.Equal(t, srv.URL, claims.Issuer)
+	assert.Equal(t, "42", claims.Subject)
+	assert.Equal(t, "ivanov.ii", claims.String("preferred_username"))
+	assert.Equal(t, "", claims.String("email"))
+
+	// Code can be used only once.
+	_, err = p.Exchange(ctx, code, verifier, "nonce")
+	assert.True(t, errors.Is(err, oidc.ErrExchange))
+}
+
+func TestCodeFlowInvalid(t *testing.T) {
+	srv := oidctest.NewServer("radio")
+	defer srv.Close()
+
+	p := oidc.New(oidc.Config{
+		Issuer:      srv.URL,
+		ClientID:    "radio",
+		RedirectURL: redirectURL,
+	}, nil)
+	ctx := context.Background()
+
+	verifier, err := oidc.NewVerifier()
+	require.NoError(t, err)
+
+	// Wrong PKCE verifier
+	authURL, err := p.AuthURL(ctx, "state", "nonce", verifier)
+	require.NoError(t, err)
+	code, _ := authorize(t, authURL)
+
+	_, err = p.Exchange(ctx, code, verifier+"x", "nonce")
+	assert.True(t, errors.Is(err, oidc.ErrExchange))
+
+	// Wrong nonce
+	authURL, err = p.AuthURL(ctx, "state", "nonce", verifier)
+	require.NoError(t, err)
+	code, _ = authorize(t, authURL)
+
+	_, err = p.Exchange(ctx, code, verifier, "other")
+	assert.True(t, errors.Is(err, oidc.ErrInvalidToken))
+
+	// Token of another client
+	other := oidc.New(oidc.Config{
+		Issuer:      srv.URL,
+		ClientID:    "other",
+		RedirectURL: redirectURL,
+	}, nil)
+	authURL, err = p.AuthURL(ctx, "state", "nonce", verifier)
+	require.NoError(t, err)
+	code, _ = authorize(t, authURL)
+
+	_, err = other.Exchange(ctx, code, verifier, "nonce")
+	assert.Error(t, err)
+}
+
+func TestDiscoveryFailed(t *testing.T) {
+	p := oidc.New(oidc.Config{Issuer: "http://127.0.0.1:1"}, nil)
+
+	_, err := p.AuthURL(context.Background(), "state", "nonce", "verifier")
+	assert.True(t, errors.Is(err, oidc.ErrDiscovery))
+}
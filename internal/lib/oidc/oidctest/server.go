@@ -0,0 +1,196 @@
+// Package oidctest provides mock OpenID provider
+// for tests. Provider approves every authorization
+// request and authenticates user with claims
+// set by SetClaims.
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/GintGld/fizteh-radio/internal/lib/oidc"
+)
+
+const keyID = "oidctest"
+
+type Server struct {
+	*httptest.Server
+	ClientID string
+
+	key *rsa.PrivateKey
+
+	mutex  sync.Mutex
+	claims map[string]any
+	grants map[string]grant
+}
+
+type grant struct {
+	redirect  string
+	challenge string
+	nonce     string
+	claims    map[string]any
+}
+
+// NewServer starts mock provider
+// for client with given id.
+func NewServer(clientID string) *Server {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic("oidctest: " + err.Error())
+	}
+
+	s := &Server{
+		ClientID: clientID,
+		key:      key,
+		claims:   map[string]any{"sub": "user"},
+		grants:   make(map[string]grant),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", s.discovery)
+	mux.HandleFunc("/authorize", s.authorize)
+	mux.HandleFunc("/token", s.token)
+	mux.HandleFunc("/jwks", s.jwks)
+
+	s.Server = httptest.NewServer(mux)
+
+	return s
+}
+
+// SetClaims sets claims of the user
+// authenticated by next authorization requests.
+func (s *Server) SetClaims(claims map[string]any) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.claims = claims
+}
+
+func (s *Server) discovery(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"issuer":                 s.URL,
+		"authorization_endpoint": s.URL + "/authorize",
+		"token_endpoint":         s.URL + "/token",
+		"jwks_uri":               s.URL + "/jwks",
+	})
+}
+
+// authorize approves request and redirects
+// back to client with authorization code.
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	if q.Get("client_id") != s.ClientID || q.Get("response_type") != "code" ||
+		q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	redirect, err := url.Parse(q.Get("redirect_uri"))
+	if err != nil || redirect.String() == "" {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+
+	code, err := oidc.NewVerifier()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	s.mutex.Lock()
+	s.grants[code] = grant{
+		redirect:  redirect.String(),
+		challenge: q.Get("code_challenge"),
+		nonce:     q.Get("nonce"),
+		claims:    s.claims,
+	}
+	s.mutex.Unlock()
+
+	rq := redirect.Query()
+	rq.Set("code", code)
+	rq.Set("state", q.Get("state"))
+	redirect.RawQuery = rq.Encode()
+
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+// token exchanges code for id token
+// checking PKCE verifier.
+func (s *Server) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	s.mutex.Lock()
+	g, ok := s.grants[r.PostForm.Get("code")]
+	delete(s.grants, r.PostForm.Get("code"))
+	s.mutex.Unlock()
+
+	if !ok || r.PostForm.Get("grant_type") != "authorization_code" ||
+		r.PostForm.Get("client_id") != s.ClientID ||
+		r.PostForm.Get("redirect_uri") != g.redirect ||
+		oidc.Challenge(r.PostForm.Get("code_verifier")) != g.challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":   s.URL,
+		"aud":   s.ClientID,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+		"nonce": g.nonce,
+	}
+	for k, v := range g.claims {
+		claims[k] = v
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = keyID
+
+	idToken, err := token.SignedString(s.key)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token": "access",
+		"token_type":   "Bearer",
+		"id_token":     idToken,
+	})
+}
+
+func (s *Server) jwks(w http.ResponseWriter, _ *http.Request) {
+	pub := s.key.PublicKey
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"kid": keyID,
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,126 @@
+// Package throttle limits failed attempts
+// per key with exponential backoff.
+package throttle
+
+import (
+	"sync"
+	"time"
+)
+
+// Policy describes throttling of the key.
+//
+// First FreeAttempts failures are not delayed,
+// each next one doubles delay starting from BaseDelay
+// up to MaxDelay. After LockoutAttempts failures
+// key is locked for Lockout. Failures are forgotten
+// if there were none for Lockout.
+type Policy struct {
+	FreeAttempts    int
+	BaseDelay       time.Duration
+	MaxDelay        time.Duration
+	LockoutAttempts int
+	Lockout         time.Duration
+}
+
+type Throttle struct {
+	policy Policy
+	now    func() time.Time
+
+	mutex   sync.Mutex
+	entries map[string]*entry
+}
+
+type entry struct {
+	failures int
+	last     time.Time
+	until    time.Time
+}
+
+func New(policy Policy) *Throttle {
+	return &Throttle{
+		policy:  policy,
+		now:     time.Now,
+		entries: make(map[string]*entry),
+	}
+}
+
+// Wait returns time left until
+// next attempt for the key is allowed.
+func (t *Throttle) Wait(key string) time.Duration {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	e, ok := t.entries[key]
+	if !ok {
+		return 0
+	}
+
+	if wait := e.until.Sub(t.now()); wait > 0 {
+		return wait
+	}
+
+	return 0
+}
+
+// Fail records failed attempt and
+// returns delay before the next one.
+func (t *Throttle) Fail(key string) time.Duration {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	now := t.now()
+	t.prune(now)
+
+	e, ok := t.entries[key]
+	if !ok {
+		e = &entry{}
+		t.entries[key] = e
+	}
+
+	e.failures++
+	e.last = now
+	e.until = now.Add(t.delay(e.failures))
+
+	return e.until.Sub(now)
+}
+
+// Reset forgets failures of the key.
+func (t *Throttle) Reset(key string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	delete(t.entries, key)
+}
+
+// delay returns delay after given number of failures.
+func (t *Throttle) delay(failures int) time.Duration {
+	p := t.policy
+
+	if p.LockoutAttempts > 0 && failures >= p.LockoutAttempts {
+		return p.Lockout
+	}
+
+	n := failures - p.FreeAttempts
+	if n <= 0 {
+		return 0
+	}
+
+	d := p.BaseDelay
+	for i := 1; i < n && d < p.MaxDelay; i++ {
+		d *= 2
+	}
+	if d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+
+	return d
+}
+
+// prune drops keys without failures for Lockout.
+func (t *Throttle) prune(now time.Time) {
+	for key, e := range t.entries {
+		if now.Sub(e.last) > t.policy.Lockout && now.After(e.until) {
+			delete(t.entries, key)
+		}
+	}
+}
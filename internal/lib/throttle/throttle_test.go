@@ -0,0 +1,45 @@
+package throttle
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThrottle(t *testing.T) {
+	now := time.Unix(0, 0)
+
+	th := New(Policy{
+		FreeAttempts:    2,
+		BaseDelay:       time.Second,
+		MaxDelay:        4 * time.Second,
+		LockoutAttempts: 6,
+		Lockout:         time.Minute,
+	})
+	th.now = func() time.Time { return now }
+
+	delays := make([]time.Duration, 0)
+	for i := 0; i < 6; i++ {
+		delays = append(delays, th.Fail("a"))
+	}
+	assert.Equal(t, []time.Duration{
+		0, 0, time.Second, 2 * time.Second, 4 * time.Second, time.Minute,
+	}, delays)
+
+	assert.Equal(t, time.Minute, th.Wait("a"))
+	assert.Equal(t, time.Duration(0), th.Wait("b"))
+
+	now = now.Add(30 * time.Second)
+	assert.Equal(t, 30*time.Second, th.Wait("a"))
+
+	th.Reset("a")
+	assert.Equal(t, time.Duration(0), th.Wait("a"))
+
+	// failures are forgotten after lockout period
+	th.Fail("b")
+	th.Fail("b")
+	now = now.Add(2 * time.Minute)
+	th.Fail("c")
+	assert.Equal(t, time.Duration(0), th.Fail("b"))
+}
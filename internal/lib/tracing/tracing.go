@@ -0,0 +1,172 @@
+// Package tracing sets up OpenTelemetry tracing
+// and provides helpers to instrument code.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	tracerName = "github.com/GintGld/fizteh-radio"
+
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+)
+
+var ErrUnknownExporter = errors.New("unknown exporter")
+
+// Setup sets global tracer provider exporting spans
+// with given exporter: "otlp" sends them via gRPC
+// to endpoint (e.g. local collector), "stdout" prints them.
+// Tracing is disabled if exporter is empty.
+//
+// Returned function flushes and stops exporter.
+func Setup(ctx context.Context, exporter, endpoint string, insecure bool, sampleRatio float64) (func(context.Context) error, error) {
+	const op = "tracing.Setup"
+
+	var (
+		exp sdktrace.SpanExporter
+		err error
+	)
+	switch exporter {
+	case "":
+		return func(context.Context) error { return nil }, nil
+	case ExporterOTLP:
+		opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(endpoint)}
+		if insecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		exp, err = otlptracegrpc.New(ctx, opts...)
+	case ExporterStdout:
+		exp, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	default:
+		return nil, fmt.Errorf("%s: %w: %q", op, ErrUnknownExporter, exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		semconv.ServiceName("radio"),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+	)
+
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	return provider.Shutdown, nil
+}
+
+// Start starts span with given name.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End records error if it's not nil and ends span.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Middleware starts server span for every request.
+// Trace context is taken from request headers
+// and span is put to the user context.
+func Middleware() fiber.Handler {
+	var (
+		once   sync.Once
+		routes map[string]struct{}
+	)
+
+	return func(c *fiber.Ctx) error {
+		// Routes are known only after app has started.
+		once.Do(func() {
+			routes = make(map[string]struct{})
+			for _, r := range c.App().GetRoutes(true) {
+				routes[r.Method+" "+r.Path] = struct{}{}
+			}
+		})
+
+		// MapCarrier expects lower-case keys,
+		// fasthttp normalizes them to canonical form.
+		carrier := propagation.MapCarrier{}
+		c.Request().Header.VisitAll(func(key, value []byte) {
+			carrier.Set(strings.ToLower(string(key)), string(value))
+		})
+		ctx := otel.GetTextMapPropagator().Extract(c.UserContext(), carrier)
+
+		ctx, span := otel.Tracer(tracerName).Start(ctx, c.Method(),
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(c.Method()),
+				semconv.URLPath(c.Path()),
+			),
+		)
+		defer span.End()
+
+		if id, ok := c.Locals("requestid").(string); ok {
+			span.SetAttributes(attribute.String("request_id", id))
+		}
+
+		c.SetUserContext(ctx)
+
+		err := c.Next()
+
+		status := c.Response().StatusCode()
+		if err != nil {
+			status = fiber.StatusInternalServerError
+			if e, ok := err.(*fiber.Error); ok {
+				status = e.Code
+			}
+		}
+
+		// Span is named by route template to keep
+		// names low-cardinality, last matched route
+		// may be a middleware if request matched no handler.
+		if route := c.Route().Path; hasRoute(routes, c.Method(), route) {
+			span.SetName(c.Method() + " " + route)
+			span.SetAttributes(semconv.HTTPRoute(route))
+		}
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if status >= fiber.StatusInternalServerError {
+			span.SetStatus(codes.Error, fmt.Sprintf("status %d", status))
+		}
+
+		return err
+	}
+}
+
+func hasRoute(routes map[string]struct{}, method, path string) bool {
+	_, ok := routes[method+" "+path]
+	return ok
+}
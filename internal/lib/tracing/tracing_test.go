@@ -0,0 +1,62 @@
+package tracing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+func TestMiddleware(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	sub := fiber.New()
+	sub.Get("/media/:id", func(c *fiber.Ctx) error {
+		_, span := Start(c.UserContext(), "child")
+		End(span, nil)
+		return c.SendString(c.Params("id"))
+	})
+	sub.Get("/fail", func(c *fiber.Ctx) error {
+		return fiber.ErrServiceUnavailable
+	})
+
+	app := fiber.New()
+	app.Use(Middleware())
+	app.Mount("/library", sub)
+
+	const parent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	req := httptest.NewRequest(fiber.MethodGet, "/library/media/1", nil)
+	req.Header.Set("Traceparent", parent)
+	for _, r := range []*http.Request{req, httptest.NewRequest(fiber.MethodGet, "/library/fail", nil)} {
+		resp, err := app.Test(r)
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 3)
+
+	// Child span is a part of incoming trace.
+	child, server, fail := spans[0], spans[1], spans[2]
+	assert.Equal(t, "child", child.Name())
+	assert.Equal(t, server.SpanContext().SpanID(), child.Parent().SpanID())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", server.SpanContext().TraceID().String())
+
+	assert.Equal(t, "GET /library/media/:id", server.Name())
+	assert.Contains(t, server.Attributes(), semconv.HTTPResponseStatusCode(200))
+
+	assert.Equal(t, "GET /library/fail", fail.Name())
+	assert.Equal(t, codes.Error, fail.Status().Code)
+}
@@ -0,0 +1,116 @@
+// Package xlsx writes minimal single-sheet
+// Office Open XML spreadsheets.
+package xlsx
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	contentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>
+<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>
+</Types>`
+
+	rootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>
+</Relationships>`
+
+	workbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="%s" sheetId="1" r:id="rId1"/></sheets>
+</workbook>`
+
+	workbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>
+</Relationships>`
+
+	sheetHeader = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+
+	sheetFooter = `</sheetData></worksheet>`
+)
+
+// Write writes spreadsheet with single sheet.
+// Cells of numeric types are written as numbers,
+// others as strings.
+func Write(w io.Writer, sheet string, rows [][]any) error {
+	z := zip.NewWriter(w)
+
+	files := []struct {
+		name string
+		body string
+	}{
+		{"[Content_Types].xml", contentTypes},
+		{"_rels/.rels", rootRels},
+		{"xl/workbook.xml", fmt.Sprintf(workbook, escape(sheet))},
+		{"xl/_rels/workbook.xml.rels", workbookRels},
+		{"xl/worksheets/sheet1.xml", sheetXML(rows)},
+	}
+
+	for _, f := range files {
+		fw, err := z.Create(f.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(fw, f.body); err != nil {
+			return err
+		}
+	}
+
+	return z.Close()
+}
+
+// sheetXML returns worksheet body.
+func sheetXML(rows [][]any) string {
+	var b strings.Builder
+
+	b.WriteString(sheetHeader)
+	for i, row := range rows {
+		fmt.Fprintf(&b, `<row r="%d">`, i+1)
+		for j, cell := range row {
+			ref := column(j) + strconv.Itoa(i+1)
+			switch v := cell.(type) {
+			case int:
+				fmt.Fprintf(&b, `<c r="%s"><v>%d</v></c>`, ref, v)
+			case int64:
+				fmt.Fprintf(&b, `<c r="%s"><v>%d</v></c>`, ref, v)
+			case float64:
+				fmt.Fprintf(&b, `<c r="%s"><v>%s</v></c>`, ref, strconv.FormatFloat(v, 'f', -1, 64))
+			default:
+				fmt.Fprintf(&b, `<c r="%s" t="inlineStr"><is><t>%s</t></is></c>`, ref, escape(fmt.Sprint(v)))
+			}
+		}
+		b.WriteString(`</row>`)
+	}
+	b.WriteString(sheetFooter)
+
+	return b.String()
+}
+
+// column returns spreadsheet column name
+// by its zero-based index (A, B, ..., Z, AA, ...).
+func column(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
+
+// escape escapes xml special characters.
+func escape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// APIKeyPrefix starts every api key,
+// so keys are easy to recognize.
+const APIKeyPrefix = "rk_"
+
+// APIKey is a long-lived key of machine client
+// (bots, studio automation). Only hash of the key is kept.
+// Expires is nil for keys without expiration.
+type APIKey struct {
+	ID          int64        `json:"id"`
+	Name        string       `json:"name"`
+	Prefix      string       `json:"prefix"`
+	Hash        string       `json:"-"`
+	Permissions []Permission `json:"permissions"`
+	Created     time.Time    `json:"created"`
+	Expires     *time.Time   `json:"expires"`
+}
+
+// APIKeyIn is a form to create api key.
+type APIKeyIn struct {
+	Name        string       `json:"name"`
+	Permissions []Permission `json:"permissions"`
+	Expires     *time.Time   `json:"expires"`
+}
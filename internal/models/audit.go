@@ -0,0 +1,52 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AuditEntry is a record of change
+// made by editor. Before and after are
+// snapshots of the changed entity.
+type AuditEntry struct {
+	ID       int64           `json:"id"`
+	EditorID int64           `json:"editorId"`
+	Login    string          `json:"login"`
+	Action   string          `json:"action"`
+	Entity   string          `json:"entity"`
+	EntityID int64           `json:"entityId,omitempty"`
+	Before   json.RawMessage `json:"before,omitempty"`
+	After    json.RawMessage `json:"after,omitempty"`
+	Time     time.Time       `json:"time"`
+}
+
+// AuditFilter selects audit entries.
+// Empty fields are not used.
+type AuditFilter struct {
+	Start    time.Time
+	Stop     time.Time
+	Login    string
+	Entity   string
+	EntityID int64
+	Limit    int
+}
+
+const (
+	AuditCreate = "create"
+	AuditUpdate = "update"
+	AuditDelete = "delete"
+	AuditStart  = "start"
+	AuditStop   = "stop"
+)
+
+const (
+	AuditMedia    = "media"
+	AuditTag      = "tag"
+	AuditSegment  = "segment"
+	AuditSchedule = "schedule"
+	AuditLive     = "live"
+	AuditAutoDJ   = "autodj"
+	AuditDJConfig = "autodj_config"
+	AuditEditor   = "editor"
+	AuditAPIKey   = "api_key"
+)
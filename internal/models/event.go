@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+type EventType string
+
+const (
+	EventDeadAir           EventType = "dead_air"
+	EventLiveLost          EventType = "live_lost"
+	EventAutoDJStopped     EventType = "autodj_stopped"
+	EventSourceUnreachable EventType = "source_unreachable"
+	EventDiskFilling       EventType = "disk_filling"
+	EventSourceBroken      EventType = "source_broken"
+)
+
+// Event is something ops have
+// to know about (e.g. dead air).
+type Event struct {
+	Type    EventType         `json:"type"`
+	Time    time.Time         `json:"time"`
+	Message string            `json:"message"`
+	Details map[string]string `json:"details,omitempty"`
+}
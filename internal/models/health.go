@@ -0,0 +1,23 @@
+package models
+
+type HealthStatus string
+
+const (
+	HealthOK HealthStatus = "ok"
+	// Component is intentionally
+	// off (e.g. AutoDJ is not started).
+	HealthStopped HealthStatus = "stopped"
+	HealthFail    HealthStatus = "fail"
+)
+
+// Health is a status of the radio
+// and of its components by names.
+type Health struct {
+	Status     HealthStatus               `json:"status"`
+	Components map[string]ComponentHealth `json:"components"`
+}
+
+type ComponentHealth struct {
+	Status HealthStatus `json:"status"`
+	Error  string       `json:"error,omitempty"`
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+type IncidentType string
+
+const (
+	// Nothing is scheduled
+	// for some time window.
+	IncidentDeadAir IncidentType = "dead_air"
+)
+
+// Incident is a failure of playout
+// in time window [Start, Stop).
+type Incident struct {
+	ID       int64        `json:"id"`
+	Type     IncidentType `json:"type"`
+	Detected time.Time    `json:"detected"`
+	Start    time.Time    `json:"start"`
+	Stop     time.Time    `json:"stop"`
+	// What was done to recover.
+	Resolution string `json:"resolution"`
+}
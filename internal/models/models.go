@@ -13,19 +13,42 @@ import (
 // to remove pointers. Add methos to convert/create one from another.
 
 type EditorIn struct {
-	Login string `json:"login"`
-	Pass  string `json:"pass"`
+	Login       string `json:"login"`
+	Pass        string `json:"pass"`
+	Roles       []Role `json:"roles,omitempty"`
+	DisplayName string `json:"displayName,omitempty"`
+	Contact     string `json:"contact,omitempty"`
 }
 
 type EditorOut struct {
-	ID    int64  `json:"id"`
-	Login string `json:"login"`
+	ID          int64  `json:"id"`
+	Login       string `json:"login"`
+	Roles       []Role `json:"roles"`
+	DisplayName string `json:"displayName"`
+	Contact     string `json:"contact"`
 }
 
 type Editor struct {
-	ID       int64  `json:"id"`
-	Login    string `json:"login"`
-	PassHash []byte `json:"pass"`
+	ID          int64  `json:"id"`
+	Login       string `json:"login"`
+	PassHash    []byte `json:"pass"`
+	Roles       []Role `json:"roles"`
+	DisplayName string `json:"displayName"`
+	Contact     string `json:"contact"`
+}
+
+// EditorProfile is editor info
+// that can be changed after registration.
+type EditorProfile struct {
+	DisplayName string `json:"displayName"`
+	Contact     string `json:"contact"`
+}
+
+// Identity is an external identity
+// (OIDC issuer and subject) of the editor.
+type Identity struct {
+	Issuer  string `json:"issuer"`
+	Subject string `json:"subject"`
 }
 
 const (
@@ -42,6 +65,11 @@ type Media struct {
 	Duration *time.Duration `json:"duration"`
 	SourceID *int64         `json:"-"`
 	Tags     TagList        `json:"tags"`
+	// Checksum is sha256 of source.
+	Checksum *string `json:"-"`
+	// Media with broken source
+	// is excluded from AutoDJ.
+	Playable *bool `json:"playable,omitempty"`
 }
 
 type MediaFilter struct {
@@ -78,6 +106,14 @@ type Segment struct {
 	StopCut   *time.Duration `json:"stopCut"`
 	Protected bool           `json:"protected"`
 	LiveId    int64          `json:"liveId,omitempty"`
+	// Playback speed, 0 means normal speed.
+	Tempo float64 `json:"tempo,omitempty"`
+	// Segment is truncated by scheduler
+	// and fades out instead of abrupt stop.
+	FadeOut bool `json:"fadeOut,omitempty"`
+	// Editor created segment,
+	// 0 if created by system.
+	CreatedBy int64 `json:"createdBy,omitempty"`
 }
 
 type Live struct {
@@ -91,17 +127,140 @@ type Live struct {
 
 // End returns time of segment end (UTC).
 func (s Segment) End() time.Time {
-	return s.Start.Add(*s.StopCut - *s.BeginCut)
+	return s.Start.Add(s.Duration())
+}
+
+// Duration returns playing duration of segment.
+func (s Segment) Duration() time.Duration {
+	return time.Duration(float64(*s.StopCut-*s.BeginCut) / s.Speed())
+}
+
+// Speed returns playback speed of segment.
+func (s Segment) Speed() float64 {
+	if s.Tempo == 0 {
+		return 1
+	}
+	return s.Tempo
 }
 
 type AutoDJConfig struct {
-	Tags TagList    `json:"tags"`
-	Stub AutoDJStub `json:"stub"`
+	Tags       TagList          `json:"tags"`
+	Categories []AutoDJCategory `json:"categories"`
+	Sequence   []string         `json:"sequence"`
+	Jingles    AutoDJJingles    `json:"jingles"`
+	Align      AutoDJAlign      `json:"align"`
+	Stub       AutoDJStub       `json:"stub"`
+}
+
+// AutoDJCategory is a pool of media
+// selected by tags. Categories are
+// played in order given by AutoDJConfig.Sequence,
+// or, if sequence is empty, each category
+// is repeated Weight times in a row.
+type AutoDJCategory struct {
+	Name   string  `json:"name"`
+	Tags   TagList `json:"tags"`
+	Weight int     `json:"weight"`
+}
+
+// AutoDJJingles describes short imaging elements
+// (jingles, station IDs, sweepers) inserted between songs.
+type AutoDJJingles struct {
+	// Tags of jingle pool (station IDs).
+	Tags TagList `json:"tags"`
+	// Insert jingle after every N songs, 0 disables the rule.
+	EverySongs int `json:"everySongs"`
+	// Insert jingle exactly at the top of the hour,
+	// song playing at this time is cut.
+	TopOfHour bool `json:"topOfHour"`
+	// Tags of sweeper pool, sweeper ends
+	// exactly at the start of live show.
+	SweeperTags TagList `json:"sweeperTags"`
+}
+
+// AutoDJAlign describes hard-timed mode:
+// tracks before protected segment are chosen
+// to fill the gap instead of cutting the last one.
+type AutoDJAlign struct {
+	Enabled bool `json:"enabled"`
+	// Alignment starts when protected
+	// segment is closer than Window.
+	Window time.Duration `json:"window"`
+	// Allowed gap left by chosen tracks.
+	Tolerance time.Duration `json:"tolerance"`
+	// Maximal relative time-stretch of tracks
+	// (e.g. 0.03 for 3%), 0 disables stretching.
+	MaxStretch float64 `json:"maxStretch"`
 }
 
+// AutoDJStub describes fillers used to
+// fill gaps before protected segments.
+// Gaps longer than Threshold are not filled,
+// the last track is cut with fade out instead.
 type AutoDJStub struct {
 	Threshold time.Duration `json:"threshold"`
-	MediaID   int64         `json:"mediaId"`
+	// Single stub media, 0 if not used.
+	MediaID int64 `json:"mediaId"`
+	// Tags of stub pool.
+	Tags TagList `json:"tags"`
+}
+
+// AutoDJRotation is saved state
+// of AutoDJ category rotation.
+type AutoDJRotation struct {
+	Category string
+	MediaIDs []int64
+	Position int
+}
+
+// AutoDJPlay is a segment put in schedule by AutoDJ.
+// It marks segment origin, airing is recorded in as-run log.
+type AutoDJPlay struct {
+	ID        int64
+	SegmentID int64
+	MediaID   int64
+	Start     time.Time
+	Category  string
+}
+
+// As-run entry sources.
+const (
+	AsRunLive   = "live"
+	AsRunAutoDJ = "autodj"
+	AsRunManual = "manual"
+)
+
+// AsRunEntry is a record of
+// segment that actually aired.
+type AsRunEntry struct {
+	ID           int64         `json:"id"`
+	SegmentID    int64         `json:"segmentId"`
+	MediaID      int64         `json:"mediaId,omitempty"`
+	LiveID       int64         `json:"liveId,omitempty"`
+	Name         string        `json:"name"`
+	Author       string        `json:"author"`
+	PlannedStart time.Time     `json:"plannedStart"`
+	ActualStart  time.Time     `json:"actualStart"`
+	Duration     time.Duration `json:"duration"`
+	Source       string        `json:"source"`
+}
+
+// Report is aggregated airplay
+// statistics for given period.
+type Report struct {
+	Start time.Time   `json:"start"`
+	Stop  time.Time   `json:"stop"`
+	Rows  []ReportRow `json:"rows"`
+}
+
+// ReportRow is airplay statistics of one track.
+type ReportRow struct {
+	MediaID       int64         `json:"mediaId"`
+	Name          string        `json:"name"`
+	Author        string        `json:"author"`
+	Plays         int           `json:"plays"`
+	AiredTime     time.Duration `json:"airedTime"`
+	ListenerHours float64       `json:"listenerHours"`
 }
 
 // specify custom time marshalling since
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// ReconcileReport is a result of
+// source storage integrity check.
+type ReconcileReport struct {
+	Start   time.Time `json:"start"`
+	Stop    time.Time `json:"stop"`
+	Checked int       `json:"checked"`
+	// Media with missing or corrupted source.
+	Broken []BrokenMedia `json:"broken"`
+	// Media playable again.
+	Recovered []int64 `json:"recovered"`
+	// Sources without media, nil if
+	// storage can't list its sources.
+	Orphans []int64 `json:"orphans"`
+	// Parts of check that were skipped.
+	Warnings []string `json:"warnings,omitempty"`
+	// Why check was interrupted.
+	Error string `json:"error,omitempty"`
+}
+
+// BrokenMedia is media which
+// source can't be played.
+type BrokenMedia struct {
+	ID       int64  `json:"id"`
+	SourceID int64  `json:"sourceID"`
+	Reason   string `json:"reason"`
+}
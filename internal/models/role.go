@@ -0,0 +1,89 @@
+package models
+
+import "slices"
+
+// Role is a named set of permissions
+// granted to editor.
+type Role string
+
+const (
+	RoleRoot      Role = "root"
+	RoleLibrarian Role = "librarian"
+	RoleScheduler Role = "scheduler"
+	RoleLiveHost  Role = "live_host"
+	RoleViewer    Role = "viewer"
+)
+
+// Permission allows single kind of action.
+type Permission string
+
+const (
+	PermLibraryRead   Permission = "library:read"
+	PermLibraryWrite  Permission = "library:write"
+	PermScheduleRead  Permission = "schedule:read"
+	PermScheduleWrite Permission = "schedule:write"
+	PermAutoDJ        Permission = "autodj:control"
+	PermLive          Permission = "live:control"
+	PermReports       Permission = "reports:read"
+	PermRadio         Permission = "radio:control"
+	PermEditors       Permission = "editors:manage"
+	// Modify objects created by others.
+	PermOverride Permission = "ownership:override"
+)
+
+var rolePermissions = map[Role][]Permission{
+	RoleRoot: {
+		PermLibraryRead, PermLibraryWrite,
+		PermScheduleRead, PermScheduleWrite,
+		PermAutoDJ, PermLive, PermReports,
+		PermRadio, PermEditors, PermOverride,
+	},
+	RoleLibrarian: {
+		PermLibraryRead, PermLibraryWrite,
+		PermScheduleRead, PermReports,
+	},
+	RoleScheduler: {
+		PermLibraryRead,
+		PermScheduleRead, PermScheduleWrite,
+		PermAutoDJ, PermReports,
+	},
+	RoleLiveHost: {
+		PermLibraryRead, PermScheduleRead, PermLive,
+	},
+	RoleViewer: {
+		PermLibraryRead, PermScheduleRead, PermReports,
+	},
+}
+
+// DefaultRoles are granted to editors
+// registered without explicit roles.
+// They keep access editors had before roles were introduced.
+var DefaultRoles = []Role{RoleLibrarian, RoleScheduler, RoleLiveHost}
+
+// Valid reports if role is known.
+// Root role can't be granted to editors.
+func (r Role) Valid() bool {
+	_, ok := rolePermissions[r]
+	return ok && r != RoleRoot
+}
+
+// Valid reports if permission is known.
+func (p Permission) Valid() bool {
+	return slices.Contains(rolePermissions[RoleRoot], p)
+}
+
+// Permissions returns permissions granted by role.
+func (r Role) Permissions() []Permission {
+	return rolePermissions[r]
+}
+
+// HasPermission reports if any of the roles
+// grants given permission.
+func HasPermission(roles []Role, perm Permission) bool {
+	for _, r := range roles {
+		if slices.Contains(rolePermissions[r], perm) {
+			return true
+		}
+	}
+	return false
+}
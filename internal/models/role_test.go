@@ -0,0 +1,24 @@
+package models_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/GintGld/fizteh-radio/internal/models"
+)
+
+func TestHasPermission(t *testing.T) {
+	require.True(t, models.HasPermission([]models.Role{models.RoleRoot}, models.PermEditors))
+	require.True(t, models.HasPermission(models.DefaultRoles, models.PermLibraryWrite))
+	require.True(t, models.HasPermission(models.DefaultRoles, models.PermLive))
+	require.False(t, models.HasPermission(models.DefaultRoles, models.PermEditors))
+	require.False(t, models.HasPermission([]models.Role{models.RoleViewer}, models.PermScheduleWrite))
+	require.False(t, models.HasPermission(nil, models.PermLibraryRead))
+}
+
+func TestRoleValid(t *testing.T) {
+	require.True(t, models.RoleLibrarian.Valid())
+	require.False(t, models.RoleRoot.Valid())
+	require.False(t, models.Role("admin").Valid())
+}
@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// TokenPair is issued to editor on login.
+type TokenPair struct {
+	Access  string `json:"token"`
+	Refresh string `json:"refresh"`
+}
+
+// RefreshToken is a stored refresh token.
+// Only hash of the token is kept.
+// Tokens obtained one from another share family.
+type RefreshToken struct {
+	Hash     string
+	Family   string
+	EditorID int64
+	Expires  time.Time
+	Used     bool
+}
+
+// Revocation invalidates access tokens
+// either by token id or by owner.
+// Editor revocation invalidates all
+// editor's tokens issued before given time.
+type Revocation struct {
+	TokenID  string
+	EditorID int64
+	Before   time.Time
+	Expires  time.Time
+}
+
+// LoginAttempt is a record of login audit.
+type LoginAttempt struct {
+	ID      int64     `json:"id"`
+	Login   string    `json:"login"`
+	IP      string    `json:"ip"`
+	Success bool      `json:"success"`
+	Reason  string    `json:"reason,omitempty"`
+	Time    time.Time `json:"time"`
+}
+
+const (
+	LoginReasonInvalidCredentials = "invalid credentials"
+)
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// Upload is a resumable upload of media source,
+// media is created when all Length bytes are received.
+type Upload struct {
+	ID      string    `json:"id"`
+	Length  int64     `json:"length"`
+	Offset  int64     `json:"offset"`
+	Media   Media     `json:"media"`
+	Created time.Time `json:"created"`
+	// Set when upload is complete.
+	MediaID *int64 `json:"mediaId,omitempty"`
+}
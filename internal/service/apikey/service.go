@@ -0,0 +1,228 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/GintGld/fizteh-radio/internal/lib/caller"
+	"github.com/GintGld/fizteh-radio/internal/lib/logger/sl"
+	"github.com/GintGld/fizteh-radio/internal/models"
+	"github.com/GintGld/fizteh-radio/internal/service"
+	"github.com/GintGld/fizteh-radio/internal/storage"
+)
+
+// prefixLength is a number of key symbols
+// (after models.APIKeyPrefix) kept to recognize key.
+const prefixLength = 6
+
+// APIKeys manages keys of machine clients.
+// Keys are cached in memory, since they
+// are checked on every request.
+type APIKeys struct {
+	log     *slog.Logger
+	storage APIKeyStorage
+	timeout time.Duration
+
+	mutex sync.RWMutex
+	keys  map[string]models.APIKey
+}
+
+type APIKeyStorage interface {
+	SaveAPIKey(ctx context.Context, key models.APIKey) (int64, error)
+	APIKeys(ctx context.Context) ([]models.APIKey, error)
+	DeleteAPIKey(ctx context.Context, id int64) error
+}
+
+func New(
+	log *slog.Logger,
+	storage APIKeyStorage,
+	timeout time.Duration,
+) *APIKeys {
+	a := &APIKeys{
+		log:     log,
+		storage: storage,
+		timeout: timeout,
+		keys:    make(map[string]models.APIKey),
+	}
+
+	a.load()
+
+	return a
+}
+
+// load reads saved keys.
+func (a *APIKeys) load() {
+	const op = "APIKeys.load"
+
+	log := a.log.With(
+		slog.String("op", op),
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), a.timeout)
+	defer cancel()
+
+	keys, err := a.storage.APIKeys(ctx)
+	if err != nil {
+		log.Error("failed to get api keys", sl.Err(err))
+		return
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	for _, key := range keys {
+		a.keys[key.Hash] = key
+	}
+}
+
+// Create generates new api key.
+// Key itself is returned only once,
+// only its hash is saved.
+//
+// Keys can't manage editors.
+func (a *APIKeys) Create(ctx context.Context, form models.APIKeyIn) (models.APIKey, string, error) {
+	const op = "APIKeys.Create"
+
+	log := a.log.With(
+		slog.String("op", op),
+		caller.Attr(ctx),
+		slog.String("name", form.Name),
+	)
+
+	if len(form.Permissions) == 0 {
+		log.Warn("no permissions given")
+		return models.APIKey{}, "", fmt.Errorf("%s: %w", op, service.ErrInvalidPermission)
+	}
+	for _, perm := range form.Permissions {
+		if !perm.Valid() || perm == models.PermEditors {
+			log.Warn("invalid permission", slog.String("permission", string(perm)))
+			return models.APIKey{}, "", fmt.Errorf("%s: %w", op, service.ErrInvalidPermission)
+		}
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		log.Error("failed to generate key", sl.Err(err))
+		return models.APIKey{}, "", fmt.Errorf("%s: %w", op, err)
+	}
+	secret := models.APIKeyPrefix + hex.EncodeToString(b)
+
+	key := models.APIKey{
+		Name:        form.Name,
+		Prefix:      secret[:len(models.APIKeyPrefix)+prefixLength],
+		Hash:        hashKey(secret),
+		Permissions: form.Permissions,
+		Created:     time.Now(),
+		Expires:     form.Expires,
+	}
+
+	id, err := a.storage.SaveAPIKey(ctx, key)
+	if err != nil {
+		if errors.Is(err, storage.ErrAPIKeyExists) {
+			log.Warn("api key exists")
+			return models.APIKey{}, "", fmt.Errorf("%s: %w", op, service.ErrAPIKeyExists)
+		}
+		if errors.Is(err, storage.ErrContextCancelled) {
+			log.Error("storage.SaveAPIKey timeout exceeded")
+			return models.APIKey{}, "", service.ErrTimeout
+		}
+		log.Error("failed to save api key", sl.Err(err))
+		return models.APIKey{}, "", fmt.Errorf("%s: %w", op, err)
+	}
+	key.ID = id
+
+	a.mutex.Lock()
+	a.keys[key.Hash] = key
+	a.mutex.Unlock()
+
+	log.Info("api key created", slog.Int64("id", id))
+
+	return key, secret, nil
+}
+
+// Keys returns all api keys.
+func (a *APIKeys) Keys(ctx context.Context) ([]models.APIKey, error) {
+	const op = "APIKeys.Keys"
+
+	log := a.log.With(
+		slog.String("op", op),
+		caller.Attr(ctx),
+	)
+
+	keys, err := a.storage.APIKeys(ctx)
+	if err != nil {
+		if errors.Is(err, storage.ErrContextCancelled) {
+			log.Error("storage.APIKeys timeout exceeded")
+			return []models.APIKey{}, service.ErrTimeout
+		}
+		log.Error("failed to get api keys", sl.Err(err))
+		return []models.APIKey{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return keys, nil
+}
+
+// Revoke deletes api key.
+func (a *APIKeys) Revoke(ctx context.Context, id int64) error {
+	const op = "APIKeys.Revoke"
+
+	log := a.log.With(
+		slog.String("op", op),
+		caller.Attr(ctx),
+		slog.Int64("id", id),
+	)
+
+	if err := a.storage.DeleteAPIKey(ctx, id); err != nil {
+		if errors.Is(err, storage.ErrAPIKeyNotFound) {
+			log.Warn("api key not found")
+			return fmt.Errorf("%s: %w", op, service.ErrAPIKeyNotFound)
+		}
+		if errors.Is(err, storage.ErrContextCancelled) {
+			log.Error("storage.DeleteAPIKey timeout exceeded")
+			return service.ErrTimeout
+		}
+		log.Error("failed to delete api key", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	a.mutex.Lock()
+	for hash, key := range a.keys {
+		if key.ID == id {
+			delete(a.keys, hash)
+		}
+	}
+	a.mutex.Unlock()
+
+	log.Info("api key revoked")
+
+	return nil
+}
+
+// Key returns api key if it exists and not expired.
+func (a *APIKeys) Key(secret string) (models.APIKey, bool) {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	key, ok := a.keys[hashKey(secret)]
+	if !ok {
+		return models.APIKey{}, false
+	}
+
+	if key.Expires != nil && key.Expires.Before(time.Now()) {
+		return models.APIKey{}, false
+	}
+
+	return key, true
+}
+
+func hashKey(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
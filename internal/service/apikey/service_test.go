@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/GintGld/fizteh-radio/internal/models"
+	"github.com/GintGld/fizteh-radio/internal/service"
+	"github.com/GintGld/fizteh-radio/internal/storage"
+)
+
+type storageMock struct {
+	keys []models.APIKey
+}
+
+func (s *storageMock) SaveAPIKey(_ context.Context, key models.APIKey) (int64, error) {
+	key.ID = int64(len(s.keys) + 1)
+	s.keys = append(s.keys, key)
+	return key.ID, nil
+}
+
+func (s *storageMock) APIKeys(_ context.Context) ([]models.APIKey, error) {
+	return s.keys, nil
+}
+
+func (s *storageMock) DeleteAPIKey(_ context.Context, id int64) error {
+	for i, k := range s.keys {
+		if k.ID == id {
+			s.keys = append(s.keys[:i], s.keys[i+1:]...)
+			return nil
+		}
+	}
+	return storage.ErrAPIKeyNotFound
+}
+
+func TestAPIKeys(t *testing.T) {
+	st := &storageMock{}
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	a := New(log, st, time.Second)
+	ctx := context.Background()
+
+	key, secret, err := a.Create(ctx, models.APIKeyIn{
+		Name:        "bot",
+		Permissions: []models.Permission{models.PermLibraryRead},
+	})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(secret, models.APIKeyPrefix))
+	assert.True(t, strings.HasPrefix(secret, key.Prefix))
+	assert.NotEqual(t, secret, st.keys[0].Hash)
+
+	got, ok := a.Key(secret)
+	assert.True(t, ok)
+	assert.Equal(t, key.ID, got.ID)
+	assert.Equal(t, []models.Permission{models.PermLibraryRead}, got.Permissions)
+
+	_, ok = a.Key(secret + "x")
+	assert.False(t, ok)
+
+	// Keys are loaded on start.
+	_, ok = New(log, st, time.Second).Key(secret)
+	assert.True(t, ok)
+
+	require.NoError(t, a.Revoke(ctx, key.ID))
+	_, ok = a.Key(secret)
+	assert.False(t, ok)
+
+	err = a.Revoke(ctx, key.ID)
+	assert.True(t, errors.Is(err, service.ErrAPIKeyNotFound))
+}
+
+func TestAPIKeysExpired(t *testing.T) {
+	a := New(slog.New(slog.NewTextHandler(io.Discard, nil)), &storageMock{}, time.Second)
+
+	expires := time.Now().Add(-time.Minute)
+	_, secret, err := a.Create(context.Background(), models.APIKeyIn{
+		Name:        "old",
+		Permissions: []models.Permission{models.PermReports},
+		Expires:     &expires,
+	})
+	require.NoError(t, err)
+
+	_, ok := a.Key(secret)
+	assert.False(t, ok)
+}
+
+func TestAPIKeysPermissions(t *testing.T) {
+	a := New(slog.New(slog.NewTextHandler(io.Discard, nil)), &storageMock{}, time.Second)
+
+	for _, perms := range [][]models.Permission{
+		nil,
+		{"unknown"},
+		{models.PermLibraryRead, models.PermEditors},
+	} {
+		_, _, err := a.Create(context.Background(), models.APIKeyIn{Name: "bot", Permissions: perms})
+		assert.True(t, errors.Is(err, service.ErrInvalidPermission), perms)
+	}
+}
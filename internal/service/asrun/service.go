@@ -0,0 +1,194 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/GintGld/fizteh-radio/internal/lib/caller"
+	"github.com/GintGld/fizteh-radio/internal/lib/logger/sl"
+	"github.com/GintGld/fizteh-radio/internal/models"
+	"github.com/GintGld/fizteh-radio/internal/service"
+	"github.com/GintGld/fizteh-radio/internal/storage"
+)
+
+type AsRun struct {
+	log     *slog.Logger
+	storage AsRunStorage
+	media   MediaInfo
+	live    LiveInfo
+}
+
+type AsRunStorage interface {
+	SaveAsRun(ctx context.Context, entry models.AsRunEntry) error
+	UpdateAsRunStop(ctx context.Context, segmentId int64, plannedStart, stop time.Time) error
+	AsRun(ctx context.Context, start, stop time.Time) ([]models.AsRunEntry, error)
+	IsAutoDJSegment(ctx context.Context, id int64, start time.Time) (bool, error)
+	Incidents(ctx context.Context, start, stop time.Time) ([]models.Incident, error)
+}
+
+type MediaInfo interface {
+	Media(ctx context.Context, id int64) (models.Media, error)
+}
+
+type LiveInfo interface {
+	Info() models.Live
+}
+
+func New(
+	log *slog.Logger,
+	storage AsRunStorage,
+	media MediaInfo,
+	live LiveInfo,
+) *AsRun {
+	return &AsRun{
+		log:     log,
+		storage: storage,
+		media:   media,
+		live:    live,
+	}
+}
+
+// Record adds segment played
+// in given interval to as-run log.
+// Stop may be expected one, it's updated by Finish.
+// Segment already recorded is ignored.
+func (a *AsRun) Record(ctx context.Context, segment models.Segment, start, stop time.Time) error {
+	const op = "AsRun.Record"
+
+	log := a.log.With(
+		slog.String("op", op),
+		caller.Attr(ctx),
+	)
+
+	entry := models.AsRunEntry{
+		SegmentID:    *segment.ID,
+		LiveID:       segment.LiveId,
+		PlannedStart: *segment.Start,
+		ActualStart:  start,
+		Duration:     stop.Sub(start),
+	}
+
+	if segment.LiveId != 0 {
+		entry.Source = models.AsRunLive
+		if live := a.live.Info(); live.ID == segment.LiveId {
+			entry.Name = live.Name
+		}
+	} else {
+		if segment.MediaID != nil {
+			entry.MediaID = *segment.MediaID
+
+			media, err := a.media.Media(ctx, entry.MediaID)
+			if err != nil {
+				if errors.Is(err, service.ErrTimeout) {
+					log.Error("media.Media timeout exceeded")
+					return service.ErrTimeout
+				}
+				if !errors.Is(err, service.ErrMediaNotFound) {
+					log.Error("failed to get media", slog.Int64("id", entry.MediaID), sl.Err(err))
+					return fmt.Errorf("%s: %w", op, err)
+				}
+				log.Warn("media not found, record without name", slog.Int64("id", entry.MediaID))
+			} else {
+				entry.Name = *media.Name
+				entry.Author = *media.Author
+			}
+		}
+
+		isDJ, err := a.storage.IsAutoDJSegment(ctx, *segment.ID, *segment.Start)
+		if err != nil {
+			if errors.Is(err, storage.ErrContextCancelled) {
+				log.Error("storage.IsAutoDJSegment timeout exceeded")
+				return service.ErrTimeout
+			}
+			log.Error("failed to check segment source", slog.Int64("id", *segment.ID), sl.Err(err))
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		entry.Source = models.AsRunManual
+		if isDJ {
+			entry.Source = models.AsRunAutoDJ
+		}
+	}
+
+	if err := a.storage.SaveAsRun(ctx, entry); err != nil {
+		if errors.Is(err, storage.ErrContextCancelled) {
+			log.Error("storage.SaveAsRun timeout exceeded")
+			return service.ErrTimeout
+		}
+		log.Error("failed to save as-run entry", slog.Int64("segment", *segment.ID), sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Finish sets actual stop
+// of the segment recorded.
+func (a *AsRun) Finish(ctx context.Context, segment models.Segment, stop time.Time) error {
+	const op = "AsRun.Finish"
+
+	log := a.log.With(
+		slog.String("op", op),
+		caller.Attr(ctx),
+	)
+
+	if err := a.storage.UpdateAsRunStop(ctx, *segment.ID, *segment.Start, stop); err != nil {
+		if errors.Is(err, storage.ErrContextCancelled) {
+			log.Error("storage.UpdateAsRunStop timeout exceeded")
+			return service.ErrTimeout
+		}
+		log.Error("failed to update as-run entry", slog.Int64("segment", *segment.ID), sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Entries returns as-run entries
+// started in given interval.
+func (a *AsRun) Entries(ctx context.Context, start, stop time.Time) ([]models.AsRunEntry, error) {
+	const op = "AsRun.Entries"
+
+	log := a.log.With(
+		slog.String("op", op),
+		caller.Attr(ctx),
+	)
+
+	res, err := a.storage.AsRun(ctx, start, stop)
+	if err != nil {
+		if errors.Is(err, storage.ErrContextCancelled) {
+			log.Error("storage.AsRun timeout exceeded")
+			return []models.AsRunEntry{}, service.ErrTimeout
+		}
+		log.Error("failed to get as-run entries", sl.Err(err))
+		return []models.AsRunEntry{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return res, nil
+}
+
+// Incidents returns playout incidents
+// (e.g. dead air) started in given interval.
+func (a *AsRun) Incidents(ctx context.Context, start, stop time.Time) ([]models.Incident, error) {
+	const op = "AsRun.Incidents"
+
+	log := a.log.With(
+		slog.String("op", op),
+		caller.Attr(ctx),
+	)
+
+	res, err := a.storage.Incidents(ctx, start, stop)
+	if err != nil {
+		if errors.Is(err, storage.ErrContextCancelled) {
+			log.Error("storage.Incidents timeout exceeded")
+			return []models.Incident{}, service.ErrTimeout
+		}
+		log.Error("failed to get incidents", sl.Err(err))
+		return []models.Incident{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return res, nil
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ptr "github.com/GintGld/fizteh-radio/internal/lib/utils/pointers"
+	"github.com/GintGld/fizteh-radio/internal/models"
+)
+
+type storageMock struct {
+	entries []models.AsRunEntry
+	dj      map[int64]bool
+}
+
+func (s *storageMock) SaveAsRun(_ context.Context, entry models.AsRunEntry) error {
+	s.entries = append(s.entries, entry)
+	return nil
+}
+
+func (s *storageMock) UpdateAsRunStop(_ context.Context, segmentId int64, plannedStart, stop time.Time) error {
+	for i, e := range s.entries {
+		if e.SegmentID == segmentId && e.PlannedStart.Equal(plannedStart) {
+			s.entries[i].Duration = stop.Sub(e.ActualStart)
+		}
+	}
+	return nil
+}
+
+func (s *storageMock) AsRun(_ context.Context, _, _ time.Time) ([]models.AsRunEntry, error) {
+	return s.entries, nil
+}
+
+func (s *storageMock) IsAutoDJSegment(_ context.Context, id int64, _ time.Time) (bool, error) {
+	return s.dj[id], nil
+}
+
+func (s *storageMock) Incidents(_ context.Context, _, _ time.Time) ([]models.Incident, error) {
+	return []models.Incident{}, nil
+}
+
+type mediaMock struct{}
+
+func (mediaMock) Media(_ context.Context, id int64) (models.Media, error) {
+	return models.Media{ID: ptr.Ptr(id), Name: ptr.Ptr("song"), Author: ptr.Ptr("author")}, nil
+}
+
+type liveMock struct{}
+
+func (liveMock) Info() models.Live {
+	return models.Live{ID: 7, Name: "show"}
+}
+
+func TestRecord(t *testing.T) {
+	st := &storageMock{dj: map[int64]bool{1: true}}
+	a := New(slog.New(slog.NewTextHandler(io.Discard, nil)), st, mediaMock{}, liveMock{})
+
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	segment := func(id int64, mediaId *int64, liveId int64) models.Segment {
+		return models.Segment{
+			ID:       ptr.Ptr(id),
+			MediaID:  mediaId,
+			Start:    ptr.Ptr(start),
+			BeginCut: ptr.Ptr[time.Duration](0),
+			StopCut:  ptr.Ptr(time.Minute),
+			LiveId:   liveId,
+		}
+	}
+
+	require.NoError(t, a.Record(context.Background(), segment(1, ptr.Ptr[int64](10), 0), start, start.Add(time.Minute)))
+	require.NoError(t, a.Record(context.Background(), segment(2, ptr.Ptr[int64](11), 0), start.Add(time.Second), start.Add(30*time.Second)))
+	require.NoError(t, a.Record(context.Background(), segment(3, nil, 7), start, start.Add(time.Minute)))
+
+	require.Len(t, st.entries, 3)
+
+	assert.Equal(t, models.AsRunAutoDJ, st.entries[0].Source)
+	assert.Equal(t, "song", st.entries[0].Name)
+	assert.Equal(t, time.Minute, st.entries[0].Duration)
+
+	assert.Equal(t, models.AsRunManual, st.entries[1].Source)
+	assert.Equal(t, start.Add(time.Second), st.entries[1].ActualStart)
+	assert.Equal(t, start, st.entries[1].PlannedStart)
+	assert.Equal(t, 29*time.Second, st.entries[1].Duration)
+
+	assert.Equal(t, models.AsRunLive, st.entries[2].Source)
+	assert.Equal(t, "show", st.entries[2].Name)
+	assert.Equal(t, int64(7), st.entries[2].LiveID)
+
+	require.NoError(t, a.Finish(context.Background(), segment(2, ptr.Ptr[int64](11), 0), start.Add(20*time.Second)))
+	assert.Equal(t, 19*time.Second, st.entries[1].Duration)
+}
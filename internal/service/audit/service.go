@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/GintGld/fizteh-radio/internal/lib/caller"
+	"github.com/GintGld/fizteh-radio/internal/lib/logger/sl"
+	"github.com/GintGld/fizteh-radio/internal/models"
+	"github.com/GintGld/fizteh-radio/internal/service"
+	"github.com/GintGld/fizteh-radio/internal/storage"
+)
+
+type Audit struct {
+	log     *slog.Logger
+	storage AuditStorage
+}
+
+type AuditStorage interface {
+	SaveAudit(ctx context.Context, entry models.AuditEntry) (int64, error)
+	Audit(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error)
+}
+
+func New(
+	log *slog.Logger,
+	storage AuditStorage,
+) *Audit {
+	return &Audit{
+		log:     log,
+		storage: storage,
+	}
+}
+
+// Record saves change made by the
+// caller saved in context. Before and after are snapshots of the
+// entity, nil if there is no such state.
+//
+// Errors are only logged, since change
+// is already made when it is recorded.
+func (a *Audit) Record(
+	ctx context.Context,
+	action string,
+	entity string,
+	id int64,
+	before any,
+	after any,
+) {
+	const op = "Audit.Record"
+
+	log := a.log.With(
+		slog.String("op", op),
+		caller.Attr(ctx),
+		slog.String("action", action),
+		slog.String("entity", entity),
+		slog.Int64("id", id),
+	)
+
+	editor, _ := caller.From(ctx)
+
+	entry := models.AuditEntry{
+		EditorID: editor.ID,
+		Login:    caller.Login(ctx),
+		Action:   action,
+		Entity:   entity,
+		EntityID: id,
+		Time:     time.Now(),
+	}
+
+	var err error
+	if entry.Before, err = snapshot(before); err != nil {
+		log.Error("failed to marshal snapshot", sl.Err(err))
+	}
+	if entry.After, err = snapshot(after); err != nil {
+		log.Error("failed to marshal snapshot", sl.Err(err))
+	}
+
+	if _, err := a.storage.SaveAudit(ctx, entry); err != nil {
+		log.Error("failed to save audit entry", sl.Err(err))
+		return
+	}
+
+	log.Info("change recorded")
+}
+
+// Entries returns audit entries
+// matching filter, latest first.
+func (a *Audit) Entries(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error) {
+	const op = "Audit.Entries"
+
+	log := a.log.With(
+		slog.String("op", op),
+		caller.Attr(ctx),
+	)
+
+	entries, err := a.storage.Audit(ctx, filter)
+	if err != nil {
+		if errors.Is(err, storage.ErrContextCancelled) {
+			log.Error("storage.Audit timeout exceeded")
+			return []models.AuditEntry{}, service.ErrTimeout
+		}
+		log.Error("failed to get audit entries", sl.Err(err))
+		return []models.AuditEntry{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return entries, nil
+}
+
+// snapshot returns json of the entity state.
+func snapshot(v any) (json.RawMessage, error) {
+	if v == nil {
+		return nil, nil
+	}
+	return json.Marshal(v)
+}
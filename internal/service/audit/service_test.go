@@ -0,0 +1,53 @@
+package service
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/GintGld/fizteh-radio/internal/lib/caller"
+	"github.com/GintGld/fizteh-radio/internal/models"
+)
+
+type storageMock struct {
+	entries []models.AuditEntry
+}
+
+func (s *storageMock) SaveAudit(_ context.Context, entry models.AuditEntry) (int64, error) {
+	s.entries = append(s.entries, entry)
+	return int64(len(s.entries)), nil
+}
+
+func (s *storageMock) Audit(_ context.Context, _ models.AuditFilter) ([]models.AuditEntry, error) {
+	return s.entries, nil
+}
+
+func TestRecord(t *testing.T) {
+	st := &storageMock{}
+	a := New(slog.New(slog.NewTextHandler(io.Discard, nil)), st)
+
+	ctx := caller.With(context.Background(), caller.Caller{ID: 3, Login: "editor"})
+
+	a.Record(ctx, models.AuditUpdate, models.AuditTag, 5,
+		models.Tag{ID: 5, Name: "old"},
+		models.Tag{ID: 5, Name: "new"},
+	)
+	a.Record(ctx, models.AuditStart, models.AuditAutoDJ, 0, nil, nil)
+
+	require.Len(t, st.entries, 2)
+
+	e := st.entries[0]
+	assert.Equal(t, int64(3), e.EditorID)
+	assert.Equal(t, "editor", e.Login)
+	assert.Equal(t, int64(5), e.EntityID)
+	assert.JSONEq(t, `{"id":5,"name":"old","type":{"id":0,"name":""},"meta":null}`, string(e.Before))
+	assert.JSONEq(t, `{"id":5,"name":"new","type":{"id":0,"name":""},"meta":null}`, string(e.After))
+	assert.False(t, e.Time.IsZero())
+
+	assert.Nil(t, st.entries[1].Before)
+	assert.Nil(t, st.entries[1].After)
+}
@@ -2,12 +2,18 @@ package auth
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"log/slog"
 	"time"
 
+	"github.com/GintGld/fizteh-radio/internal/lib/caller"
 	"github.com/GintGld/fizteh-radio/internal/lib/logger/sl"
+	"github.com/GintGld/fizteh-radio/internal/lib/throttle"
 	"github.com/GintGld/fizteh-radio/internal/models"
 	"github.com/GintGld/fizteh-radio/internal/service"
 	"github.com/GintGld/fizteh-radio/internal/storage"
@@ -15,14 +21,19 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
-// TODO: enable refesh token
-
 type Auth struct {
-	log           *slog.Logger
-	editorStorage EditorStorage
-	jwtMaker      jwtMaker
-	rootPassHash  []byte
-	tokenTTL      time.Duration
+	log             *slog.Logger
+	editorStorage   EditorStorage
+	tokenStorage    TokenStorage
+	auditStorage    AuditStorage
+	jwtMaker        jwtMaker
+	revoker         Revoker
+	rootPassHash    []byte
+	tokenTTL        time.Duration
+	refreshTTL      time.Duration
+	loginThrottle   *throttle.Throttle
+	ipThrottle      *throttle.Throttle
+	accountThrottle *throttle.Throttle
 }
 
 type jwtMaker interface {
@@ -30,52 +41,196 @@ type jwtMaker interface {
 }
 
 type EditorStorage interface {
+	Editor(ctx context.Context, id int64) (models.Editor, error)
 	EditorByLogin(ctx context.Context, login string) (models.Editor, error)
+	EditorRoles(ctx context.Context, id int64) ([]models.Role, error)
+}
+
+type TokenStorage interface {
+	SaveRefreshToken(ctx context.Context, token models.RefreshToken) error
+	RefreshToken(ctx context.Context, hash string) (models.RefreshToken, error)
+	UseRefreshToken(ctx context.Context, hash string) (bool, error)
+	DeleteRefreshFamily(ctx context.Context, family string) error
+}
+
+type AuditStorage interface {
+	SaveLoginAttempt(ctx context.Context, attempt models.LoginAttempt) error
+}
+
+type Revoker interface {
+	RevokeToken(ctx context.Context, id string, expires time.Time) error
 }
 
 // New returns new instance of authentication service
 func New(
 	log *slog.Logger,
 	editorStorage EditorStorage,
+	tokenStorage TokenStorage,
+	auditStorage AuditStorage,
 	jwtMaker jwtMaker,
+	revoker Revoker,
 	rootPassHash []byte,
 	tokenTTL time.Duration,
+	refreshTTL time.Duration,
+	loginPolicy throttle.Policy,
+	ipPolicy throttle.Policy,
+	accountPolicy throttle.Policy,
 ) *Auth {
 	return &Auth{
-		log:           log,
-		editorStorage: editorStorage,
-		jwtMaker:      jwtMaker,
-		rootPassHash:  rootPassHash,
-		tokenTTL:      tokenTTL,
+		log:             log,
+		editorStorage:   editorStorage,
+		tokenStorage:    tokenStorage,
+		auditStorage:    auditStorage,
+		jwtMaker:        jwtMaker,
+		revoker:         revoker,
+		rootPassHash:    rootPassHash,
+		tokenTTL:        tokenTTL,
+		refreshTTL:      refreshTTL,
+		loginThrottle:   throttle.New(loginPolicy),
+		ipThrottle:      throttle.New(ipPolicy),
+		accountThrottle: throttle.New(accountPolicy),
 	}
 }
 
-// Login checks if editor with given credentials exists in the system and returns access token.
+// Login checks if editor with given credentials exists in the system
+// and returns access and refresh tokens.
 //
 // If editor exists, but password is incorrect, returns error.
 // If editor doesn't exist, returns error.
-func (a *Auth) Login(ctx context.Context, login string, password string) (string, error) {
+// If there were too many failed attempts for the login from
+// the ip, from the ip for any login or for the login from
+// any ip, returns *service.RetryAfterError.
+func (a *Auth) Login(ctx context.Context, login string, password string, ip string) (models.TokenPair, error) {
 	const op = "Auth.Login"
 
-	var token string
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("editorname", login),
+		slog.String("ip", ip),
+	)
+
+	// Lockout is keyed on login and ip together,
+	// so nobody can easily lock a known editor out.
+	// Guesses spread over many ips are limited
+	// by per login counter with higher threshold.
+	loginKey := login + "|" + ip
+
+	// Rejected attempts aren't audited
+	// to prevent flooding the storage.
+	if wait := max(
+		a.loginThrottle.Wait(loginKey),
+		a.ipThrottle.Wait(ip),
+		a.accountThrottle.Wait(login),
+	); wait > 0 {
+		log.Warn("login throttled", slog.Duration("wait", wait))
+		return models.TokenPair{}, fmt.Errorf("%s: %w", op, &service.RetryAfterError{After: wait})
+	}
+
+	var editor models.Editor
 	var err error
 
 	if login == models.RootLogin {
-		token, err = a.loginRoot(ctx, password)
-		if err != nil {
-			return "", fmt.Errorf("%s: %w", op, err)
-		}
+		editor, err = a.loginRoot(ctx, password)
 	} else {
-		token, err = a.loginEditor(ctx, login, password)
-		if err != nil {
-			return "", fmt.Errorf("%s: %w", op, err)
+		editor, err = a.loginEditor(ctx, login, password)
+	}
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidCredentials) {
+			wait := max(
+				a.loginThrottle.Fail(loginKey),
+				a.ipThrottle.Fail(ip),
+				a.accountThrottle.Fail(login),
+			)
+			if wait > 0 {
+				log.Warn("login delayed", slog.Duration("wait", wait))
+			}
+			a.audit(ctx, login, ip, models.LoginReasonInvalidCredentials)
 		}
+		return models.TokenPair{}, fmt.Errorf("%s: %w", op, err)
 	}
 
-	return token, nil
+	// Ip and login counters aren't reset, otherwise
+	// owner of any account could guess passwords
+	// of others without limit.
+	a.loginThrottle.Reset(loginKey)
+	a.audit(ctx, login, ip, "")
+
+	tokens, err := a.issue(ctx, editor, "")
+	if err != nil {
+		return models.TokenPair{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return tokens, nil
 }
 
-func (a *Auth) loginRoot(_ context.Context, password string) (string, error) {
+// LoginExternal returns tokens of the editor
+// authenticated by external identity provider.
+func (a *Auth) LoginExternal(ctx context.Context, id int64, ip string) (models.TokenPair, error) {
+	const op = "Auth.LoginExternal"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int64("id", id),
+	)
+
+	editor, err := a.editorStorage.Editor(ctx, id)
+	if err != nil {
+		if errors.Is(err, storage.ErrEditorNotFound) {
+			log.Warn("editor not found")
+			return models.TokenPair{}, fmt.Errorf("%s: %w", op, service.ErrEditorNotFound)
+		}
+		if errors.Is(err, storage.ErrContextCancelled) {
+			log.Error("editorStorage.Editor timeout exceeded")
+			return models.TokenPair{}, service.ErrTimeout
+		}
+		log.Error("failed to get editor", sl.Err(err))
+		return models.TokenPair{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	editor.Roles, err = a.editorStorage.EditorRoles(ctx, editor.ID)
+	if err != nil {
+		if errors.Is(err, storage.ErrContextCancelled) {
+			log.Error("editorStorage.EditorRoles timeout exceeded")
+			return models.TokenPair{}, service.ErrTimeout
+		}
+		log.Error("failed to get editor roles", sl.Err(err))
+		return models.TokenPair{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	tokens, err := a.issue(ctx, editor, "")
+	if err != nil {
+		return models.TokenPair{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	a.audit(ctx, editor.Login, ip, "")
+
+	log.Info("editor logged in with external identity", slog.String("editorname", editor.Login))
+
+	return tokens, nil
+}
+
+// audit records login attempt.
+// Successful if reason is empty.
+func (a *Auth) audit(ctx context.Context, login, ip, reason string) {
+	const op = "Auth.audit"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("editorname", login),
+	)
+
+	if err := a.auditStorage.SaveLoginAttempt(ctx, models.LoginAttempt{
+		Login:   login,
+		IP:      ip,
+		Success: reason == "",
+		Reason:  reason,
+		Time:    time.Now(),
+	}); err != nil {
+		log.Error("failed to save login attempt", sl.Err(err))
+	}
+}
+
+func (a *Auth) loginRoot(_ context.Context, password string) (models.Editor, error) {
 	const op = "Auth.login.Root"
 
 	log := a.log.With(
@@ -88,22 +243,15 @@ func (a *Auth) loginRoot(_ context.Context, password string) (string, error) {
 	if err := bcrypt.CompareHashAndPassword(a.rootPassHash, []byte(password)); err != nil {
 		log.Info("invalid credentials", sl.Err(err))
 
-		return "", fmt.Errorf("%s: %w", op, service.ErrInvalidCredentials)
+		return models.Editor{}, fmt.Errorf("%s: %w", op, service.ErrInvalidCredentials)
 	}
 
 	log.Info("root logged successfully")
 
-	token, err := a.jwtMaker.NewToken(models.Editor{ID: models.RootID, Login: models.RootLogin}, a.tokenTTL)
-	if err != nil {
-		log.Error("failed to generate token", sl.Err(err))
-
-		return "", fmt.Errorf("%s: %w", op, err)
-	}
-
-	return token, nil
+	return rootEditor(), nil
 }
 
-func (a *Auth) loginEditor(ctx context.Context, login string, password string) (string, error) {
+func (a *Auth) loginEditor(ctx context.Context, login string, password string) (models.Editor, error) {
 	const op = "Auth.loginEditor"
 
 	log := a.log.With(
@@ -117,32 +265,253 @@ func (a *Auth) loginEditor(ctx context.Context, login string, password string) (
 	if err != nil {
 		if errors.Is(err, storage.ErrEditorNotFound) {
 			log.Warn("editor not found", sl.Err(err))
-			return "", fmt.Errorf("%s: %w", op, service.ErrInvalidCredentials)
+			return models.Editor{}, fmt.Errorf("%s: %w", op, service.ErrInvalidCredentials)
 		}
 		if errors.Is(err, storage.ErrContextCancelled) {
 			log.Error("editorStorage.EditorByLogin timeout exceeded")
-			return "", service.ErrTimeout
+			return models.Editor{}, service.ErrTimeout
 		}
 
 		log.Error("failed to get editor", sl.Err(err))
 
-		return "", fmt.Errorf("%s: %w", op, err)
+		return models.Editor{}, fmt.Errorf("%s: %w", op, err)
 	}
 
 	if err := bcrypt.CompareHashAndPassword(editor.PassHash, []byte(password)); err != nil {
 		log.Info("invalid credentials", sl.Err(err))
 
-		return "", fmt.Errorf("%s: %w", op, service.ErrInvalidCredentials)
+		return models.Editor{}, fmt.Errorf("%s: %w", op, service.ErrInvalidCredentials)
+	}
+
+	editor.Roles, err = a.editorStorage.EditorRoles(ctx, editor.ID)
+	if err != nil {
+		if errors.Is(err, storage.ErrContextCancelled) {
+			log.Error("editorStorage.EditorRoles timeout exceeded")
+			return models.Editor{}, service.ErrTimeout
+		}
+
+		log.Error("failed to get editor roles", sl.Err(err))
+
+		return models.Editor{}, fmt.Errorf("%s: %w", op, err)
 	}
 
 	log.Info("editor logged in successfully")
 
-	token, err := a.jwtMaker.NewToken(editor, a.tokenTTL)
+	return editor, nil
+}
+
+// Refresh exchanges refresh token for a new token pair.
+// Used refresh token becomes invalid.
+//
+// If refresh token was already used, the whole chain
+// of tokens obtained from the same login is revoked
+// and error is returned.
+func (a *Auth) Refresh(ctx context.Context, refresh string) (models.TokenPair, error) {
+	const op = "Auth.Refresh"
+
+	log := a.log.With(
+		slog.String("op", op),
+	)
+
+	hash := hashToken(refresh)
+
+	token, err := a.tokenStorage.RefreshToken(ctx, hash)
+	if err != nil {
+		if errors.Is(err, storage.ErrRefreshTokenNotFound) {
+			log.Warn("refresh token not found")
+			return models.TokenPair{}, fmt.Errorf("%s: %w", op, service.ErrInvalidToken)
+		}
+		if errors.Is(err, storage.ErrContextCancelled) {
+			log.Error("tokenStorage.RefreshToken timeout exceeded")
+			return models.TokenPair{}, service.ErrTimeout
+		}
+		log.Error("failed to get refresh token", sl.Err(err))
+		return models.TokenPair{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log = log.With(slog.Int64("editor", token.EditorID))
+
+	if token.Expires.Before(time.Now()) {
+		log.Info("refresh token expired")
+		return models.TokenPair{}, fmt.Errorf("%s: %w", op, service.ErrTimeoutToken)
+	}
+
+	fresh, err := a.tokenStorage.UseRefreshToken(ctx, hash)
+	if err != nil {
+		if errors.Is(err, storage.ErrContextCancelled) {
+			log.Error("tokenStorage.UseRefreshToken timeout exceeded")
+			return models.TokenPair{}, service.ErrTimeout
+		}
+		log.Error("failed to use refresh token", sl.Err(err))
+		return models.TokenPair{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if token.Used || !fresh {
+		log.Warn("refresh token reused, revoking family")
+		if err := a.tokenStorage.DeleteRefreshFamily(ctx, token.Family); err != nil {
+			log.Error("failed to delete refresh family", sl.Err(err))
+		}
+		return models.TokenPair{}, fmt.Errorf("%s: %w", op, service.ErrInvalidToken)
+	}
+
+	var editor models.Editor
+	if token.EditorID == models.RootID {
+		editor = rootEditor()
+	} else {
+		editor, err = a.editorStorage.Editor(ctx, token.EditorID)
+		if err != nil {
+			if errors.Is(err, storage.ErrEditorNotFound) {
+				log.Warn("editor not found")
+				return models.TokenPair{}, fmt.Errorf("%s: %w", op, service.ErrInvalidToken)
+			}
+			if errors.Is(err, storage.ErrContextCancelled) {
+				log.Error("editorStorage.Editor timeout exceeded")
+				return models.TokenPair{}, service.ErrTimeout
+			}
+			log.Error("failed to get editor", sl.Err(err))
+			return models.TokenPair{}, fmt.Errorf("%s: %w", op, err)
+		}
+
+		editor.Roles, err = a.editorStorage.EditorRoles(ctx, editor.ID)
+		if err != nil {
+			if errors.Is(err, storage.ErrContextCancelled) {
+				log.Error("editorStorage.EditorRoles timeout exceeded")
+				return models.TokenPair{}, service.ErrTimeout
+			}
+			log.Error("failed to get editor roles", sl.Err(err))
+			return models.TokenPair{}, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	tokens, err := a.issue(ctx, editor, token.Family)
+	if err != nil {
+		return models.TokenPair{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("tokens refreshed", slog.String("editorname", editor.Login))
+
+	return tokens, nil
+}
+
+// Logout revokes access token and
+// refresh token (if given) of the caller.
+func (a *Auth) Logout(ctx context.Context, tokenId string, expires time.Time, refresh string) error {
+	const op = "Auth.Logout"
+
+	log := a.log.With(
+		slog.String("op", op),
+		caller.Attr(ctx),
+	)
+
+	if err := a.revoker.RevokeToken(ctx, tokenId, expires); err != nil {
+		log.Error("failed to revoke access token", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if refresh == "" {
+		log.Info("editor logged out")
+		return nil
+	}
+
+	token, err := a.tokenStorage.RefreshToken(ctx, hashToken(refresh))
+	if err != nil {
+		if errors.Is(err, storage.ErrRefreshTokenNotFound) {
+			log.Warn("refresh token not found")
+			return fmt.Errorf("%s: %w", op, service.ErrInvalidToken)
+		}
+		if errors.Is(err, storage.ErrContextCancelled) {
+			log.Error("tokenStorage.RefreshToken timeout exceeded")
+			return service.ErrTimeout
+		}
+		log.Error("failed to get refresh token", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if editor, _ := caller.From(ctx); token.EditorID != editor.ID {
+		log.Warn("refresh token belongs to another editor")
+		return fmt.Errorf("%s: %w", op, service.ErrInvalidToken)
+	}
+
+	if err := a.tokenStorage.DeleteRefreshFamily(ctx, token.Family); err != nil {
+		if errors.Is(err, storage.ErrContextCancelled) {
+			log.Error("tokenStorage.DeleteRefreshFamily timeout exceeded")
+			return service.ErrTimeout
+		}
+		log.Error("failed to delete refresh family", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("editor logged out")
+
+	return nil
+}
+
+// issue creates access token and refresh token
+// of the given family (new family if empty).
+func (a *Auth) issue(ctx context.Context, editor models.Editor, family string) (models.TokenPair, error) {
+	const op = "Auth.issue"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("editorname", editor.Login),
+	)
+
+	access, err := a.jwtMaker.NewToken(editor, a.tokenTTL)
 	if err != nil {
 		log.Error("failed to generate token", sl.Err(err))
+		return models.TokenPair{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	refresh, err := randomString()
+	if err != nil {
+		log.Error("failed to generate refresh token", sl.Err(err))
+		return models.TokenPair{}, fmt.Errorf("%s: %w", op, err)
+	}
 
-		return "", fmt.Errorf("%s: %w", op, err)
+	if family == "" {
+		if family, err = randomString(); err != nil {
+			log.Error("failed to generate token family", sl.Err(err))
+			return models.TokenPair{}, fmt.Errorf("%s: %w", op, err)
+		}
 	}
 
-	return token, nil
+	if err := a.tokenStorage.SaveRefreshToken(ctx, models.RefreshToken{
+		Hash:     hashToken(refresh),
+		Family:   family,
+		EditorID: editor.ID,
+		Expires:  time.Now().Add(a.refreshTTL),
+	}); err != nil {
+		if errors.Is(err, storage.ErrContextCancelled) {
+			log.Error("tokenStorage.SaveRefreshToken timeout exceeded")
+			return models.TokenPair{}, service.ErrTimeout
+		}
+		log.Error("failed to save refresh token", sl.Err(err))
+		return models.TokenPair{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return models.TokenPair{
+		Access:  access,
+		Refresh: refresh,
+	}, nil
+}
+
+func rootEditor() models.Editor {
+	return models.Editor{
+		ID:    models.RootID,
+		Login: models.RootLogin,
+		Roles: []models.Role{models.RoleRoot},
+	}
+}
+
+// randomString returns url-safe random string.
+func randomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken returns hash of the token to store.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
@@ -0,0 +1,125 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/GintGld/fizteh-radio/internal/lib/throttle"
+	"github.com/GintGld/fizteh-radio/internal/models"
+	"github.com/GintGld/fizteh-radio/internal/service"
+	"github.com/GintGld/fizteh-radio/internal/storage"
+)
+
+type editorStorageMock struct {
+	editors map[string]models.Editor
+}
+
+func (s editorStorageMock) Editor(_ context.Context, id int64) (models.Editor, error) {
+	for _, e := range s.editors {
+		if e.ID == id {
+			return e, nil
+		}
+	}
+	return models.Editor{}, storage.ErrEditorNotFound
+}
+
+func (s editorStorageMock) EditorByLogin(_ context.Context, login string) (models.Editor, error) {
+	e, ok := s.editors[login]
+	if !ok {
+		return models.Editor{}, storage.ErrEditorNotFound
+	}
+	return e, nil
+}
+
+func (s editorStorageMock) EditorRoles(context.Context, int64) ([]models.Role, error) {
+	return []models.Role{}, nil
+}
+
+type tokenStorageMock struct {
+	TokenStorage
+}
+
+func (tokenStorageMock) SaveRefreshToken(context.Context, models.RefreshToken) error {
+	return nil
+}
+
+type auditStorageMock struct {
+	attempts []models.LoginAttempt
+}
+
+func (a *auditStorageMock) SaveLoginAttempt(_ context.Context, attempt models.LoginAttempt) error {
+	a.attempts = append(a.attempts, attempt)
+	return nil
+}
+
+type jwtMock struct{}
+
+func (jwtMock) NewToken(editor models.Editor, _ time.Duration) (string, error) {
+	return editor.Login, nil
+}
+
+func TestLoginThrottle(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("pass"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	audit := &auditStorageMock{}
+	a := New(
+		slog.New(slog.NewTextHandler(io.Discard, nil)),
+		editorStorageMock{editors: map[string]models.Editor{
+			"alice": {ID: 1, Login: "alice", PassHash: hash},
+			"bob":   {ID: 2, Login: "bob", PassHash: hash},
+		}},
+		tokenStorageMock{}, audit, jwtMock{}, nil,
+		hash, time.Hour, time.Hour,
+		throttle.Policy{LockoutAttempts: 2, Lockout: time.Hour},
+		throttle.Policy{LockoutAttempts: 4, Lockout: time.Hour},
+		throttle.Policy{LockoutAttempts: 3, Lockout: time.Hour},
+	)
+
+	ctx := context.Background()
+	retry := func(err error) bool {
+		var retryErr *service.RetryAfterError
+		return errors.As(err, &retryErr)
+	}
+
+	// Lock alice out from the first ip.
+	for i := 0; i < 2; i++ {
+		_, err := a.Login(ctx, "alice", "wrong", "1.1.1.1")
+		assert.ErrorIs(t, err, service.ErrInvalidCredentials)
+	}
+	_, err = a.Login(ctx, "alice", "pass", "1.1.1.1")
+	assert.True(t, retry(err))
+
+	// Throttled attempt isn't audited.
+	assert.Len(t, audit.attempts, 2)
+
+	// Alice can still log in from another ip.
+	_, err = a.Login(ctx, "alice", "pass", "2.2.2.2")
+	assert.NoError(t, err)
+
+	// Guesses from many ips lock the login out.
+	_, err = a.Login(ctx, "alice", "wrong", "3.3.3.3")
+	assert.ErrorIs(t, err, service.ErrInvalidCredentials)
+	_, err = a.Login(ctx, "alice", "pass", "4.4.4.4")
+	assert.True(t, retry(err))
+
+	// Successful login doesn't reset ip failures.
+	_, err = a.Login(ctx, "bob", "wrong", "5.5.5.5")
+	assert.ErrorIs(t, err, service.ErrInvalidCredentials)
+	_, err = a.Login(ctx, "bob", "pass", "5.5.5.5")
+	assert.NoError(t, err)
+	for _, login := range []string{"carol", "dave", "eve"} {
+		_, err = a.Login(ctx, login, "wrong", "5.5.5.5")
+		assert.ErrorIs(t, err, service.ErrInvalidCredentials)
+	}
+	_, err = a.Login(ctx, "bob", "pass", "5.5.5.5")
+	assert.True(t, retry(err))
+}
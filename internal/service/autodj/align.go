@@ -0,0 +1,148 @@
+package service
+
+import (
+	"slices"
+	"time"
+
+	ptr "github.com/GintGld/fizteh-radio/internal/lib/utils/pointers"
+	"github.com/GintGld/fizteh-radio/internal/models"
+)
+
+// alignCandidates is the number of upcoming
+// tracks considered while aligning.
+const alignCandidates = 30
+
+// alignGap returns segments filling time from the
+// time horizon to the given stop with upcoming tracks,
+// so that protected segment starts exactly in time.
+//
+// Tracks keep rotation order: rotation is advanced
+// only past chosen tracks, upcoming tracks left
+// aside are played next. Gap left
+// by tracks is not longer than tolerance,
+// otherwise tracks are time-stretched (if allowed)
+// to fill the gap entirely.
+// Returns nil if there's no suitable combination,
+// rotation is not changed in this case.
+func (a *AutoDJ) alignGap(conf models.AutoDJAlign, stop time.Time) []models.Segment {
+	gap := stop.Sub(a.timeHorizon)
+	if gap <= 0 {
+		return nil
+	}
+
+	candidates, slots := a.upcoming(alignCandidates)
+
+	durations := make([]time.Duration, 0, len(candidates))
+	for _, m := range candidates {
+		durations = append(durations, *m.Duration)
+	}
+
+	var tempo float64
+
+	chosen := fit(durations, gap)
+	if gap-total(durations, chosen) > conf.Tolerance {
+		if conf.MaxStretch <= 0 {
+			return nil
+		}
+
+		// Take tracks fitting into stretched gap
+		// and change their tempo to fill the gap.
+		chosen = fit(durations, time.Duration(float64(gap)*(1+conf.MaxStretch)))
+		tempo = float64(total(durations, chosen)) / float64(gap)
+		if len(chosen) == 0 || tempo < 1-conf.MaxStretch {
+			return nil
+		}
+	}
+
+	if len(chosen) == 0 {
+		return nil
+	}
+
+	segments := make([]models.Segment, 0, len(chosen))
+	start := a.timeHorizon
+	for _, i := range chosen {
+		a.take(candidates[i], slots[i])
+		segm := models.Segment{
+			MediaID:   ptr.Ptr(*candidates[i].ID),
+			Start:     ptr.Ptr(start),
+			BeginCut:  ptr.Ptr[time.Duration](0),
+			StopCut:   ptr.Ptr(durations[i]),
+			Protected: false,
+			Tempo:     tempo,
+		}
+		segments = append(segments, segm)
+		start = segm.End()
+	}
+
+	return segments
+}
+
+// upcoming returns up to n media going next
+// in rotation and format clock positions
+// right after each of them.
+// Only media left in current pool shuffles
+// are considered, rotation is not changed.
+func (a *AutoDJ) upcoming(n int) ([]models.Media, []int) {
+	positions := make([]int64, len(a.categories))
+	for i, c := range a.categories {
+		positions[i] = c.currentId
+	}
+
+	media := make([]models.Media, 0, n)
+	slots := make([]int, 0, n)
+
+	clockPos := a.clockPos
+	for idle := 0; len(media) < n && idle < len(a.clock); {
+		if clockPos >= len(a.clock) {
+			clockPos = 0
+		}
+		i := a.clock[clockPos]
+		clockPos++
+
+		c := a.categories[i]
+		if int(positions[i]) >= len(c.shuffledIds) {
+			idle++
+			continue
+		}
+		idle = 0
+
+		media = append(media, c.library[c.shuffledIds[positions[i]]])
+		slots = append(slots, clockPos)
+		positions[i]++
+	}
+
+	return media, slots
+}
+
+// take advances rotation past given upcoming media.
+// Media is moved to the current position of its pool,
+// so media skipped over keep their order.
+func (a *AutoDJ) take(media models.Media, clockPos int) {
+	c := a.categories[a.clock[clockPos-1]]
+
+	j := slices.IndexFunc(c.shuffledIds[c.currentId:], func(i int64) bool {
+		return *c.library[i].ID == *media.ID
+	})
+	if j == -1 {
+		return
+	}
+
+	ids := slices.Clone(c.shuffledIds)
+	id := ids[c.currentId+int64(j)]
+	copy(ids[c.currentId+1:], ids[c.currentId:c.currentId+int64(j)])
+	ids[c.currentId] = id
+
+	c.shuffledIds = ids
+	c.currentId++
+	a.clockPos = clockPos
+	a.picked[*media.ID] = clockPos
+}
+
+// total returns total duration of chosen items.
+func total(durations []time.Duration, chosen []int) time.Duration {
+	var sum time.Duration
+	for _, i := range chosen {
+		sum += durations[i]
+	}
+	return sum
+}
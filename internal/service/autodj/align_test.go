@@ -0,0 +1,100 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	ptr "github.com/GintGld/fizteh-radio/internal/lib/utils/pointers"
+	"github.com/GintGld/fizteh-radio/internal/models"
+)
+
+func TestAlignGap(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 50, 0, 0, time.UTC)
+
+	newDJ := func(durations ...time.Duration) *AutoDJ {
+		c := &category{}
+		for i, d := range durations {
+			c.library = append(c.library, models.Media{ID: ptr.Ptr(int64(i + 1)), Duration: ptr.Ptr(d)})
+		}
+		a := &AutoDJ{
+			timeHorizon: start,
+			categories:  []*category{c},
+			clock:       []int{0},
+		}
+		a.updateIndices()
+		return a
+	}
+
+	t.Run("within tolerance", func(t *testing.T) {
+		a := newDJ(4*time.Minute, 3*time.Minute, 5*time.Minute, 2*time.Minute)
+		stop := start.Add(10*time.Minute + 20*time.Second)
+
+		segments := a.alignGap(models.AutoDJAlign{Tolerance: 30 * time.Second}, stop)
+		if assert.NotEmpty(t, segments) {
+			last := segments[len(segments)-1].End()
+			assert.False(t, last.After(stop))
+			assert.LessOrEqual(t, stop.Sub(last), 30*time.Second)
+			for _, s := range segments {
+				assert.Zero(t, s.Tempo)
+			}
+		}
+	})
+
+	t.Run("stretch", func(t *testing.T) {
+		a := newDJ(4*time.Minute, 4*time.Minute)
+		stop := start.Add(8*time.Minute + 10*time.Second)
+
+		segments := a.alignGap(models.AutoDJAlign{MaxStretch: 0.05}, stop)
+		if assert.Len(t, segments, 2) {
+			assert.Less(t, segments[0].Tempo, 1.)
+			assert.WithinDuration(t, stop, segments[1].End(), time.Millisecond)
+		}
+	})
+
+	t.Run("keeps rotation order", func(t *testing.T) {
+		a := newDJ(4*time.Minute, 3*time.Minute, 5*time.Minute, 2*time.Minute)
+		order := a.categories[0].order()
+		durations := make(map[int64]time.Duration)
+		for _, m := range a.categories[0].library {
+			durations[*m.ID] = *m.Duration
+		}
+
+		// Gap is filled by the first and the third
+		// upcoming tracks, the second one is left aside.
+		stop := start.Add(durations[order[0]] + durations[order[2]])
+
+		segments := a.alignGap(models.AutoDJAlign{}, stop)
+		if assert.NotEmpty(t, segments) {
+			assert.Equal(t, stop, segments[len(segments)-1].End())
+		}
+
+		chosen := make(map[int64]bool)
+		for _, s := range segments {
+			chosen[*s.MediaID] = true
+		}
+
+		// Skipped tracks are played next in the same order.
+		for _, id := range order {
+			if chosen[id] {
+				continue
+			}
+			media, err := a.nextMedia()
+			if assert.NoError(t, err) {
+				assert.Equal(t, id, *media.ID)
+			}
+		}
+	})
+
+	t.Run("no combination", func(t *testing.T) {
+		a := newDJ(4*time.Minute, 4*time.Minute)
+		order := a.categories[0].order()
+
+		segments := a.alignGap(models.AutoDJAlign{Tolerance: time.Second}, start.Add(6*time.Minute))
+		assert.Nil(t, segments)
+		assert.Equal(t, order, a.categories[0].order())
+		assert.Zero(t, a.categories[0].currentId)
+		assert.Zero(t, a.clockPos)
+	})
+}
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"math/rand"
+
+	"github.com/GintGld/fizteh-radio/internal/models"
+)
+
+// category is a rotation pool
+// of media satisfying one AutoDJ category.
+type category struct {
+	name        string
+	library     []models.Media
+	shuffledIds []int64
+	currentId   int64
+}
+
+// empty reports if category has no media.
+func (c *category) empty() bool {
+	return len(c.library) == 0
+}
+
+// next returns next media from the pool.
+// When pool is exhausted it is reshuffled.
+func (c *category) next() models.Media {
+	media := c.peek()
+	c.currentId++
+
+	return media
+}
+
+// peek returns media that will be
+// returned by next call of next.
+func (c *category) peek() models.Media {
+	if int(c.currentId) >= len(c.shuffledIds) {
+		c.shuffle()
+	}
+
+	return c.library[c.shuffledIds[c.currentId]]
+}
+
+// shuffle updates shuffled indices.
+// New order never starts with the
+// media played last (if possible).
+func (c *category) shuffle() {
+	var lastId int64 = -1
+	if len(c.shuffledIds) > 0 && c.currentId > 0 {
+		lastId = c.shuffledIds[c.currentId-1]
+	}
+
+	perm := rand.Perm(len(c.library))
+	if len(perm) > 1 && int64(perm[0]) == lastId {
+		j := 1 + rand.Intn(len(perm)-1)
+		perm[0], perm[j] = perm[j], perm[0]
+	}
+
+	c.shuffledIds = make([]int64, 0, len(perm))
+	for _, id := range perm {
+		c.shuffledIds = append(c.shuffledIds, int64(id))
+	}
+
+	c.currentId = 0
+}
+
+// rewind moves pool position
+// right after given media.
+// Returns false if media is not in the pool.
+func (c *category) rewind(mediaId int64) bool {
+	for i, id := range c.shuffledIds {
+		if *c.library[id].ID == mediaId {
+			c.currentId = int64(i) + 1
+			return true
+		}
+	}
+	return false
+}
+
+// order returns media ids
+// of the pool in rotation order.
+func (c *category) order() []int64 {
+	ids := make([]int64, 0, len(c.shuffledIds))
+	for _, i := range c.shuffledIds {
+		ids = append(ids, *c.library[i].ID)
+	}
+	return ids
+}
+
+// restore sets rotation order by media ids
+// and position in it. Ids absent in the pool
+// are skipped, media absent in order
+// are appended in random order.
+func (c *category) restore(ids []int64, position int) {
+	index := make(map[int64]int64, len(c.library))
+	for i, m := range c.library {
+		index[*m.ID] = int64(i)
+	}
+
+	c.shuffledIds = make([]int64, 0, len(c.library))
+	c.currentId = 0
+	for j, id := range ids {
+		i, ok := index[id]
+		if !ok {
+			continue
+		}
+		delete(index, id)
+		c.shuffledIds = append(c.shuffledIds, i)
+		if j < position {
+			c.currentId++
+		}
+	}
+
+	rest := make([]int64, 0, len(index))
+	for _, i := range index {
+		rest = append(rest, i)
+	}
+	rand.Shuffle(len(rest), func(i, j int) { rest[i], rest[j] = rest[j], rest[i] })
+	c.shuffledIds = append(c.shuffledIds, rest...)
+}
+
+// buildClock returns format clock:
+// sequence of category indices to play in.
+//
+// If sequence is given, it's used as is
+// (unknown names are skipped),
+// otherwise every category is repeated
+// its weight times (at least once).
+func buildClock(categories []models.AutoDJCategory, sequence []string) []int {
+	clock := make([]int, 0)
+
+	if len(sequence) > 0 {
+		for _, name := range sequence {
+			for i, c := range categories {
+				if c.Name == name {
+					clock = append(clock, i)
+					break
+				}
+			}
+		}
+		return clock
+	}
+
+	for i, c := range categories {
+		for j := 0; j < max(c.Weight, 1); j++ {
+			clock = append(clock, i)
+		}
+	}
+
+	return clock
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	ptr "github.com/GintGld/fizteh-radio/internal/lib/utils/pointers"
+	"github.com/GintGld/fizteh-radio/internal/models"
+)
+
+func TestBuildClock(t *testing.T) {
+	categories := []models.AutoDJCategory{
+		{Name: "hits", Weight: 2},
+		{Name: "gold", Weight: 1},
+		{Name: "russian"},
+	}
+
+	testCases := []struct {
+		desc     string
+		sequence []string
+		expect   []int
+	}{
+		{
+			desc:     "weights",
+			sequence: nil,
+			expect:   []int{0, 0, 1, 2},
+		},
+		{
+			desc:     "fixed sequence",
+			sequence: []string{"gold", "hits", "russian", "hits"},
+			expect:   []int{1, 0, 2, 0},
+		},
+		{
+			desc:     "unknown category in sequence",
+			sequence: []string{"hits", "unknown", "gold"},
+			expect:   []int{0, 1},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			assert.Equal(t, tc.expect, buildClock(categories, tc.sequence))
+		})
+	}
+}
+
+func TestCategoryRotation(t *testing.T) {
+	c := &category{}
+	for i := int64(1); i <= 5; i++ {
+		c.library = append(c.library, models.Media{ID: ptr.Ptr(i)})
+	}
+
+	// Every media is played once per round.
+	for round := 0; round < 3; round++ {
+		played := make(map[int64]bool)
+		for range c.library {
+			played[*c.next().ID] = true
+		}
+		assert.Len(t, played, len(c.library))
+	}
+
+	// Rewind continues right after given media.
+	first := *c.next().ID
+	second := *c.next().ID
+	c.next()
+	assert.True(t, c.rewind(first))
+	assert.Equal(t, second, *c.next().ID)
+	assert.False(t, c.rewind(100))
+}
+
+func TestCategorySingleMedia(t *testing.T) {
+	c := &category{library: []models.Media{{ID: ptr.Ptr[int64](1)}}}
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, int64(1), *c.next().ID)
+	}
+}
+
+func TestCategoryRestore(t *testing.T) {
+	c := &category{}
+	for i := int64(1); i <= 4; i++ {
+		c.library = append(c.library, models.Media{ID: ptr.Ptr(i)})
+	}
+
+	// Media 5 was deleted, media 4 is new.
+	c.restore([]int64{3, 5, 1, 2}, 2)
+
+	assert.Equal(t, []int64{3, 1, 2, 4}, c.order())
+	assert.Equal(t, int64(1), *c.next().ID)
+	assert.Equal(t, int64(2), *c.next().ID)
+	assert.Equal(t, int64(4), *c.next().ID)
+}
@@ -0,0 +1,63 @@
+package service
+
+import "time"
+
+// fitQuant is the precision of gap fitting.
+const fitQuant = 100 * time.Millisecond
+
+// fit solves subset sum problem:
+// returns indices of durations with maximal
+// total duration not exceeding target.
+//
+// Durations are rounded up to fitQuant,
+// so real total never exceeds target.
+// Indices are returned in ascending order.
+func fit(durations []time.Duration, target time.Duration) []int {
+	if target <= 0 {
+		return nil
+	}
+
+	size := int(target / fitQuant)
+
+	// by[s] is index of item by which
+	// sum s was reached first, -1 if unreachable.
+	by := make([]int, size+1)
+	for i := range by {
+		by[i] = -1
+	}
+
+	reached := make([]bool, size+1)
+	reached[0] = true
+
+	for i, d := range durations {
+		w := int((d + fitQuant - 1) / fitQuant)
+		if w <= 0 || w > size {
+			continue
+		}
+		for s := size; s >= w; s-- {
+			if !reached[s] && reached[s-w] {
+				reached[s] = true
+				by[s] = i
+			}
+		}
+	}
+
+	best := size
+	for !reached[best] {
+		best--
+	}
+
+	res := make([]int, 0)
+	for s := best; s > 0; {
+		i := by[s]
+		res = append(res, i)
+		s -= int((durations[i] + fitQuant - 1) / fitQuant)
+	}
+
+	// Restore ascending order.
+	for l, r := 0, len(res)-1; l < r; l, r = l+1, r-1 {
+		res[l], res[r] = res[r], res[l]
+	}
+
+	return res
+}
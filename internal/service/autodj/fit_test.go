@@ -0,0 +1,94 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	ptr "github.com/GintGld/fizteh-radio/internal/lib/utils/pointers"
+	"github.com/GintGld/fizteh-radio/internal/models"
+)
+
+func TestFit(t *testing.T) {
+	testCases := []struct {
+		desc      string
+		durations []time.Duration
+		target    time.Duration
+		expect    []int
+	}{
+		{
+			desc:      "exact",
+			durations: []time.Duration{5 * time.Second, 7 * time.Second, 3 * time.Second},
+			target:    10 * time.Second,
+			expect:    []int{1, 2},
+		},
+		{
+			desc:      "best under target",
+			durations: []time.Duration{6 * time.Second, 6 * time.Second, 4 * time.Second},
+			target:    11 * time.Second,
+			expect:    []int{0, 2},
+		},
+		{
+			desc:      "everything is too long",
+			durations: []time.Duration{20 * time.Second},
+			target:    10 * time.Second,
+			expect:    []int{},
+		},
+		{
+			desc:      "empty target",
+			durations: []time.Duration{time.Second},
+			target:    0,
+			expect:    nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			assert.Equal(t, tc.expect, fit(tc.durations, tc.target))
+		})
+	}
+}
+
+func TestFillGap(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	a := &AutoDJ{
+		timeHorizon: start,
+		stubs: &category{library: []models.Media{
+			{ID: ptr.Ptr[int64](1), Duration: ptr.Ptr(7 * time.Second)},
+			{ID: ptr.Ptr[int64](2), Duration: ptr.Ptr(15 * time.Second)},
+		}},
+	}
+
+	testCases := []struct {
+		desc string
+		gap  time.Duration
+	}{
+		{desc: "exact", gap: 22 * time.Second},
+		{desc: "repeat stubs", gap: 30 * time.Second},
+		{desc: "cut last stub", gap: 10 * time.Second},
+		{desc: "shorter than any stub", gap: 5 * time.Second},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			stop := start.Add(tc.gap)
+			segments := a.fillGap(stop)
+
+			if assert.NotEmpty(t, segments) {
+				assert.Equal(t, start, *segments[0].Start)
+				for i := 1; i < len(segments); i++ {
+					assert.Equal(t, segments[i-1].End(), *segments[i].Start)
+				}
+				assert.Equal(t, stop, segments[len(segments)-1].End())
+				for _, s := range segments {
+					assert.Equal(t, *s.StopCut < 7*time.Second || (*s.StopCut > 7*time.Second && *s.StopCut < 15*time.Second), s.FadeOut)
+				}
+			}
+		})
+	}
+
+	a.stubs = nil
+	assert.Nil(t, a.fillGap(start.Add(time.Minute)))
+}
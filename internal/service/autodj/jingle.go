@@ -0,0 +1,115 @@
+package service
+
+import (
+	"time"
+
+	ptr "github.com/GintGld/fizteh-radio/internal/lib/utils/pointers"
+	"github.com/GintGld/fizteh-radio/internal/models"
+)
+
+// nextItem returns next media to put in schedule:
+// jingle if any jingle rule fires, song otherwise.
+// Second value reports if jingle was chosen.
+func (a *AutoDJ) nextItem(conf models.AutoDJJingles) (models.Media, bool, error) {
+	if a.jingleDue(conf) {
+		return a.jingles.next(), true, nil
+	}
+
+	media, err := a.nextMedia()
+	return media, false, err
+}
+
+// jingleDue reports if jingle
+// must be played at time horizon.
+func (a *AutoDJ) jingleDue(conf models.AutoDJJingles) bool {
+	if a.jingles == nil || a.jingles.empty() {
+		return false
+	}
+
+	if conf.EverySongs > 0 && a.songsSinceJingle >= conf.EverySongs {
+		return true
+	}
+
+	// Time horizon is exactly at the top of the hour
+	// (e.g. protected segment ended there).
+	hour := hourStart(a.timeHorizon)
+	if conf.TopOfHour && hour.After(a.lastJingleHour) && a.timeHorizon.Sub(hour) < timeDelta {
+		return true
+	}
+
+	return false
+}
+
+// hourCrossed reports if segment starting at the
+// time horizon with given length crosses the top
+// of the hour, where jingle must be played.
+// Returns the top of the hour.
+func (a *AutoDJ) hourCrossed(conf models.AutoDJJingles, length time.Duration) (time.Time, bool) {
+	if !conf.TopOfHour || a.jingles == nil || a.jingles.empty() {
+		return time.Time{}, false
+	}
+
+	hour := hourStart(a.timeHorizon).Add(time.Hour)
+	if !hour.After(a.lastJingleHour) || !a.timeHorizon.Add(length).After(hour) {
+		return time.Time{}, false
+	}
+
+	return hour, true
+}
+
+// countPlayed updates jingle rules
+// state after item was put in schedule.
+func (a *AutoDJ) countPlayed(jingle bool, start time.Time) {
+	if jingle {
+		a.songsSinceJingle = 0
+		a.lastJingleHour = hourStart(start)
+		return
+	}
+	a.songsSinceJingle++
+}
+
+// resetJingles resets jingle rules state.
+// Top of the current hour is
+// considered as already passed.
+func (a *AutoDJ) resetJingles(now time.Time) {
+	a.songsSinceJingle = 0
+	a.lastJingleHour = hourStart(now)
+}
+
+// sweeperBefore returns sweeper segment
+// ending exactly at the given live show start.
+// Returns false if there's no sweeper or it doesn't
+// fit between the time horizon and the show.
+// Sweeper pool isn't advanced until the segment is used.
+func (a *AutoDJ) sweeperBefore(live time.Time) (models.Segment, bool) {
+	if a.sweepers == nil || a.sweepers.empty() {
+		return models.Segment{}, false
+	}
+
+	sweeper := a.sweepers.peek()
+	start := live.Add(-*sweeper.Duration)
+	if start.Before(a.timeHorizon) {
+		return models.Segment{}, false
+	}
+
+	return imagingSegment(sweeper, start), true
+}
+
+// imagingSegment returns segment playing
+// whole imaging item (jingle or sweeper)
+// starting at given time.
+func imagingSegment(media models.Media, start time.Time) models.Segment {
+	return models.Segment{
+		MediaID:   ptr.Ptr(*media.ID),
+		Start:     ptr.Ptr(start),
+		BeginCut:  ptr.Ptr[time.Duration](0),
+		StopCut:   ptr.Ptr(*media.Duration),
+		Protected: false,
+	}
+}
+
+// hourStart returns beginning
+// of the hour containing t.
+func hourStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
+}
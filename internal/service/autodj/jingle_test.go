@@ -0,0 +1,103 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	ptr "github.com/GintGld/fizteh-radio/internal/lib/utils/pointers"
+	"github.com/GintGld/fizteh-radio/internal/models"
+)
+
+func TestJingleDue(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 50, 0, 0, time.UTC)
+
+	a := &AutoDJ{
+		jingles: &category{library: []models.Media{{ID: ptr.Ptr[int64](1)}}},
+	}
+	a.timeHorizon = now
+	a.resetJingles(now)
+
+	conf := models.AutoDJJingles{EverySongs: 2, TopOfHour: true}
+
+	assert.False(t, a.jingleDue(conf))
+	a.countPlayed(false, now)
+	assert.False(t, a.jingleDue(conf))
+	a.countPlayed(false, now)
+	assert.True(t, a.jingleDue(conf))
+	a.countPlayed(true, now)
+	assert.False(t, a.jingleDue(conf))
+
+	// Top of the hour is passed.
+	a.timeHorizon = now.Add(15 * time.Minute)
+	assert.False(t, a.jingleDue(conf))
+
+	// Exactly at the top of the hour.
+	a.timeHorizon = now.Add(10 * time.Minute)
+	assert.True(t, a.jingleDue(conf))
+	a.countPlayed(true, a.timeHorizon)
+	assert.False(t, a.jingleDue(conf))
+
+	// No jingles, no rules.
+	a.jingles = nil
+	a.songsSinceJingle = 10
+	assert.False(t, a.jingleDue(conf))
+}
+
+func TestHourCrossed(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 58, 0, 0, time.UTC)
+	hour := time.Date(2024, 1, 1, 13, 0, 0, 0, time.UTC)
+
+	a := &AutoDJ{
+		jingles: &category{library: []models.Media{{ID: ptr.Ptr[int64](1)}}},
+	}
+	a.timeHorizon = now
+	a.resetJingles(now)
+
+	conf := models.AutoDJJingles{TopOfHour: true}
+
+	got, ok := a.hourCrossed(conf, 3*time.Minute)
+	assert.True(t, ok)
+	assert.Equal(t, hour, got)
+
+	// Segment ends before the hour.
+	_, ok = a.hourCrossed(conf, time.Minute)
+	assert.False(t, ok)
+
+	// Jingle at this hour is already played.
+	a.countPlayed(true, hour)
+	_, ok = a.hourCrossed(conf, 3*time.Minute)
+	assert.False(t, ok)
+
+	// Rule is disabled.
+	a.resetJingles(now)
+	_, ok = a.hourCrossed(models.AutoDJJingles{}, 3*time.Minute)
+	assert.False(t, ok)
+}
+
+func TestSweeperBefore(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	live := start.Add(time.Minute)
+
+	a := &AutoDJ{
+		sweepers: &category{library: []models.Media{{ID: ptr.Ptr[int64](2), Duration: ptr.Ptr(10 * time.Second)}}},
+	}
+	a.timeHorizon = start
+
+	segm, ok := a.sweeperBefore(live)
+	if assert.True(t, ok) {
+		assert.Equal(t, int64(2), *segm.MediaID)
+		assert.Equal(t, live.Add(-10*time.Second), *segm.Start)
+		assert.Equal(t, live, segm.End())
+	}
+
+	// Sweeper doesn't fit.
+	_, ok = a.sweeperBefore(start.Add(5 * time.Second))
+	assert.False(t, ok)
+
+	// No sweepers.
+	a.sweepers = nil
+	_, ok = a.sweeperBefore(live)
+	assert.False(t, ok)
+}
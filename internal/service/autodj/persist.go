@@ -0,0 +1,196 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"slices"
+
+	"github.com/GintGld/fizteh-radio/internal/lib/logger/sl"
+	"github.com/GintGld/fizteh-radio/internal/models"
+	"github.com/GintGld/fizteh-radio/internal/storage"
+)
+
+// defaultCategory is the name of
+// the pool used if there are no categories.
+const defaultCategory = ""
+
+// recoverConfig reads saved config.
+// If there is no saved config, legacy
+// cache file is imported (if given).
+func (a *AutoDJ) recoverConfig() {
+	const op = "AutoDJ.recoverConfig"
+
+	log := a.log.With(
+		slog.String("op", op),
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), a.timeout)
+	defer cancel()
+
+	conf, err := a.storage.AutoDJConfig(ctx)
+	if err == nil {
+		a.conf = conf
+		return
+	}
+	if !errors.Is(err, storage.ErrAutoDJConfigNotFound) {
+		log.Error("failed to get config", sl.Err(err))
+		return
+	}
+
+	if a.cacheFile == "" {
+		return
+	}
+
+	body, err := os.ReadFile(a.cacheFile)
+	if err != nil {
+		log.Warn("failed to read legacy config file", slog.String("file", a.cacheFile), sl.Err(err))
+		return
+	}
+
+	if err := json.Unmarshal(body, &conf); err != nil {
+		log.Error("failed to parse legacy config", slog.String("file", a.cacheFile), sl.Err(err))
+		return
+	}
+
+	a.conf = conf
+	a.saveConfig()
+
+	log.Info("legacy config imported", slog.String("file", a.cacheFile))
+}
+
+// saveConfig saves config.
+// does not use mutex, since called only
+// from SetConfing, which already mutex config.
+func (a *AutoDJ) saveConfig() {
+	const op = "AutoDJ.saveConfig"
+
+	log := a.log.With(
+		slog.String("op", op),
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), a.timeout)
+	defer cancel()
+
+	if err := a.storage.SaveAutoDJConfig(ctx, a.conf); err != nil {
+		log.Error("failed to save config", sl.Err(err))
+	}
+}
+
+// loadRotation recovers saved rotation state.
+// Categories are matched by name.
+func (a *AutoDJ) loadRotation(ctx context.Context) {
+	const op = "AutoDJ.loadRotation"
+
+	log := a.log.With(
+		slog.String("op", op),
+	)
+
+	ctx, cancel := context.WithTimeout(ctx, a.timeout)
+	defer cancel()
+
+	clockPos, rotation, err := a.storage.AutoDJRotation(ctx)
+	if err != nil {
+		log.Error("failed to get rotation", sl.Err(err))
+		return
+	}
+
+	for _, r := range rotation {
+		for _, c := range a.categories {
+			if c.name == r.Category {
+				c.restore(r.MediaIDs, r.Position)
+			}
+		}
+	}
+
+	if clockPos < len(a.clock) {
+		a.clockPos = clockPos
+	}
+
+	// Restored state may differ from the saved one
+	// (e.g. library changed), save it anew.
+	a.storedRotation = nil
+}
+
+// storeRotation saves rotation state
+// if it changed since the last save.
+func (a *AutoDJ) storeRotation(ctx context.Context) {
+	const op = "AutoDJ.storeRotation"
+
+	log := a.log.With(
+		slog.String("op", op),
+	)
+
+	ctx, cancel := context.WithTimeout(ctx, a.timeout)
+	defer cancel()
+
+	rotation := make([]models.AutoDJRotation, 0, len(a.categories))
+	for _, c := range a.categories {
+		rotation = append(rotation, models.AutoDJRotation{
+			Category: c.name,
+			MediaIDs: c.order(),
+			Position: int(c.currentId),
+		})
+	}
+
+	if a.storedRotation != nil &&
+		a.storedClockPos == a.clockPos &&
+		slices.EqualFunc(a.storedRotation, rotation, equalRotation) {
+		return
+	}
+
+	if err := a.storage.SaveAutoDJRotation(ctx, a.clockPos, rotation); err != nil {
+		log.Error("failed to save rotation", sl.Err(err))
+		return
+	}
+
+	a.storedClockPos, a.storedRotation = a.clockPos, rotation
+}
+
+// equalRotation reports if rotation states are equal.
+func equalRotation(r1, r2 models.AutoDJRotation) bool {
+	return r1.Category == r2.Category &&
+		r1.Position == r2.Position &&
+		slices.Equal(r1.MediaIDs, r2.MediaIDs)
+}
+
+// recordPlay records segment put in schedule.
+func (a *AutoDJ) recordPlay(ctx context.Context, id int64, segm models.Segment) {
+	const op = "AutoDJ.recordPlay"
+
+	log := a.log.With(
+		slog.String("op", op),
+	)
+
+	if _, err := a.storage.SaveAutoDJPlay(ctx, models.AutoDJPlay{
+		SegmentID: id,
+		MediaID:   *segm.MediaID,
+		Start:     *segm.Start,
+		Category:  a.poolOf(*segm.MediaID),
+	}); err != nil {
+		log.Error("failed to record segment", slog.Int64("segment", id), sl.Err(err))
+	}
+}
+
+// poolOf returns name of the pool
+// given media was taken from.
+func (a *AutoDJ) poolOf(mediaId int64) string {
+	if pos, ok := a.picked[mediaId]; ok && pos > 0 && pos <= len(a.clock) {
+		return a.categories[a.clock[pos-1]].name
+	}
+
+	for _, c := range []*category{a.jingles, a.sweepers, a.stubs} {
+		if c == nil {
+			continue
+		}
+		for _, m := range c.library {
+			if *m.ID == mediaId {
+				return c.name
+			}
+		}
+	}
+
+	return defaultCategory
+}
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	ptr "github.com/GintGld/fizteh-radio/internal/lib/utils/pointers"
+	"github.com/GintGld/fizteh-radio/internal/models"
+)
+
+type djStorageMock struct {
+	DJStorage
+	rotationSaves int
+}
+
+func (s *djStorageMock) SaveAutoDJRotation(ctx context.Context, clockPos int, rotation []models.AutoDJRotation) error {
+	s.rotationSaves++
+	return nil
+}
+
+func TestStoreRotation(t *testing.T) {
+	st := &djStorageMock{}
+
+	c := &category{name: "new"}
+	for i := 0; i < 3; i++ {
+		c.library = append(c.library, models.Media{ID: ptr.Ptr(int64(i + 1)), Duration: ptr.Ptr(time.Minute)})
+	}
+	a := &AutoDJ{
+		log:        slog.New(slog.NewTextHandler(io.Discard, nil)),
+		timeout:    time.Second,
+		storage:    st,
+		categories: []*category{c},
+		clock:      []int{0},
+	}
+	a.updateIndices()
+
+	a.storeRotation(context.Background())
+	assert.Equal(t, 1, st.rotationSaves)
+
+	// Nothing changed.
+	a.storeRotation(context.Background())
+	assert.Equal(t, 1, st.rotationSaves)
+
+	_, err := a.nextMedia()
+	assert.NoError(t, err)
+	a.storeRotation(context.Background())
+	assert.Equal(t, 2, st.rotationSaves)
+}
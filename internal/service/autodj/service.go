@@ -2,18 +2,17 @@ package service
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"log/slog"
-	"math/rand"
-	"os"
 	"slices"
 	"sync"
 	"time"
 
+	"github.com/GintGld/fizteh-radio/internal/lib/caller"
+	"github.com/GintGld/fizteh-radio/internal/lib/heartbeat"
 	"github.com/GintGld/fizteh-radio/internal/lib/logger/sl"
+	"github.com/GintGld/fizteh-radio/internal/lib/metrics"
 	chans "github.com/GintGld/fizteh-radio/internal/lib/utils/channels"
 	ptr "github.com/GintGld/fizteh-radio/internal/lib/utils/pointers"
 	"github.com/GintGld/fizteh-radio/internal/models"
@@ -21,7 +20,6 @@ import (
 	"github.com/gofiber/fiber/v2/log"
 )
 
-// TODO: save config and restore it
 // TODO: move segmentsBuff to config
 
 const (
@@ -42,6 +40,8 @@ type AutoDJ struct {
 	timeout time.Duration
 	media   MediaSearcher
 	sch     Schedule
+	storage DJStorage
+	events  Events
 	conf    models.AutoDJConfig
 
 	// External notifying channels
@@ -55,24 +55,34 @@ type AutoDJ struct {
 	stopChan  chan struct{}
 	confMutex sync.Mutex
 	runMutex  sync.Mutex
+	heartbeat heartbeat.Heartbeat
 
 	// Cache
 	timeHorizon       time.Time
-	library           []models.Media
-	stub              models.Media
+	categories        []*category
+	clock             []int
+	clockPos          int
+	picked            map[int64]int
+	storedClockPos    int
+	storedRotation    []models.AutoDJRotation
+	jingles           *category
+	sweepers          *category
+	songsSinceJingle  int
+	lastJingleHour    time.Time
+	stubs             *category
 	protectedSegments []models.Segment
-	shuffledIds       []int64
-	currentId         int64
 	timerId           int
-	// stubWasUsed       bool
-	cacheFile string
+	cacheFile         string
 }
 
 func New(
+	ctx context.Context,
 	log *slog.Logger,
 	timeout time.Duration,
 	media MediaSearcher,
 	sch Schedule,
+	storage DJStorage,
+	events Events,
 	cacheFile string,
 	scheduleChan <-chan struct{},
 	mediaChan <-chan struct{},
@@ -82,6 +92,8 @@ func New(
 		timeout: timeout,
 		media:   media,
 		sch:     sch,
+		storage: storage,
+		events:  events,
 		conf: models.AutoDJConfig{
 			Tags: make(models.TagList, 0),
 			Stub: models.AutoDJStub{
@@ -97,7 +109,7 @@ func New(
 	}
 
 	a.recoverConfig()
-	go a.redirectChannels(context.TODO())
+	go a.redirectChannels(ctx)
 
 	return a
 }
@@ -113,6 +125,18 @@ type Schedule interface {
 	ClearSchedule(ctx context.Context, from time.Time) error
 }
 
+type DJStorage interface {
+	AutoDJConfig(ctx context.Context) (models.AutoDJConfig, error)
+	SaveAutoDJConfig(ctx context.Context, conf models.AutoDJConfig) error
+	AutoDJRotation(ctx context.Context) (int, []models.AutoDJRotation, error)
+	SaveAutoDJRotation(ctx context.Context, clockPos int, rotation []models.AutoDJRotation) error
+	SaveAutoDJPlay(ctx context.Context, play models.AutoDJPlay) (int64, error)
+}
+
+type Events interface {
+	Publish(e models.Event)
+}
+
 // SetConfig updates AutoDJ settings.
 func (a *AutoDJ) SetConfig(conf models.AutoDJConfig) {
 	a.confMutex.Lock()
@@ -138,12 +162,26 @@ func (a *AutoDJ) Config() models.AutoDJConfig {
 // given cirteria.
 // Empty time is time not reserved
 // by protected segments.
+//
+// Event is published if AutoDJ stopped with an error.
 func (a *AutoDJ) Run(ctx context.Context) error {
+	err := a.run(ctx)
+	if err != nil {
+		a.events.Publish(models.Event{
+			Type:    models.EventAutoDJStopped,
+			Time:    time.Now(),
+			Message: "AutoDJ stopped: " + err.Error(),
+		})
+	}
+	return err
+}
+
+func (a *AutoDJ) run(ctx context.Context) error {
 	const op = "AutoDJ.Run"
 
 	log := a.log.With(
 		slog.String("op", op),
-		slog.String("editorname", models.RootLogin),
+		caller.Attr(ctx),
 	)
 
 	// mutex to prevent multiple
@@ -153,6 +191,9 @@ func (a *AutoDJ) Run(ctx context.Context) error {
 	}
 	defer a.runMutex.Unlock()
 
+	a.heartbeat.Start()
+	defer a.heartbeat.Stop()
+
 	log.Info("start autodj")
 
 dj_start:
@@ -170,8 +211,10 @@ dj_start:
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
-	// Setup shuffled indices.
+	// Setup shuffled indices
+	// and recover saved rotation.
 	a.updateIndices()
+	a.loadRotation(ctx)
 
 	// Update protected segments info.
 	ctxUpdProt, cancelUpdProt := context.WithTimeout(ctx, a.timeout)
@@ -216,6 +259,8 @@ dj_start:
 
 	log.Debug("start time", slog.Time("", a.timeHorizon))
 
+	a.resetJingles(a.timeHorizon)
+
 main_loop:
 	for {
 		// Count how many dj segment
@@ -260,6 +305,8 @@ main_loop:
 			}
 		}
 
+		a.storeRotation(ctx)
+
 	select_case_with_time:
 		ctxTimer, cancelTimer := context.WithTimeout(ctx, a.timeout)
 		defer cancelTimer()
@@ -268,6 +315,7 @@ main_loop:
 			if errors.Is(err, service.ErrTimeout) {
 				log.Error("getTimer timeout exceeded, set timer to timeDelta", slog.Float64("timeDelta", timeDelta.Seconds()))
 				timer = time.After(timeDelta)
+				a.heartbeat.Beat(timeDelta)
 			} else {
 				log.Error("failed to get timer", sl.Err(err))
 				return fmt.Errorf("%s: %w", op, err)
@@ -306,6 +354,7 @@ main_loop:
 				}
 			}
 			a.updateIndices()
+			a.loadRotation(ctx)
 		case <-a.stopChan:
 			log.Debug("got stop chan")
 			break main_loop
@@ -324,7 +373,13 @@ main_loop:
 // New segment starts at the end of the previous one.
 //
 // If there's an intersection with protected segment,
-// AutoDJ prevents it by cutting the segment.
+// AutoDJ prevents it by filling the gap with stubs
+// (if gap is shorter than stub threshold),
+// by aligning upcoming tracks (in hard-timed mode)
+// or by cutting the segment.
+// Live show is preceded by sweeper ending exactly
+// at its start, segment crossing the top of the hour
+// is cut to play jingle exactly at the hour (if enabled).
 func (a *AutoDJ) addSegment(ctx context.Context) error {
 	const op = "AutoDJ.addSegment"
 
@@ -332,13 +387,67 @@ func (a *AutoDJ) addSegment(ctx context.Context) error {
 		slog.String("op", op),
 	)
 
-	// conf := a.Config()
+	conf := a.Config()
+
+	// Get id for the nearest protected segment
+	// to prevent segment intersection.
+	protectedId := a.nearestProtectedSegment()
+
+	// Time to fill up to: start of the protected
+	// segment or start of the sweeper before live show.
+	var (
+		stop    time.Time
+		sweeper *models.Segment
+	)
+	if protectedId != -1 {
+		protected := a.protectedSegments[protectedId]
+		stop = *protected.Start
+		if protected.LiveId != 0 {
+			if s, ok := a.sweeperBefore(stop); ok {
+				stop = *s.Start
+				sweeper = &s
+			}
+		}
+	}
+
+	// Time horizon reached the sweeper.
+	if sweeper != nil && !a.timeHorizon.Before(stop) {
+		return a.saveBeforeProtected(ctx, protectedId, nil, sweeper)
+	}
+
+	// Handle small time with stubs.
+	if protectedId != -1 {
+		if gap := stop.Sub(a.timeHorizon); gap > 0 && gap <= conf.Stub.Threshold {
+			if stubs := a.fillGap(stop); len(stubs) > 0 {
+				log.Debug("fill gap with stubs", slog.Int("stubs", len(stubs)))
+				return a.saveBeforeProtected(ctx, protectedId, stubs, sweeper)
+			}
+		}
+	}
+
+	// Hard-timed alignment to protected segment.
+	if protectedId != -1 && conf.Align.Enabled {
+		if gap := stop.Sub(a.timeHorizon); gap > conf.Stub.Threshold && gap <= conf.Align.Window {
+			if tracks := a.alignGap(conf.Align, stop); len(tracks) > 0 {
+				log.Debug("align tracks to protected segment", slog.Int("tracks", len(tracks)))
+				for _, t := range tracks {
+					a.countPlayed(false, *t.Start)
+				}
+				a.timeHorizon = tracks[len(tracks)-1].End()
+				if tracks[0].Tempo != 0 || !a.timeHorizon.Before(stop) {
+					return a.saveBeforeProtected(ctx, protectedId, tracks, sweeper)
+				}
+				// The rest of the gap is filled in usual way.
+				return a.saveSegments(ctx, tracks)
+			}
+		}
+	}
 
 	// Get next media to put in schedule.
-	media := a.library[a.shuffledIds[a.currentId]]
-	a.currentId++
-	if int(a.currentId) >= len(a.shuffledIds) {
-		a.currentId = 0
+	media, jingle, err := a.nextItem(conf.Jingles)
+	if err != nil {
+		log.Error("no media to play")
+		return err
 	}
 
 	// Create new segment
@@ -350,52 +459,73 @@ func (a *AutoDJ) addSegment(ctx context.Context) error {
 		Protected: false,
 	}
 
-	// Get id for the nearest protected segment
-	// to prevent segment intersection.
-	protectedId := a.nearestProtectedSegment()
+	a.countPlayed(jingle, *newSegm.Start)
 
-	if protectedId != -1 &&
-		a.timeHorizon.Add(*media.Duration).After(*a.protectedSegments[protectedId].Start) {
-		protectedStart := *a.protectedSegments[protectedId].Start
-
-		// TODO enable stubs
-		// Handle small time with stub
-		// if protectedStart.Sub(a.timeHorizon) <= conf.Stub.Threshold {
-		// 	newSegm = models.Segment{
-		// 		MediaID:   ptr.Ptr(*a.stub.ID),
-		// 		Start:     ptr.Ptr(a.timeHorizon),
-		// 		BeginCut:  ptr.Ptr[time.Duration](0),
-		// 		StopCut:   ptr.Ptr(*a.stub.Duration),
-		// 		Protected: false,
-		// 	}
-		// 	a.stubWasUsed = true
-		// } else {
-		// 	// Handle possible intersection
-		// 	// by cutting the end of new segment.
-		// 	cut := protectedStart.Sub(a.timeHorizon)
-		// 	newSegm.StopCut = &cut
-		// }
-		cut := protectedStart.Sub(a.timeHorizon)
-		newSegm.StopCut = &cut
-
-		// Shift autodj horizon to the end of
-		// protected media series.
-		i := protectedId + 1
-		for i < len(a.protectedSegments) &&
-			a.protectedSegments[i].Start.Sub(*a.protectedSegments[i-1].Start) < timeDelta {
-			i++
-		}
-		s := a.protectedSegments[i-1]
-		a.timeHorizon = s.Start.Add(*s.StopCut - *s.BeginCut)
-
-		// Delete protected segments that
-		// already got around.
-		if i < len(a.protectedSegments) {
-			a.protectedSegments = a.protectedSegments[i:]
-		} else {
-			a.protectedSegments = nil
+	// Handle possible intersection
+	// by cutting the end of new segment
+	// (it fades out).
+	if protectedId != -1 && a.timeHorizon.Add(*media.Duration).After(stop) {
+		newSegm.StopCut = ptr.Ptr(stop.Sub(a.timeHorizon))
+		newSegm.FadeOut = true
+		return a.saveBeforeProtected(ctx, protectedId, []models.Segment{newSegm}, sweeper)
+	}
+
+	// Cut segment to play jingle at the top of the hour,
+	// unless protected segment starts there.
+	if hour, ok := a.hourCrossed(conf.Jingles, *media.Duration); ok {
+		station := imagingSegment(a.jingles.peek(), hour)
+		if protectedId == -1 || !station.End().After(stop) {
+			a.jingles.next()
+			newSegm.StopCut = ptr.Ptr(hour.Sub(a.timeHorizon))
+			newSegm.FadeOut = true
+			a.countPlayed(true, hour)
+			a.timeHorizon = station.End()
+			return a.saveSegments(ctx, []models.Segment{newSegm, station})
 		}
+	}
+
+	// Move time horizon.
+	a.timeHorizon = a.timeHorizon.Add(*newSegm.StopCut)
+
+	return a.saveSegments(ctx, []models.Segment{newSegm})
+}
+
+// saveBeforeProtected adds segments filling time up to
+// protected segment followed by sweeper (if any)
+// and shifts time horizon after protected segment.
+func (a *AutoDJ) saveBeforeProtected(ctx context.Context, protectedId int, segments []models.Segment, sweeper *models.Segment) error {
+	if sweeper != nil {
+		a.sweepers.next()
+		segments = append(segments, *sweeper)
+	}
+	a.passProtected(protectedId)
 
+	return a.saveSegments(ctx, segments)
+}
+
+// passProtected shifts autodj horizon to the end of
+// protected media series started with given segment.
+func (a *AutoDJ) passProtected(protectedId int) {
+	i := protectedId + 1
+	for i < len(a.protectedSegments) &&
+		a.protectedSegments[i].Start.Sub(*a.protectedSegments[i-1].Start) < timeDelta {
+		i++
+	}
+	s := a.protectedSegments[i-1]
+	a.timeHorizon = s.End()
+
+	// Delete protected segments that
+	// already got around.
+	if i < len(a.protectedSegments) {
+		a.protectedSegments = a.protectedSegments[i:]
+	} else {
+		a.protectedSegments = nil
+	}
+}
+
+// saveSegments adds segments to the schedule.
+func (a *AutoDJ) saveSegments(ctx context.Context, segments []models.Segment) error {
+	for _, s := range segments {
 		// FIXME: crutch, fix it may be
 		// If supposed segment has zero duration,
 		// just skip its adding to schedule.
@@ -403,16 +533,28 @@ func (a *AutoDJ) addSegment(ctx context.Context) error {
 		// during stacking protected segments,
 		// dj tries to put segment after first protected one
 		// and the next step cuts it to zero.
-		if cut == 0 {
-			return nil
+		if s.Duration() == 0 {
+			continue
+		}
+		if err := a.saveSegment(ctx, s); err != nil {
+			return err
 		}
-	} else {
-		// Move time horizon.
-		a.timeHorizon = a.timeHorizon.Add(*newSegm.StopCut)
 	}
 
-	// Add new segment.
-	if _, err := a.sch.NewSegment(ctx, newSegm); err != nil {
+	return nil
+}
+
+// saveSegment adds segment to the schedule.
+func (a *AutoDJ) saveSegment(ctx context.Context, newSegm models.Segment) error {
+	const op = "AutoDJ.saveSegment"
+
+	log := a.log.With(
+		slog.String("op", op),
+	)
+
+	id, err := a.sch.NewSegment(ctx, newSegm)
+	if err != nil {
+		metrics.AutoDJFailures.Inc()
 		if errors.Is(err, service.ErrSegmentIntersection) {
 			log.Error(
 				"failed to add segment (intersection)",
@@ -428,13 +570,16 @@ func (a *AutoDJ) addSegment(ctx context.Context) error {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
+	metrics.AutoDJSegments.Inc()
+	a.recordPlay(ctx, id, newSegm)
+
 	return nil
 }
 
 // getTimer returns timer to wait before
 // add new segment.
 func (a *AutoDJ) getTimer(ctx context.Context) (<-chan time.Time, error) {
-	if a.timerId >= len(a.library) {
+	if a.timerId >= a.librarySize() {
 		a.timerId = 0
 	}
 
@@ -457,13 +602,21 @@ func (a *AutoDJ) getTimer(ctx context.Context) (<-chan time.Time, error) {
 
 	// No dj periods
 	if j == -1 {
+		a.heartbeat.Beat(0)
 		return time.After(0), nil
 	}
 
-	return time.After(time.Until(*sch[j].Start)), nil
+	wait := time.Until(*sch[j].Start)
+	a.heartbeat.Beat(wait)
+
+	return time.After(wait), nil
 }
 
 // updateLibrary updates library via current config.
+//
+// Every category gets its own pool,
+// if there are no categories, single pool
+// is made by common tags.
 func (a *AutoDJ) updateLibrary(ctx context.Context) error {
 	const op = "AutoDJ.updateLibrary"
 
@@ -476,61 +629,190 @@ func (a *AutoDJ) updateLibrary(ctx context.Context) error {
 	var err error
 
 	conf := a.Config()
-	tagNames := make([]string, len(conf.Tags))
-	for i, t := range conf.Tags {
-		tagNames[i] = t.Name
+
+	categories := conf.Categories
+	if len(categories) == 0 {
+		categories = []models.AutoDJCategory{{Name: defaultCategory, Weight: 1}}
 	}
 
-	a.library, err = a.media.SearchMedia(ctx, models.MediaFilter{Tags: tagNames})
-	if err != nil {
-		if errors.Is(err, service.ErrTimeout) {
-			log.Error("media.SearchMedia timeout exceeded")
-			return service.ErrTimeout
+	a.categories = make([]*category, len(categories))
+	for i, c := range categories {
+		tags := append(tagNames(conf.Tags), tagNames(c.Tags)...)
+
+		a.categories[i], err = a.searchPool(ctx, c.Name, tags)
+		if err != nil {
+			return err
 		}
-		log.Error("failed to update library", sl.Err(err))
-		return fmt.Errorf("%s: %w", op, err)
 	}
 
-	if len(a.library) == 0 {
+	if a.librarySize() == 0 {
 		log.Error("library is empty, stop autodj")
 		return service.ErrMediaNotFound
 	}
 
-	// Update tail media
+	a.clock = buildClock(categories, conf.Sequence)
+	if !slices.ContainsFunc(a.clock, func(i int) bool { return !a.categories[i].empty() }) {
+		log.Error("format clock has no media, stop autodj", slog.Any("sequence", conf.Sequence))
+		return service.ErrMediaNotFound
+	}
+
+	// Update imaging pools.
+	a.jingles, a.sweepers = nil, nil
+	if len(conf.Jingles.Tags) > 0 {
+		if a.jingles, err = a.searchPool(ctx, "jingles", tagNames(conf.Jingles.Tags)); err != nil {
+			return err
+		}
+	}
+	if len(conf.Jingles.SweeperTags) > 0 {
+		if a.sweepers, err = a.searchPool(ctx, "sweepers", tagNames(conf.Jingles.SweeperTags)); err != nil {
+			return err
+		}
+	}
+
+	// Update stubs.
+	a.stubs = nil
 	if conf.Stub.Threshold > 0 {
-		a.stub, err = a.media.Media(ctx, conf.Stub.MediaID)
-		if err != nil {
-			if errors.Is(err, service.ErrMediaNotFound) {
-				log.Error("invalid tail media", slog.Int64("id", conf.Stub.MediaID))
-				return service.ErrMediaNotFound
+		a.stubs = &category{name: "stubs"}
+		if len(conf.Stub.Tags) > 0 {
+			if a.stubs, err = a.searchPool(ctx, "stubs", tagNames(conf.Stub.Tags)); err != nil {
+				return err
 			}
-			log.Error("failed to get tail media, disable tail", slog.Int64("id", conf.Stub.MediaID))
-			return fmt.Errorf("%s: %w", op, err)
+		}
+		if conf.Stub.MediaID != 0 {
+			stub, err := a.media.Media(ctx, conf.Stub.MediaID)
+			if err != nil {
+				if errors.Is(err, service.ErrMediaNotFound) {
+					log.Error("invalid tail media", slog.Int64("id", conf.Stub.MediaID))
+					return service.ErrMediaNotFound
+				}
+				log.Error("failed to get tail media, disable tail", slog.Int64("id", conf.Stub.MediaID))
+				return fmt.Errorf("%s: %w", op, err)
+			}
+			if !playable(stub) {
+				log.Warn("tail media is unplayable", slog.Int64("id", conf.Stub.MediaID))
+			} else if !slices.ContainsFunc(a.stubs.library, func(m models.Media) bool { return *m.ID == *stub.ID }) {
+				a.stubs.library = append(a.stubs.library, stub)
+			}
+		}
+		if a.stubs.empty() {
+			log.Warn("stub threshold is set, but there are no stubs")
 		}
 	}
 
 	return nil
 }
 
-// updateIndices updates shuffled indices.
+// searchPool returns rotation pool
+// of media having all given tags.
+func (a *AutoDJ) searchPool(ctx context.Context, name string, tags []string) (*category, error) {
+	const op = "AutoDJ.searchPool"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("category", name),
+	)
+
+	lib, err := a.media.SearchMedia(ctx, models.MediaFilter{Tags: tags})
+	if err != nil {
+		if errors.Is(err, service.ErrTimeout) {
+			log.Error("media.SearchMedia timeout exceeded")
+			return nil, service.ErrTimeout
+		}
+		log.Error("failed to update library", sl.Err(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	lib = slices.DeleteFunc(lib, func(m models.Media) bool { return !playable(m) })
+
+	if len(lib) == 0 {
+		log.Warn("category is empty")
+	}
+
+	return &category{
+		name:    name,
+		library: lib,
+	}, nil
+}
+
+// playable reports if media source
+// isn't marked broken by reconciliation.
+func playable(m models.Media) bool {
+	return m.Playable == nil || *m.Playable
+}
+
+// tagNames returns names of given tags.
+func tagNames(tags models.TagList) []string {
+	names := make([]string, 0, len(tags))
+	for _, t := range tags {
+		names = append(names, t.Name)
+	}
+	return names
+}
+
+// updateIndices updates shuffled indices
+// of all categories and resets format clock.
 func (a *AutoDJ) updateIndices() {
-	var lastId int64 = -1
-	if len(a.shuffledIds) > 0 {
-		lastId = a.shuffledIds[len(a.shuffledIds)-1]
+	for _, c := range a.categories {
+		c.shuffle()
 	}
+	for _, c := range []*category{a.jingles, a.sweepers} {
+		if c != nil && !c.empty() {
+			c.shuffle()
+		}
+	}
+	a.clockPos = 0
+	a.picked = make(map[int64]int)
+}
+
+// nextMedia returns next media
+// according to format clock.
+// Empty categories are skipped.
+func (a *AutoDJ) nextMedia() (models.Media, error) {
+	for range a.clock {
+		if a.clockPos >= len(a.clock) {
+			a.clockPos = 0
+		}
+		c := a.categories[a.clock[a.clockPos]]
+		a.clockPos++
 
-	sl := rand.Perm(len(a.library))
-	for sl[len(sl)-1] == int(lastId) {
-		sl = rand.Perm(len(a.library))
+		if c.empty() {
+			continue
+		}
+
+		media := c.next()
+		a.picked[*media.ID] = a.clockPos
+		return media, nil
 	}
 
-	a.shuffledIds = make([]int64, 0, len(sl))
+	return models.Media{}, service.ErrMediaNotFound
+}
 
-	for _, id := range sl {
-		a.shuffledIds = append(a.shuffledIds, int64(id))
+// rewind restores rotation state
+// right after given media was picked.
+func (a *AutoDJ) rewind(mediaId int64) bool {
+	pos, ok := a.picked[mediaId]
+	if !ok {
+		return false
 	}
 
-	a.currentId = 0
+	for _, c := range a.categories {
+		if c.rewind(mediaId) {
+			a.clockPos = pos
+			return true
+		}
+	}
+
+	return false
+}
+
+// librarySize returns total number
+// of media in all categories.
+func (a *AutoDJ) librarySize() int {
+	n := 0
+	for _, c := range a.categories {
+		n += len(c.library)
+	}
+	return n
 }
 
 // updateProtected updates schedule with protected segments.
@@ -645,17 +927,12 @@ func (a *AutoDJ) updateProtected(ctx context.Context) error {
 					return fmt.Errorf("%s: %w", op, err)
 				}
 
-				// Recover id of last segment before gap
-				// to continue playing the same segment.
-				lastId := slices.IndexFunc(a.library, func(m models.Media) bool {
-					return *m.ID == *s1.MediaID
-				})
-
-				if lastId == -1 {
-					log.Error("failed to recover last valid media used by dj, reset to 0")
+				// Recover rotation after last segment
+				// before gap to continue playing from it.
+				if !a.rewind(*s1.MediaID) {
+					log.Error("failed to recover last valid media used by dj, continue rotation")
 				}
 
-				a.currentId = int64(lastId) + 1
 				return nil
 			}
 		}
@@ -694,7 +971,7 @@ func (a *AutoDJ) nowPlaying(ctx context.Context) (models.Segment, error) {
 	}
 
 	if sch[0].Start.Before(now) {
-		if err := a.sch.ClearSchedule(ctx, sch[0].Start.Add(*sch[0].StopCut-*sch[0].BeginCut)); err != nil {
+		if err := a.sch.ClearSchedule(ctx, sch[0].End()); err != nil {
 			log.Error("failed to clear schedule", sl.Err(err))
 		}
 		return sch[0], nil
@@ -712,7 +989,7 @@ func (a *AutoDJ) nearestProtectedSegment() int {
 	)
 
 	for i, s := range a.protectedSegments {
-		stop := s.Start.Add(*s.StopCut - *s.BeginCut)
+		stop := s.End()
 		if stop.After(a.timeHorizon) {
 			log.Debug("time horizon intersects protected segment", slog.Int("id", i))
 			return i
@@ -737,68 +1014,11 @@ func (a *AutoDJ) redirectChannels(ctx context.Context) {
 				chans.Notify(a.mediaChanRedirect)
 			}
 		case <-ctx.Done():
+			return
 		}
 	}
 }
 
-// recoverConfig tries to read config file.
-func (a *AutoDJ) recoverConfig() {
-	const op = "AutoDj.recoverConfig"
-
-	log := a.log.With(
-		slog.String("op", op),
-		slog.String("file", a.cacheFile),
-	)
-
-	file, err := os.Open(a.cacheFile)
-	if err != nil {
-		log.Warn("failed to open file", sl.Err(err))
-		return
-	}
-	defer file.Close()
-
-	body, err := io.ReadAll(file)
-	if err != nil {
-		log.Error("failed to read file", sl.Err(err))
-		return
-	}
-
-	if err := json.Unmarshal(body, &a.conf); err != nil {
-		log.Error("failed to parse config", sl.Err(err))
-		a.conf = models.AutoDJConfig{}
-		return
-	}
-}
-
-// saveConfig saves config to a file.
-// does not use mutex, since called only
-// from SetConfing, which already mutex config.
-func (a *AutoDJ) saveConfig() {
-	const op = "AutoDJ.saveConfig"
-
-	log := a.log.With(
-		slog.String("op", op),
-		slog.String("file", a.cacheFile),
-	)
-
-	file, err := os.OpenFile(a.cacheFile, os.O_WRONLY|os.O_CREATE, 0644)
-	if err != nil {
-		log.Error("failed to open file", sl.Err(err))
-		return
-	}
-	defer file.Close()
-
-	body, err := json.Marshal(a.conf)
-	if err != nil {
-		log.Error("failed to marshal config", sl.Err(err))
-		return
-	}
-
-	if _, err := file.Write(body); err != nil {
-		log.Error("failed to write to file", sl.Err(err))
-	}
-}
-
 // IsPlaying returns autodj status.
 func (a *AutoDJ) IsPlaying() bool {
 	if a.runMutex.TryLock() {
@@ -808,6 +1028,11 @@ func (a *AutoDJ) IsPlaying() bool {
 	return true
 }
 
+// Heartbeat returns state of the main loop.
+func (a *AutoDJ) Heartbeat() heartbeat.Status {
+	return a.heartbeat.Status()
+}
+
 func (a *AutoDJ) Stop() {
 	if a.IsPlaying() {
 		chans.Notify(a.stopChan)
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"math/rand"
+	"time"
+
+	ptr "github.com/GintGld/fizteh-radio/internal/lib/utils/pointers"
+	"github.com/GintGld/fizteh-radio/internal/models"
+)
+
+// fillGap returns segments filling time
+// from the time horizon to the given stop with stubs.
+//
+// Stubs are chosen to fit the gap best,
+// the rest is covered by a cut stub
+// (it fades out at the end).
+// Returns nil if there are no stubs.
+func (a *AutoDJ) fillGap(stop time.Time) []models.Segment {
+	if a.stubs == nil || a.stubs.empty() {
+		return nil
+	}
+
+	segments := make([]models.Segment, 0)
+	start := a.timeHorizon
+
+	for stop.Sub(start) >= fitQuant {
+		gap := stop.Sub(start)
+
+		// Shuffle candidates to vary stubs.
+		candidates := make([]models.Media, 0, len(a.stubs.library))
+		for _, i := range rand.Perm(len(a.stubs.library)) {
+			candidates = append(candidates, a.stubs.library[i])
+		}
+		durations := make([]time.Duration, 0, len(candidates))
+		for _, m := range candidates {
+			durations = append(durations, *m.Duration)
+		}
+
+		chosen := fit(durations, gap)
+
+		// Every stub is longer than the gap,
+		// cut the shortest one.
+		if len(chosen) == 0 {
+			shortest := 0
+			for i, d := range durations {
+				if d < durations[shortest] {
+					shortest = i
+				}
+			}
+			stub := stubSegment(candidates[shortest], start, gap)
+			stub.FadeOut = true
+			segments = append(segments, stub)
+			break
+		}
+
+		for _, i := range chosen {
+			segments = append(segments, stubSegment(candidates[i], start, durations[i]))
+			start = start.Add(durations[i])
+		}
+	}
+
+	return segments
+}
+
+// stubSegment returns segment for stub
+// starting at given time and playing given duration.
+func stubSegment(stub models.Media, start time.Time, duration time.Duration) models.Segment {
+	return models.Segment{
+		MediaID:   ptr.Ptr(*stub.ID),
+		Start:     ptr.Ptr(start),
+		BeginCut:  ptr.Ptr[time.Duration](0),
+		StopCut:   ptr.Ptr(duration),
+		Protected: false,
+	}
+}
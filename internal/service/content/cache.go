@@ -0,0 +1,303 @@
+package service
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/GintGld/fizteh-radio/internal/lib/metrics"
+)
+
+var errChecksum = errors.New("checksum mismatch")
+
+// cache is a size-bounded LRU cache of source files.
+//
+// Files are named by source id, checksum is
+// computed on insertion and validated on every hit.
+// Checksum is also compared with the one stored in
+// library, if it's known, so stale or broken
+// downloads aren't served.
+// Pinned files are never evicted, so cache
+// may exceed its size if all files are pinned.
+type cache struct {
+	dir     string
+	maxSize int64
+
+	mutex   sync.Mutex
+	lru     *list.List // front is the most recently used
+	entries map[int64]*list.Element
+	size    int64
+	pinned  map[int64]struct{}
+	loading map[int64]*load
+}
+
+type cacheEntry struct {
+	id       int64
+	size     int64
+	checksum string
+}
+
+// load is a download in progress,
+// concurrent requests wait for it.
+type load struct {
+	done chan struct{}
+	err  error
+}
+
+// loadFunc downloads source to dst.
+type loadFunc func(ctx context.Context, dst string) error
+
+func newCache(dir string, maxSize int64) *cache {
+	return &cache{
+		dir:     dir,
+		maxSize: maxSize,
+		lru:     list.New(),
+		entries: make(map[int64]*list.Element),
+		pinned:  make(map[int64]struct{}),
+		loading: make(map[int64]*load),
+	}
+}
+
+// init indexes files left from previous runs,
+// the oldest modified are evicted first.
+func (c *cache) init() error {
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return err
+	}
+
+	type file struct {
+		id   int64
+		path string
+		time int64
+	}
+	files := make([]file, 0, len(entries))
+	for _, e := range entries {
+		path := filepath.Join(c.dir, e.Name())
+		id, err := strconv.ParseInt(e.Name(), 10, 64)
+		if err != nil || e.IsDir() {
+			// Unfinished downloads and
+			// files of older versions.
+			os.RemoveAll(path)
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			return err
+		}
+		files = append(files, file{id, path, info.ModTime().UnixNano()})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].time < files[j].time })
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for _, f := range files {
+		if _, ok := c.entries[f.id]; ok {
+			continue
+		}
+		size, sum, err := checksum(f.path)
+		if err != nil {
+			return err
+		}
+		c.entries[f.id] = c.lru.PushFront(&cacheEntry{f.id, size, sum})
+		c.size += size
+	}
+	c.evict(0)
+
+	return nil
+}
+
+// get returns path to cached source,
+// source is loaded if it's missing or corrupted.
+// Empty sum disables comparison with library checksum.
+//
+// Concurrent requests wait for the single download,
+// if it was cancelled by its caller, they retry.
+func (c *cache) get(ctx context.Context, id int64, sum string, fn loadFunc) (string, error) {
+	for {
+		c.mutex.Lock()
+
+		if l, ok := c.loading[id]; ok {
+			c.mutex.Unlock()
+			select {
+			case <-l.done:
+			case <-ctx.Done():
+				return "", ctx.Err()
+			}
+			if l.err != nil {
+				if errors.Is(l.err, context.Canceled) || errors.Is(l.err, context.DeadlineExceeded) {
+					continue
+				}
+				return "", l.err
+			}
+			continue
+		}
+
+		if elem, ok := c.entries[id]; ok {
+			c.lru.MoveToFront(elem)
+			e := elem.Value.(*cacheEntry)
+			c.mutex.Unlock()
+
+			if err := c.validate(e, sum); err == nil {
+				metrics.SourceCacheHits.Inc()
+				return c.path(id), nil
+			}
+
+			// Corrupted or deleted file is loaded again.
+			c.mutex.Lock()
+			if c.entries[id] == elem {
+				c.remove(elem)
+			}
+			c.mutex.Unlock()
+			continue
+		}
+
+		l := &load{done: make(chan struct{})}
+		c.loading[id] = l
+		c.mutex.Unlock()
+
+		metrics.SourceCacheMisses.Inc()
+
+		l.err = c.load(ctx, id, sum, fn)
+
+		c.mutex.Lock()
+		delete(c.loading, id)
+		c.mutex.Unlock()
+		close(l.done)
+
+		if l.err != nil {
+			return "", l.err
+		}
+		return c.path(id), nil
+	}
+}
+
+// load downloads source to temporary file
+// and moves it to cache when it's complete
+// and matches library checksum.
+func (c *cache) load(ctx context.Context, id int64, want string, fn loadFunc) error {
+	tmp, err := os.CreateTemp(c.dir, ".load-*")
+	if err != nil {
+		return err
+	}
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+
+	if err := fn(ctx, tmp.Name()); err != nil {
+		return err
+	}
+
+	size, sum, err := checksum(tmp.Name())
+	if err != nil {
+		return err
+	}
+	if want != "" && sum != want {
+		return fmt.Errorf("source %d: %w", id, errChecksum)
+	}
+	if err := os.Rename(tmp.Name(), c.path(id)); err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// Room is made before insertion, so
+	// loaded file isn't evicted before use.
+	c.evict(size)
+	c.entries[id] = c.lru.PushFront(&cacheEntry{id, size, sum})
+	c.size += size
+
+	return nil
+}
+
+// contains reports if source is cached or being loaded.
+func (c *cache) contains(id int64) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	_, cached := c.entries[id]
+	_, loading := c.loading[id]
+	return cached || loading
+}
+
+// pin replaces set of pinned sources.
+func (c *cache) pin(ids []int64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.pinned = make(map[int64]struct{}, len(ids))
+	for _, id := range ids {
+		c.pinned[id] = struct{}{}
+	}
+	c.evict(0)
+}
+
+// evict removes the least recently used unpinned
+// files until cache has room for extra bytes.
+// Must be called with mutex locked.
+func (c *cache) evict(extra int64) {
+	for elem := c.lru.Back(); elem != nil && c.size+extra > c.maxSize; {
+		prev := elem.Prev()
+		if _, ok := c.pinned[elem.Value.(*cacheEntry).id]; !ok {
+			c.remove(elem)
+		}
+		elem = prev
+	}
+}
+
+// remove deletes file from cache.
+// Must be called with mutex locked.
+func (c *cache) remove(elem *list.Element) {
+	e := elem.Value.(*cacheEntry)
+	c.lru.Remove(elem)
+	delete(c.entries, e.id)
+	c.size -= e.size
+	os.Remove(c.path(e.id))
+}
+
+// validate checks that file wasn't changed since
+// insertion and matches library checksum.
+func (c *cache) validate(e *cacheEntry, want string) error {
+	if want != "" && e.checksum != want {
+		return errChecksum
+	}
+	size, sum, err := checksum(c.path(e.id))
+	if err != nil {
+		return err
+	}
+	if size != e.size || sum != e.checksum {
+		return errChecksum
+	}
+	return nil
+}
+
+func (c *cache) path(id int64) string {
+	return filepath.Join(c.dir, strconv.FormatInt(id, 10))
+}
+
+// checksum returns size and sha256 of file.
+func checksum(path string) (int64, string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return 0, "", fmt.Errorf("checksum: %w", err)
+	}
+
+	return size, hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,165 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// write returns loader writing given data.
+func write(data string, calls *atomic.Int32) loadFunc {
+	return func(_ context.Context, dst string) error {
+		calls.Add(1)
+		return os.WriteFile(dst, []byte(data), 0644)
+	}
+}
+
+func TestCacheEviction(t *testing.T) {
+	c := newCache(t.TempDir(), 10)
+	ctx := context.Background()
+	var calls atomic.Int32
+
+	for _, id := range []int64{1, 2} {
+		_, err := c.get(ctx, id, "", write("12345", &calls))
+		require.NoError(t, err)
+	}
+
+	// 1 is used recently, 2 is pinned.
+	_, err := c.get(ctx, 1, "", write("12345", &calls))
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), calls.Load())
+	c.pin([]int64{2})
+
+	_, err = c.get(ctx, 3, "", write("12345", &calls))
+	require.NoError(t, err)
+	assert.False(t, c.contains(1))
+	assert.True(t, c.contains(2))
+	assert.True(t, c.contains(3))
+	assert.NoFileExists(t, c.path(1))
+
+	// Pinned files may exceed size,
+	// loaded file is kept until next eviction.
+	c.pin([]int64{2, 3})
+	path, err := c.get(ctx, 4, "", write("12345", &calls))
+	require.NoError(t, err)
+	assert.FileExists(t, path)
+	assert.Equal(t, int64(15), c.size)
+
+	c.pin([]int64{3})
+	assert.False(t, c.contains(2))
+	assert.True(t, c.contains(4))
+	assert.Equal(t, int64(10), c.size)
+}
+
+func TestCacheChecksum(t *testing.T) {
+	dir := t.TempDir()
+	c := newCache(dir, 100)
+	ctx := context.Background()
+	var calls atomic.Int32
+
+	path, err := c.get(ctx, 1, "", write("sound", &calls))
+	require.NoError(t, err)
+
+	// Corrupted file is loaded again.
+	require.NoError(t, os.WriteFile(path, []byte("noise"), 0644))
+	path, err = c.get(ctx, 1, "", write("sound", &calls))
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), calls.Load())
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "sound", string(data))
+
+	// Files are indexed after restart.
+	c = newCache(dir, 100)
+	require.NoError(t, c.init())
+	_, err = c.get(ctx, 1, "", write("sound", &calls))
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestCacheConcurrentLoad(t *testing.T) {
+	c := newCache(t.TempDir(), 100)
+	var calls atomic.Int32
+
+	release := make(chan struct{})
+	slow := func(ctx context.Context, dst string) error {
+		<-release
+		return write("sound", &calls)(ctx, dst)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := c.get(context.Background(), 1, "", slow)
+			assert.NoError(t, err)
+		}()
+	}
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestCacheLibraryChecksum(t *testing.T) {
+	c := newCache(t.TempDir(), 100)
+	ctx := context.Background()
+	var calls atomic.Int32
+
+	h := sha256.Sum256([]byte("sound"))
+	sum := hex.EncodeToString(h[:])
+
+	// Download not matching library isn't cached.
+	_, err := c.get(ctx, 1, sum, write("noise", &calls))
+	assert.True(t, errors.Is(err, errChecksum))
+	assert.False(t, c.contains(1))
+
+	_, err = c.get(ctx, 1, sum, write("sound", &calls))
+	require.NoError(t, err)
+
+	// Cached file is loaded again if library checksum changed.
+	_, err = c.get(ctx, 1, "changed", write("sound", &calls))
+	assert.True(t, errors.Is(err, errChecksum))
+	assert.Equal(t, int32(3), calls.Load())
+}
+
+func TestCacheCancelledLoad(t *testing.T) {
+	c := newCache(t.TempDir(), 100)
+	var calls atomic.Int32
+
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan struct{})
+	cancelled := func(ctx context.Context, _ string) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	done := make(chan error)
+	go func() {
+		_, err := c.get(ctx, 1, "", cancelled)
+		done <- err
+	}()
+	<-started
+
+	// Waiter doesn't inherit error of the cancelled download.
+	waiter := make(chan error)
+	go func() {
+		_, err := c.get(context.Background(), 1, "", write("sound", &calls))
+		waiter <- err
+	}()
+
+	cancel()
+	assert.True(t, errors.Is(<-done, context.Canceled))
+	require.NoError(t, <-waiter)
+	assert.Equal(t, int32(1), calls.Load())
+}
@@ -8,14 +8,18 @@ import (
 	"os"
 	"os/exec"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/GintGld/fizteh-radio/internal/lib/ffmpeg"
 	"github.com/GintGld/fizteh-radio/internal/lib/logger/sl"
+	"github.com/GintGld/fizteh-radio/internal/lib/metrics"
+	"github.com/GintGld/fizteh-radio/internal/lib/tracing"
 	"github.com/GintGld/fizteh-radio/internal/lib/utils/writer"
 	"github.com/GintGld/fizteh-radio/internal/models"
 	"github.com/GintGld/fizteh-radio/internal/service"
 	"github.com/GintGld/fizteh-radio/internal/storage"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // TODO: move waitBeforeDelete to config
@@ -25,12 +29,19 @@ const (
 	maxSamplingRate  = 44100
 	mpdFile          = "tmp.mpd" // ffmpeg needs to have file to dump manifest for splitted composition
 	waitBeforeDelete = 15 * time.Second
+	fadeOutDuration  = 3 * time.Second
 )
 
 // Generate segments
-func (c *Content) generateDASHFiles(ctx context.Context, s models.Segment) error {
+func (c *Content) generateDASHFiles(ctx context.Context, s models.Segment) (err error) {
 	const op = "Content.generateDASHFiles"
 
+	ctx, span := tracing.Start(ctx, op,
+		attribute.Int64("segment.id", *s.ID),
+		attribute.Int64("media.id", *s.MediaID),
+	)
+	defer func() { tracing.End(span, err) }()
+
 	log := c.log.With(
 		slog.String("op", op),
 	)
@@ -80,7 +91,7 @@ func (c *Content) generateDASHFiles(ctx context.Context, s models.Segment) error
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
-	filePath, err := c.source.LoadSource(ctx, c.path+"/.cache", media)
+	filePath, err := c.loadSource(ctx, media)
 	if err != nil {
 		if errors.Is(err, service.ErrTimeout) {
 			log.Error("LoadSource timeout exceeded")
@@ -112,13 +123,29 @@ func (c *Content) generateDASHFiles(ctx context.Context, s models.Segment) error
 	stopString := strconv.FormatFloat(s.StopCut.Seconds(), 'g', -1, 64)
 	durationString := strconv.FormatFloat(c.chunkLength.Seconds(), 'g', -1, 64)
 
-	cmd := exec.Command(
-		"ffmpeg",           //							call converter
+	args := []string{
 		"-hide_banner",     //							hide banner
 		"-y",               //							force rewriting file
 		"-ss", startString, //							start cut
 		"-to", stopString, //							stop cut
 		"-i", filePath, //								input file
+	}
+
+	filters := make([]string, 0)
+	// Time-stretched segment.
+	if s.Tempo != 0 {
+		filters = append(filters, "atempo="+strconv.FormatFloat(s.Tempo, 'g', -1, 64))
+	}
+	// Segment is truncated by scheduler,
+	// fade it out instead of abrupt stop.
+	if s.FadeOut {
+		filters = append(filters, fadeOut(s.Duration()))
+	}
+	if len(filters) > 0 {
+		args = append(args, "-af", strings.Join(filters, ","))
+	}
+
+	args = append(args,
 		"-c:a", "aac", //								choose codec
 		"-b:a", strconv.Itoa(bitrate), //				choose bitrate (TODO: make different bitrate to enable bitrateSwitching)
 		"-ac", strconv.Itoa(2), //						number of channels (1 - mono, 2 - stereo)
@@ -133,10 +160,16 @@ func (c *Content) generateDASHFiles(ctx context.Context, s models.Segment) error
 		c.path+"/"+mpdFile, //							output file
 	)
 
+	cmd := exec.Command("ffmpeg", args...)
+
 	errorWriter := writer.New()
 	cmd.Stderr = errorWriter
 
-	if err := cmd.Run(); err != nil {
+	start := time.Now()
+	_, run := tracing.Start(ctx, "ffmpeg")
+	err = cmd.Run()
+	tracing.End(run, err)
+	if err != nil {
 		log.Error(
 			"failed to run command",
 			slog.String("cmd", cmd.String()),
@@ -145,15 +178,32 @@ func (c *Content) generateDASHFiles(ctx context.Context, s models.Segment) error
 		)
 		return fmt.Errorf("%s: %w", op, err)
 	}
+	metrics.FFmpegDuration.Observe(time.Since(start).Seconds())
+
+	// Directory contains init file and chunks.
+	if entries, err := os.ReadDir(path); err == nil && len(entries) > 0 {
+		metrics.ChunksGenerated.Add(float64(len(entries) - 1))
+	}
 
 	time.AfterFunc(
-		time.Until(*s.Start)+*s.StopCut-*s.BeginCut+waitBeforeDelete,
+		time.Until(s.End())+waitBeforeDelete,
 		func() { c.deleteDASHFiles(s) },
 	)
 
 	return nil
 }
 
+// fadeOut returns audio filter fading out
+// the last seconds of segment with given length.
+func fadeOut(length time.Duration) string {
+	d := min(fadeOutDuration, length)
+	return fmt.Sprintf(
+		"afade=t=out:st=%s:d=%s",
+		strconv.FormatFloat((length-d).Seconds(), 'g', -1, 64),
+		strconv.FormatFloat(d.Seconds(), 'g', -1, 64),
+	)
+}
+
 // FIXME close timeout after remove segment
 // from schedule.
 
@@ -175,47 +225,8 @@ func (c *Content) deleteDASHFiles(s models.Segment) error {
 	return nil
 }
 
-// deleteCache deletes all files in .cache
-func (c *Content) deleteCache() error {
-	const op = "Content.deleteCache"
-
-	log := c.log.With(
-		slog.String("op", op),
-	)
-
-	cacheDir := c.path + "/.cache"
-
-	files, err := os.ReadDir(cacheDir)
-	if err != nil {
-		log.Error(
-			"failed to get list of content dir",
-			slog.String("dir", c.path),
-			sl.Err(err),
-		)
-	}
-
-	errAll := make([]error, 0)
-	for _, file := range files {
-		err = os.Remove(cacheDir + "/" + file.Name())
-		log.Debug("delete", slog.String("file", cacheDir+"/"+file.Name()))
-		if err != nil {
-			log.Error(
-				"failed to delete",
-				slog.String("item", file.Name()),
-				slog.String("cache dir", cacheDir),
-				sl.Err(err),
-			)
-			errAll = append(errAll, err)
-		}
-	}
-	if len(errAll) > 0 {
-		return errors.Join(errAll...)
-	}
-
-	return nil
-}
-
-// deleteAll deletes all files from working directory
+// deleteAll deletes all files from working
+// directory, source cache is kept.
 func (c *Content) deleteAll() error {
 	const op = "Content.deleteAll"
 
@@ -251,12 +262,5 @@ func (c *Content) deleteAll() error {
 		return errors.Join(errAll...)
 	}
 
-	if err := c.deleteCache(); err != nil {
-		log.Error(
-			"failed to delete cache",
-			sl.Err(err),
-		)
-	}
-
 	return nil
 }
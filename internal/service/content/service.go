@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/GintGld/fizteh-radio/internal/lib/logger/sl"
@@ -19,12 +20,20 @@ type Content struct {
 	chunkLength time.Duration
 	media       Media
 	source      Source
+
+	cache         *cache
+	cacheOnce     sync.Once
+	prefetchMutex sync.Mutex
 }
 
+// New returns content maker.
+//
+// Sources are kept in cache of cacheSize bytes.
 func New(
 	log *slog.Logger,
 	path string,
 	chunkLength time.Duration,
+	cacheSize int64,
 	media Media,
 	source Source,
 ) *Content {
@@ -43,6 +52,7 @@ func New(
 		chunkLength: chunkLength,
 		media:       media,
 		source:      source,
+		cache:       newCache(path+"/.cache", cacheSize),
 	}
 }
 
@@ -51,7 +61,7 @@ type Media interface {
 }
 
 type Source interface {
-	LoadSource(ctx context.Context, destDir string, media models.Media) (string, error)
+	LoadSource(ctx context.Context, dst string, media models.Media) error
 }
 
 func (c *Content) Init() error {
@@ -69,6 +79,18 @@ func (c *Content) Init() error {
 		)
 		return fmt.Errorf("%s: %w", op, err)
 	}
+
+	// Sources left from previous run
+	// are indexed once.
+	var err error
+	c.cacheOnce.Do(func() {
+		err = c.cache.init()
+	})
+	if err != nil {
+		log.Error("failed to index cache", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
 	return nil
 }
 
@@ -92,25 +114,108 @@ func (c *Content) Generate(ctx context.Context, s models.Segment) error {
 	return nil
 }
 
-// ClearCache clears cache. Must be called
-// regularly if dash works not in cyclic regime
-func (c *Content) ClearCache() error {
-	const op = "Content.ClearCache"
+// Pin keeps sources of given segments in cache,
+// sources pinned by previous call are released.
+func (c *Content) Pin(ctx context.Context, segments []models.Segment) error {
+	const op = "Content.Pin"
 
 	log := c.log.With(
 		slog.String("op", op),
 	)
 
-	if err := c.deleteCache(); err != nil {
-		log.Error("failed to clear cache", sl.Err(err))
+	media, err := c.segmentsMedia(ctx, segments)
+	if err != nil {
+		if errors.Is(err, service.ErrTimeout) {
+			log.Error("get media timeout exceeded")
+			return service.ErrTimeout
+		}
+		log.Error("failed to get media", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
 	}
 
+	ids := make([]int64, 0, len(media))
+	for _, m := range media {
+		ids = append(ids, *m.SourceID)
+	}
+	c.cache.pin(ids)
+
 	return nil
 }
 
+// Prefetch loads sources of given segments to cache.
+// Call is skipped if previous one isn't finished.
+func (c *Content) Prefetch(ctx context.Context, segments []models.Segment) {
+	const op = "Content.Prefetch"
+
+	log := c.log.With(
+		slog.String("op", op),
+	)
+
+	if !c.prefetchMutex.TryLock() {
+		return
+	}
+	defer c.prefetchMutex.Unlock()
+
+	media, err := c.segmentsMedia(ctx, segments)
+	if err != nil {
+		log.Error("failed to get media", sl.Err(err))
+		return
+	}
+
+	for _, m := range media {
+		if c.cache.contains(*m.SourceID) {
+			continue
+		}
+		if _, err := c.loadSource(ctx, m); err != nil {
+			log.Error("failed to prefetch source", slog.Int64("sourceID", *m.SourceID), sl.Err(err))
+			continue
+		}
+		log.Debug("prefetched source", slog.Int64("sourceID", *m.SourceID))
+	}
+}
+
+// loadSource returns path to cached source of media.
+func (c *Content) loadSource(ctx context.Context, media models.Media) (string, error) {
+	var sum string
+	if media.Checksum != nil {
+		sum = *media.Checksum
+	}
+
+	return c.cache.get(ctx, *media.SourceID, sum, func(ctx context.Context, dst string) error {
+		return c.source.LoadSource(ctx, dst, media)
+	})
+}
+
+// segmentsMedia returns distinct media of
+// given segments, live segments are skipped.
+func (c *Content) segmentsMedia(ctx context.Context, segments []models.Segment) ([]models.Media, error) {
+	res := make([]models.Media, 0, len(segments))
+	seen := make(map[int64]struct{}, len(segments))
+	for _, s := range segments {
+		if s.LiveId != 0 || s.MediaID == nil {
+			continue
+		}
+		if _, ok := seen[*s.MediaID]; ok {
+			continue
+		}
+		seen[*s.MediaID] = struct{}{}
+
+		media, err := c.media.Media(ctx, *s.MediaID)
+		if err != nil {
+			return nil, err
+		}
+		if media.SourceID == nil {
+			continue
+		}
+		res = append(res, media)
+	}
+
+	return res, nil
+}
+
 // CleanUp deletes all files create by
-// Content struct. Must be called after
-// stopping dash
+// Content struct except source cache.
+// Must be called after stopping dash
 func (c *Content) CleanUp() {
 	const op = "Content.CleanUp"
 
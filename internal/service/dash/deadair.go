@@ -0,0 +1,223 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/GintGld/fizteh-radio/internal/lib/logger/sl"
+	ptr "github.com/GintGld/fizteh-radio/internal/lib/utils/pointers"
+	"github.com/GintGld/fizteh-radio/internal/models"
+	"github.com/GintGld/fizteh-radio/internal/service"
+	"github.com/GintGld/fizteh-radio/internal/storage"
+)
+
+const (
+	// Shortest uncovered window
+	// considered to be dead air.
+	minGap = time.Second
+	// Limit of emergency segments
+	// added at once (e.g. if all
+	// emergency media are very short).
+	maxEmergencySegments = 100
+)
+
+// watchDeadAir detects uncovered time within
+// dead air window and fills it with emergency playlist
+// (media with emergency tag). Incident is recorded
+// and event is published.
+//
+// Returns schedule with added segments.
+func (d *Dash) watchDeadAir(ctx context.Context, schedule []models.Segment, now time.Time) []models.Segment {
+	const op = "Dash.watchDeadAir"
+
+	if d.deadAirWindow == 0 {
+		return schedule
+	}
+
+	log := d.log.With(
+		slog.String("op", op),
+	)
+
+	start, stop, bounded, ok := findGap(schedule, now, now.Add(d.deadAirWindow), now.Add(d.horizon))
+	if !ok {
+		return schedule
+	}
+
+	log.Warn("dead air detected", slog.Time("start", start), slog.Time("stop", stop))
+
+	added := d.fillGap(ctx, log, start, stop, bounded)
+
+	end := stop
+	if len(added) > 0 {
+		end = added[len(added)-1].End()
+	}
+	d.recordIncident(ctx, log, now, start, end, len(added))
+
+	if len(added) == 0 {
+		return schedule
+	}
+
+	res := append(slices.Clone(schedule), added...)
+	slices.SortFunc(res, func(a, b models.Segment) int {
+		return a.Start.Compare(*b.Start)
+	})
+
+	return res
+}
+
+// findGap returns first window not covered by segments
+// which starts in [from, to). Gap lasts until next segment
+// start (bounded) or until horizon if there's no such segment.
+func findGap(schedule []models.Segment, from, to, horizon time.Time) (start, stop time.Time, bounded, ok bool) {
+	sorted := slices.Clone(schedule)
+	slices.SortFunc(sorted, func(a, b models.Segment) int {
+		return a.Start.Compare(*b.Start)
+	})
+
+	cur := from
+	for _, s := range sorted {
+		if !cur.Before(to) {
+			return time.Time{}, time.Time{}, false, false
+		}
+		if s.Start.Sub(cur) >= minGap {
+			return cur, *s.Start, true, true
+		}
+		if end := s.End(); end.After(cur) {
+			cur = end
+		}
+	}
+
+	if !cur.Before(to) || horizon.Sub(cur) < minGap {
+		return time.Time{}, time.Time{}, false, false
+	}
+
+	return cur, horizon, false, true
+}
+
+// fillGap adds emergency segments to the schedule.
+// Last segment is cut to fit bounded gap,
+// unbounded gap is filled with whole tracks.
+func (d *Dash) fillGap(ctx context.Context, log *slog.Logger, start, stop time.Time, bounded bool) []models.Segment {
+	ctxSearch, cancelSearch := context.WithTimeout(ctx, d.ctxTimeout)
+	defer cancelSearch()
+	media, err := d.library.SearchMedia(ctxSearch, models.MediaFilter{Tags: []string{d.emergencyTag}})
+	if err != nil {
+		if errors.Is(err, service.ErrTimeout) {
+			log.Error("library.SearchMedia timeout exceeded")
+		} else {
+			log.Error("failed to get emergency media", sl.Err(err))
+		}
+		return nil
+	}
+	// Broken sources would leave gap again.
+	media = slices.DeleteFunc(media, func(m models.Media) bool {
+		return m.Duration == nil || *m.Duration < minGap ||
+			m.Playable != nil && !*m.Playable
+	})
+	if len(media) == 0 {
+		log.Error("no emergency media", slog.String("tag", d.emergencyTag))
+		return nil
+	}
+
+	added := make([]models.Segment, 0)
+	t := start
+	for i := 0; stop.Sub(t) >= minGap && i < maxEmergencySegments; i++ {
+		m := media[d.emergencyPos%len(media)]
+		d.emergencyPos++
+
+		length := *m.Duration
+		if rest := stop.Sub(t); bounded && length > rest {
+			length = rest
+		}
+
+		segment := models.Segment{
+			MediaID:  m.ID,
+			Start:    ptr.Ptr(t),
+			BeginCut: ptr.Ptr[time.Duration](0),
+			StopCut:  ptr.Ptr(length),
+			FadeOut:  length < *m.Duration,
+		}
+
+		ctxNewSeg, cancelNewSeg := context.WithTimeout(ctx, d.ctxTimeout)
+		id, err := d.schedule.NewSegment(ctxNewSeg, segment)
+		cancelNewSeg()
+		if err != nil {
+			if errors.Is(err, service.ErrTimeout) {
+				log.Error("schedule.NewSegment timeout exceeded")
+			} else {
+				log.Error("failed to add emergency segment", slog.Int64("media", *m.ID), sl.Err(err))
+			}
+			break
+		}
+		segment.ID = ptr.Ptr(id)
+
+		added = append(added, segment)
+		t = segment.End()
+	}
+
+	log.Info("added emergency segments", slog.Int("count", len(added)))
+
+	return added
+}
+
+// recordIncident saves dead air incident and publishes event.
+// Gap adjacent to the previous one continues its incident.
+func (d *Dash) recordIncident(ctx context.Context, log *slog.Logger, now, start, stop time.Time, segments int) {
+	ctxIncident, cancelIncident := context.WithTimeout(ctx, d.ctxTimeout)
+	defer cancelIncident()
+
+	if d.incident.ID != 0 && start.Sub(d.incident.Stop) < minGap {
+		d.incidentSegments += segments
+		d.incident.Stop = stop
+		d.incident.Resolution = resolution(d.incidentSegments)
+
+		if err := d.incidents.UpdateIncident(ctxIncident, d.incident); err != nil {
+			if errors.Is(err, storage.ErrContextCancelled) {
+				log.Error("incidents.UpdateIncident timeout exceeded")
+			} else {
+				log.Error("failed to update incident", slog.Int64("id", d.incident.ID), sl.Err(err))
+			}
+		}
+		return
+	}
+
+	d.incidentSegments = segments
+	d.incident = models.Incident{
+		Type:       models.IncidentDeadAir,
+		Detected:   now,
+		Start:      start,
+		Stop:       stop,
+		Resolution: resolution(segments),
+	}
+
+	id, err := d.incidents.SaveIncident(ctxIncident, d.incident)
+	if err != nil {
+		if errors.Is(err, storage.ErrContextCancelled) {
+			log.Error("incidents.SaveIncident timeout exceeded")
+		} else {
+			log.Error("failed to save incident", sl.Err(err))
+		}
+	}
+	d.incident.ID = id
+
+	d.events.Publish(models.Event{
+		Type:    models.EventDeadAir,
+		Time:    now,
+		Message: fmt.Sprintf("Dead air from %s, %s", start.Format(time.TimeOnly), d.incident.Resolution),
+		Details: map[string]string{
+			"start": start.Format(time.RFC3339),
+			"stop":  stop.Format(time.RFC3339),
+		},
+	})
+}
+
+func resolution(segments int) string {
+	if segments == 0 {
+		return "no emergency media"
+	}
+	return fmt.Sprintf("emergency playlist, %d segments", segments)
+}
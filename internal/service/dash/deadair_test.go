@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ptr "github.com/GintGld/fizteh-radio/internal/lib/utils/pointers"
+	"github.com/GintGld/fizteh-radio/internal/models"
+)
+
+type scheduleMock struct {
+	added []models.Segment
+}
+
+func (s *scheduleMock) ScheduleCut(context.Context, time.Time, time.Time) ([]models.Segment, error) {
+	return nil, nil
+}
+
+func (s *scheduleMock) Segment(context.Context, int64) (models.Segment, error) {
+	return models.Segment{}, nil
+}
+
+func (s *scheduleMock) NewSegment(_ context.Context, segment models.Segment) (int64, error) {
+	s.added = append(s.added, segment)
+	return int64(len(s.added)), nil
+}
+
+type libraryMock struct {
+	media []models.Media
+}
+
+func (l libraryMock) SearchMedia(context.Context, models.MediaFilter) ([]models.Media, error) {
+	return l.media, nil
+}
+
+type incidentsMock struct {
+	saved   []models.Incident
+	updated []models.Incident
+}
+
+func (i *incidentsMock) SaveIncident(_ context.Context, incident models.Incident) (int64, error) {
+	i.saved = append(i.saved, incident)
+	return int64(len(i.saved)), nil
+}
+
+func (i *incidentsMock) UpdateIncident(_ context.Context, incident models.Incident) error {
+	i.updated = append(i.updated, incident)
+	return nil
+}
+
+type eventsMock struct {
+	events []models.Event
+}
+
+func (e *eventsMock) Publish(event models.Event) {
+	e.events = append(e.events, event)
+}
+
+func segment(start time.Time, length time.Duration) models.Segment {
+	return models.Segment{
+		ID:       ptr.Ptr[int64](100),
+		MediaID:  ptr.Ptr[int64](1),
+		Start:    ptr.Ptr(start),
+		BeginCut: ptr.Ptr[time.Duration](0),
+		StopCut:  ptr.Ptr(length),
+	}
+}
+
+func TestFindGap(t *testing.T) {
+	now := time.Now()
+	window, horizon := now.Add(30*time.Second), now.Add(time.Minute)
+
+	// Covered
+	_, _, _, ok := findGap([]models.Segment{
+		segment(now.Add(-time.Minute), 80*time.Second),
+		segment(now.Add(20*time.Second), time.Minute),
+	}, now, window, horizon)
+	assert.False(t, ok)
+
+	// Gap between segments
+	start, stop, bounded, ok := findGap([]models.Segment{
+		segment(now.Add(20*time.Second), time.Minute),
+		segment(now.Add(-time.Minute), 70*time.Second),
+	}, now, window, horizon)
+	require.True(t, ok)
+	assert.True(t, bounded)
+	assert.Equal(t, now.Add(10*time.Second), start)
+	assert.Equal(t, now.Add(20*time.Second), stop)
+
+	// Gap after the last segment
+	start, stop, bounded, ok = findGap([]models.Segment{
+		segment(now, 10*time.Second),
+	}, now, window, horizon)
+	require.True(t, ok)
+	assert.False(t, bounded)
+	assert.Equal(t, now.Add(10*time.Second), start)
+	assert.Equal(t, horizon, stop)
+
+	// Gap after window
+	_, _, _, ok = findGap([]models.Segment{
+		segment(now, 40*time.Second),
+	}, now, window, horizon)
+	assert.False(t, ok)
+}
+
+func TestWatchDeadAir(t *testing.T) {
+	sch := &scheduleMock{}
+	incidents := &incidentsMock{}
+	events := &eventsMock{}
+
+	d := New(
+		slog.New(slog.NewTextHandler(io.Discard, nil)),
+		time.Second, time.Second, time.Minute, 0,
+		30*time.Second, "emergency",
+		nil, nil, sch, nil,
+		libraryMock{media: []models.Media{
+			{ID: ptr.Ptr[int64](7), Duration: ptr.Ptr(25 * time.Second)},
+		}},
+		incidents, events, nil,
+	)
+
+	now := time.Now()
+	next := segment(now.Add(40*time.Second), time.Minute)
+
+	schedule := d.watchDeadAir(context.Background(), []models.Segment{next}, now)
+
+	// Gap is filled up to the next segment,
+	// the last emergency segment is cut.
+	require.Len(t, sch.added, 2)
+	assert.Equal(t, now, *sch.added[0].Start)
+	assert.Equal(t, 25*time.Second, *sch.added[0].StopCut)
+	assert.Equal(t, 15*time.Second, *sch.added[1].StopCut)
+	assert.False(t, sch.added[0].FadeOut)
+	assert.True(t, sch.added[1].FadeOut)
+	assert.Equal(t, int64(7), *sch.added[1].MediaID)
+	require.Len(t, schedule, 3)
+	assert.Equal(t, next.Start, schedule[2].Start)
+
+	require.Len(t, incidents.saved, 1)
+	assert.Equal(t, models.IncidentDeadAir, incidents.saved[0].Type)
+	assert.Equal(t, now.Add(40*time.Second), incidents.saved[0].Stop)
+	require.Len(t, events.events, 1)
+	assert.Equal(t, models.EventDeadAir, events.events[0].Type)
+
+	// Adjacent gap continues incident.
+	later := now.Add(100 * time.Second)
+	d.watchDeadAir(context.Background(), []models.Segment{segment(later, 0)}, now.Add(40*time.Second))
+
+	assert.Len(t, incidents.saved, 1)
+	require.Len(t, incidents.updated, 1)
+	assert.Equal(t, int64(1), incidents.updated[0].ID)
+	assert.Len(t, events.events, 1)
+}
@@ -8,6 +8,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/GintGld/fizteh-radio/internal/lib/heartbeat"
 	"github.com/GintGld/fizteh-radio/internal/lib/logger/sl"
 	"github.com/GintGld/fizteh-radio/internal/models"
 	"github.com/GintGld/fizteh-radio/internal/service"
@@ -20,39 +21,78 @@ type Dash struct {
 	ctxTimeout time.Duration
 	updateFreq time.Duration
 	horizon    time.Duration
+	prefetch   time.Duration
 	manifest   Manifest
 	content    Content
 	schedule   Schedule
+	asRun      AsRun
+	library    Library
+	incidents  IncidentStorage
+	events     Events
+
+	// dead air watchdog
+	deadAirWindow    time.Duration
+	emergencyTag     string
+	emergencyPos     int
+	incident         models.Incident
+	incidentSegments int
+
+	// segments put in manifest
+	// and not recorded to as-run log yet
+	published map[int64]airing
 
 	// notify to update
 	notifyChan <-chan models.Segment
 	// stop
 	stopChan chan struct{}
 
-	runMutex sync.Mutex
+	runMutex  sync.Mutex
+	heartbeat heartbeat.Heartbeat
 }
 
-// New returns new dash manager
+// New returns new dash manager.
+//
+// Sources of segments within prefetch
+// after horizon are loaded in advance.
+//
+// Time within deadAirWindow not covered by schedule
+// is filled with media tagged with emergencyTag,
+// zero window disables dead air watchdog.
 func New(
 	log *slog.Logger,
 	ctxTimeout time.Duration,
 	updateFreq time.Duration,
 	horizon time.Duration,
+	prefetch time.Duration,
+	deadAirWindow time.Duration,
+	emergencyTag string,
 	manifest Manifest,
 	content Content,
 	schedule Schedule,
+	asRun AsRun,
+	library Library,
+	incidents IncidentStorage,
+	events Events,
 	notifyChan <-chan models.Segment,
 ) *Dash {
 	return &Dash{
-		log:        log,
-		ctxTimeout: ctxTimeout,
-		updateFreq: updateFreq,
-		horizon:    horizon,
-		manifest:   manifest,
-		content:    content,
-		schedule:   schedule,
-		notifyChan: notifyChan,
-		stopChan:   make(chan struct{}),
+		log:           log,
+		ctxTimeout:    ctxTimeout,
+		updateFreq:    updateFreq,
+		horizon:       horizon,
+		prefetch:      prefetch,
+		deadAirWindow: deadAirWindow,
+		emergencyTag:  emergencyTag,
+		manifest:      manifest,
+		content:       content,
+		schedule:      schedule,
+		asRun:         asRun,
+		library:       library,
+		incidents:     incidents,
+		events:        events,
+		published:     make(map[int64]airing),
+		notifyChan:    notifyChan,
+		stopChan:      make(chan struct{}),
 	}
 }
 
@@ -65,13 +105,43 @@ type Manifest interface {
 type Content interface {
 	Init() error
 	Generate(ctx context.Context, segment models.Segment) error
-	ClearCache() error
+	Pin(ctx context.Context, segments []models.Segment) error
+	Prefetch(ctx context.Context, segments []models.Segment)
 	CleanUp()
 }
 
 type Schedule interface {
 	ScheduleCut(ctx context.Context, start time.Time, stop time.Time) ([]models.Segment, error)
 	Segment(ctx context.Context, id int64) (models.Segment, error)
+	NewSegment(ctx context.Context, segment models.Segment) (int64, error)
+}
+
+type AsRun interface {
+	Record(ctx context.Context, segment models.Segment, start, stop time.Time) error
+	Finish(ctx context.Context, segment models.Segment, stop time.Time) error
+}
+
+type Library interface {
+	SearchMedia(ctx context.Context, filter models.MediaFilter) ([]models.Media, error)
+}
+
+type IncidentStorage interface {
+	SaveIncident(ctx context.Context, incident models.Incident) (int64, error)
+	UpdateIncident(ctx context.Context, incident models.Incident) error
+}
+
+type Events interface {
+	Publish(e models.Event)
+}
+
+// airing is a segment put in manifest,
+// time it starts and stops playing there
+// and whether it is in as-run log.
+type airing struct {
+	segment  models.Segment
+	start    time.Time
+	stop     time.Time
+	recorded bool
 }
 
 // RunInfinitely runs dash,
@@ -119,6 +189,9 @@ func (d *Dash) Run(ctx context.Context) error {
 	}
 	defer d.runMutex.Unlock()
 
+	d.heartbeat.Start()
+	defer d.heartbeat.Stop()
+
 	log.Info("start dash")
 
 	// Before loop starts, working directories will
@@ -147,6 +220,9 @@ mainloop:
 			return err
 		}
 
+		// Fill dead air if any.
+		schedule = d.watchDeadAir(ctx, schedule, now)
+
 		// Update manifest.
 		// Do not set timeout since
 		// ctx not used in manifest.
@@ -155,11 +231,17 @@ mainloop:
 			return err
 		}
 
+		// Register segments aired.
+		d.recordAsRun(ctx, schedule, now)
+
 		// Save new manifest
 		if err := d.manifest.Dump(); err != nil {
 			log.Error("failed to dump manifest")
 		}
 
+		// Keep sources in cache.
+		d.cacheSources(ctx, schedule, now)
+
 		// Create dash chunks for non-live segments.
 		for _, segment := range schedule {
 			if segment.LiveId == 0 {
@@ -175,18 +257,15 @@ mainloop:
 			}
 		}
 
-		if err := d.content.ClearCache(); err != nil {
-			log.Error("failed to clear cache", sl.Err(err))
-		}
-
 	select_case_with_timer:
 		timer := time.After(d.updateFreq)
+		d.heartbeat.Beat(d.updateFreq)
 
 	select_case:
 		select {
 		case segm := <-d.notifyChan:
 			start := *segm.Start
-			stop := segm.Start.Add(*segm.StopCut - *segm.BeginCut)
+			stop := segm.End()
 			now := time.Now()
 			hor := now.Add(d.horizon)
 			if start.After(hor) || stop.Before(now) {
@@ -206,6 +285,163 @@ mainloop:
 	return nil
 }
 
+// recordAsRun adds segments aired to as-run log.
+//
+// Segments are tracked as they are put in the manifest.
+// Segment is recorded once it started with the time
+// it started in the manifest and expected stop,
+// so it isn't lost if the process stops.
+// Stop is updated once segment ended or left the manifest,
+// so segments shorter than update period aren't missed.
+// Segment removed from the manifest before start isn't recorded.
+func (d *Dash) recordAsRun(ctx context.Context, schedule []models.Segment, now time.Time) {
+	current := make(map[int64]airing, len(schedule))
+	for i, segment := range schedule {
+		// Manifest truncates intersecting segments.
+		stop := segment.End()
+		if i < len(schedule)-1 && stop.After(*schedule[i+1].Start) {
+			stop = *schedule[i+1].Start
+		}
+
+		a, ok := d.published[*segment.ID]
+		if !ok || !a.segment.Start.Equal(*segment.Start) {
+			// Segment put in manifest
+			// after start plays from now.
+			a = airing{start: *segment.Start}
+			if a.start.Before(now) {
+				a.start = now
+			}
+		}
+		a.segment, a.stop = segment, stop
+		current[*segment.ID] = a
+	}
+
+	published := make(map[int64]airing, len(current))
+
+	// Left the manifest.
+	for id, a := range d.published {
+		if c, ok := current[id]; ok && c.segment.Start.Equal(*a.segment.Start) {
+			continue
+		}
+		if err := d.finishAsRun(ctx, &a, now); err != nil {
+			// Try again on the next iteration.
+			if _, ok := current[id]; !ok {
+				published[id] = a
+			}
+		}
+	}
+
+	for id, a := range current {
+		if !now.Before(a.stop) {
+			if err := d.finishAsRun(ctx, &a, now); err != nil {
+				published[id] = a
+			}
+			continue
+		}
+		if !a.recorded && !now.Before(a.start) {
+			// Try again on the next iteration if failed.
+			a.recorded = d.startAsRun(ctx, a) == nil
+		}
+		published[id] = a
+	}
+
+	d.published = published
+}
+
+// startAsRun records segment
+// started with expected stop.
+func (d *Dash) startAsRun(ctx context.Context, a airing) error {
+	const op = "Dash.startAsRun"
+
+	log := d.log.With(
+		slog.String("op", op),
+	)
+
+	ctxRecord, cancelRecord := context.WithTimeout(ctx, d.ctxTimeout)
+	defer cancelRecord()
+	if err := d.asRun.Record(ctxRecord, a.segment, a.start, a.stop); err != nil {
+		log.Error("failed to record segment", slog.Int64("id", *a.segment.ID), sl.Err(err))
+		return err
+	}
+
+	return nil
+}
+
+// finishAsRun sets stop of segment ended
+// or left the manifest, recording it if needed.
+func (d *Dash) finishAsRun(ctx context.Context, a *airing, now time.Time) error {
+	const op = "Dash.finishAsRun"
+
+	log := d.log.With(
+		slog.String("op", op),
+	)
+
+	// Removed before start.
+	if !a.start.Before(now) {
+		return nil
+	}
+
+	if now.Before(a.stop) {
+		a.stop = now
+	}
+
+	if !a.recorded {
+		if err := d.startAsRun(ctx, *a); err != nil {
+			return err
+		}
+		a.recorded = true
+	}
+
+	// Entry may be recorded before restart
+	// with another start, so stop is always set.
+	ctxFinish, cancelFinish := context.WithTimeout(ctx, d.ctxTimeout)
+	defer cancelFinish()
+	if err := d.asRun.Finish(ctxFinish, a.segment, a.stop); err != nil {
+		log.Error("failed to set segment stop", slog.Int64("id", *a.segment.ID), sl.Err(err))
+		return err
+	}
+
+	return nil
+}
+
+// cacheSources pins sources of segments within
+// horizon and upcoming ones, so they aren't evicted
+// from cache, and starts loading upcoming sources.
+func (d *Dash) cacheSources(ctx context.Context, schedule []models.Segment, now time.Time) {
+	const op = "Dash.cacheSources"
+
+	log := d.log.With(
+		slog.String("op", op),
+	)
+
+	var upcoming []models.Segment
+	if d.prefetch > 0 {
+		ctxCut, cancelCut := context.WithTimeout(ctx, d.ctxTimeout)
+		var err error
+		upcoming, err = d.schedule.ScheduleCut(ctxCut, now.Add(d.horizon), now.Add(d.horizon+d.prefetch))
+		cancelCut()
+		if err != nil {
+			log.Error("failed to load upcoming schedule", sl.Err(err))
+		}
+	}
+
+	ctxPin, cancelPin := context.WithTimeout(ctx, d.ctxTimeout)
+	err := d.content.Pin(ctxPin, append(schedule[:len(schedule):len(schedule)], upcoming...))
+	cancelPin()
+	if err != nil {
+		log.Error("failed to pin sources", sl.Err(err))
+	}
+
+	if len(upcoming) > 0 {
+		go d.content.Prefetch(ctx, upcoming)
+	}
+}
+
+// Heartbeat returns state of the main loop.
+func (d *Dash) Heartbeat() heartbeat.Status {
+	return d.heartbeat.Status()
+}
+
 // Stop stops dash
 func (d *Dash) Stop() {
 	d.stopChan <- struct{}{}
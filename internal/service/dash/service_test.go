@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ptr "github.com/GintGld/fizteh-radio/internal/lib/utils/pointers"
+	"github.com/GintGld/fizteh-radio/internal/models"
+)
+
+type asRunMock struct {
+	entries map[int64]*models.AsRunEntry
+}
+
+func (a *asRunMock) Record(_ context.Context, segment models.Segment, start, stop time.Time) error {
+	if _, ok := a.entries[*segment.ID]; ok {
+		return nil
+	}
+	a.entries[*segment.ID] = &models.AsRunEntry{
+		SegmentID:    *segment.ID,
+		PlannedStart: *segment.Start,
+		ActualStart:  start,
+		Duration:     stop.Sub(start),
+	}
+	return nil
+}
+
+func (a *asRunMock) Finish(_ context.Context, segment models.Segment, stop time.Time) error {
+	if e, ok := a.entries[*segment.ID]; ok {
+		e.Duration = stop.Sub(e.ActualStart)
+	}
+	return nil
+}
+
+func TestRecordAsRun(t *testing.T) {
+	asRun := &asRunMock{entries: make(map[int64]*models.AsRunEntry)}
+	newDash := func() *Dash {
+		return &Dash{
+			log:        slog.New(slog.NewTextHandler(io.Discard, nil)),
+			ctxTimeout: time.Second,
+			asRun:      asRun,
+			published:  make(map[int64]airing),
+		}
+	}
+	d := newDash()
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	withId := func(s models.Segment, id int64) models.Segment {
+		s.ID = ptr.Ptr(id)
+		return s
+	}
+
+	playing := withId(segment(now.Add(-10*time.Second), 11*time.Second), 5)
+	short := withId(segment(playing.End(), 2*time.Second), 1)
+	long := withId(segment(short.End(), time.Minute), 2)
+	removed := withId(segment(long.End(), time.Minute), 3)
+
+	// Segment put in manifest after start
+	// is recorded from now with expected stop.
+	d.recordAsRun(context.Background(), []models.Segment{playing, short, long, removed}, now)
+	require.Len(t, asRun.entries, 1)
+	assert.Equal(t, now, asRun.entries[5].ActualStart)
+	assert.Equal(t, time.Second, asRun.entries[5].Duration)
+
+	// Short segment aired entirely between updates,
+	// long one is cut by new segment,
+	// the last one is removed before start.
+	now = now.Add(10 * time.Second)
+	cut := withId(segment(now, time.Minute), 4)
+	d.recordAsRun(context.Background(), []models.Segment{cut}, now)
+
+	require.Len(t, asRun.entries, 4)
+	assert.Equal(t, 2*time.Second, asRun.entries[1].Duration)
+	assert.Equal(t, *short.Start, asRun.entries[1].ActualStart)
+	assert.Equal(t, now.Sub(*long.Start), asRun.entries[2].Duration)
+	assert.Equal(t, time.Second, asRun.entries[5].Duration)
+
+	// Started segment is recorded at once.
+	assert.Equal(t, now, asRun.entries[4].ActualStart)
+	assert.Equal(t, time.Minute, asRun.entries[4].Duration)
+
+	// After restart segment keeps its start,
+	// stop is updated once it ended.
+	d = newDash()
+	d.recordAsRun(context.Background(), []models.Segment{cut}, now.Add(30*time.Second))
+	d.recordAsRun(context.Background(), nil, now.Add(50*time.Second))
+	assert.Equal(t, now, asRun.entries[4].ActualStart)
+	assert.Equal(t, 50*time.Second, asRun.entries[4].Duration)
+
+	d.recordAsRun(context.Background(), nil, now.Add(2*time.Minute))
+	assert.Len(t, asRun.entries, 4)
+}
@@ -0,0 +1,253 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/GintGld/fizteh-radio/internal/lib/caller"
+	"github.com/GintGld/fizteh-radio/internal/lib/logger/sl"
+	"github.com/GintGld/fizteh-radio/internal/lib/throttle"
+	"github.com/GintGld/fizteh-radio/internal/models"
+	"github.com/GintGld/fizteh-radio/internal/service"
+	"github.com/GintGld/fizteh-radio/internal/storage"
+)
+
+// minPassLength is a minimal
+// length of the new password.
+const minPassLength = 8
+
+// Editor lets editors manage
+// their own account.
+type Editor struct {
+	log          *slog.Logger
+	edtStorage   EditorStorage
+	revoker      Revoker
+	passThrottle *throttle.Throttle
+}
+
+type EditorStorage interface {
+	Editor(ctx context.Context, id int64) (models.Editor, error)
+	EditorRoles(ctx context.Context, id int64) ([]models.Role, error)
+	UpdateEditorProfile(ctx context.Context, id int64, profile models.EditorProfile) error
+	UpdateEditorPass(ctx context.Context, id int64, passHash []byte) error
+	DeleteEditorRefreshTokens(ctx context.Context, editorId int64) error
+}
+
+type Revoker interface {
+	RevokeEditor(ctx context.Context, id int64) error
+}
+
+func New(
+	log *slog.Logger,
+	edtStorage EditorStorage,
+	revoker Revoker,
+	passPolicy throttle.Policy,
+) *Editor {
+	return &Editor{
+		log:          log,
+		edtStorage:   edtStorage,
+		revoker:      revoker,
+		passThrottle: throttle.New(passPolicy),
+	}
+}
+
+// Me returns account of the caller.
+func (e *Editor) Me(ctx context.Context) (models.EditorOut, error) {
+	const op = "Editor.Me"
+
+	id, ok := callerID(ctx)
+	if !ok {
+		return models.EditorOut{}, fmt.Errorf("%s: %w", op, service.ErrEditorNotFound)
+	}
+
+	if id == models.RootID {
+		return models.EditorOut{
+			ID:    models.RootID,
+			Login: models.RootLogin,
+			Roles: []models.Role{models.RoleRoot},
+		}, nil
+	}
+
+	log := e.log.With(
+		slog.String("op", op),
+		caller.Attr(ctx),
+	)
+
+	editor, err := e.edtStorage.Editor(ctx, id)
+	if err != nil {
+		if errors.Is(err, storage.ErrEditorNotFound) {
+			log.Warn("editor not found")
+			return models.EditorOut{}, fmt.Errorf("%s: %w", op, service.ErrEditorNotFound)
+		}
+		if errors.Is(err, storage.ErrContextCancelled) {
+			log.Error("edtStorage.Editor timeout exceeded")
+			return models.EditorOut{}, service.ErrTimeout
+		}
+		log.Error("failed to get editor", sl.Err(err))
+		return models.EditorOut{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	roles, err := e.edtStorage.EditorRoles(ctx, id)
+	if err != nil {
+		if errors.Is(err, storage.ErrContextCancelled) {
+			log.Error("edtStorage.EditorRoles timeout exceeded")
+			return models.EditorOut{}, service.ErrTimeout
+		}
+		log.Error("failed to get editor roles", sl.Err(err))
+		return models.EditorOut{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return models.EditorOut{
+		ID:          editor.ID,
+		Login:       editor.Login,
+		Roles:       roles,
+		DisplayName: editor.DisplayName,
+		Contact:     editor.Contact,
+	}, nil
+}
+
+// UpdateProfile updates profile of the caller.
+//
+// Root account can't be changed.
+func (e *Editor) UpdateProfile(ctx context.Context, profile models.EditorProfile) error {
+	const op = "Editor.UpdateProfile"
+
+	id, ok := callerID(ctx)
+	if !ok {
+		return fmt.Errorf("%s: %w", op, service.ErrEditorNotFound)
+	}
+
+	if id == models.RootID {
+		return fmt.Errorf("%s: %w", op, service.ErrRootReadOnly)
+	}
+
+	log := e.log.With(
+		slog.String("op", op),
+		caller.Attr(ctx),
+	)
+
+	if err := e.edtStorage.UpdateEditorProfile(ctx, id, profile); err != nil {
+		if errors.Is(err, storage.ErrEditorNotFound) {
+			log.Warn("editor not found")
+			return fmt.Errorf("%s: %w", op, service.ErrEditorNotFound)
+		}
+		if errors.Is(err, storage.ErrContextCancelled) {
+			log.Error("edtStorage.UpdateEditorProfile timeout exceeded")
+			return service.ErrTimeout
+		}
+		log.Error("failed to update profile", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("profile updated")
+
+	return nil
+}
+
+// ChangePassword sets new password of the caller
+// if current one is correct. All editor's sessions
+// are ended, so editor has to log in again.
+//
+// Root password can't be changed.
+// New password must have at least minPassLength
+// characters, otherwise returns ErrPasswordTooShort.
+// If there were too many failed attempts of the editor,
+// returns *service.RetryAfterError.
+func (e *Editor) ChangePassword(ctx context.Context, current, pass string) error {
+	const op = "Editor.ChangePassword"
+
+	id, ok := callerID(ctx)
+	if !ok {
+		return fmt.Errorf("%s: %w", op, service.ErrEditorNotFound)
+	}
+
+	if id == models.RootID {
+		return fmt.Errorf("%s: %w", op, service.ErrRootReadOnly)
+	}
+
+	if len([]rune(pass)) < minPassLength {
+		return fmt.Errorf("%s: %w", op, service.ErrPasswordTooShort)
+	}
+
+	log := e.log.With(
+		slog.String("op", op),
+		caller.Attr(ctx),
+	)
+
+	// Stolen access token must not allow
+	// guessing the password without limit.
+	key := strconv.FormatInt(id, 10)
+	if wait := e.passThrottle.Wait(key); wait > 0 {
+		log.Warn("password change throttled", slog.Duration("wait", wait))
+		return fmt.Errorf("%s: %w", op, &service.RetryAfterError{After: wait})
+	}
+
+	editor, err := e.edtStorage.Editor(ctx, id)
+	if err != nil {
+		if errors.Is(err, storage.ErrEditorNotFound) {
+			log.Warn("editor not found")
+			return fmt.Errorf("%s: %w", op, service.ErrEditorNotFound)
+		}
+		if errors.Is(err, storage.ErrContextCancelled) {
+			log.Error("edtStorage.Editor timeout exceeded")
+			return service.ErrTimeout
+		}
+		log.Error("failed to get editor", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := bcrypt.CompareHashAndPassword(editor.PassHash, []byte(current)); err != nil {
+		e.passThrottle.Fail(key)
+		log.Info("invalid credentials", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, service.ErrInvalidCredentials)
+	}
+	e.passThrottle.Reset(key)
+
+	passHash, err := bcrypt.GenerateFromPassword([]byte(pass), bcrypt.DefaultCost)
+	if err != nil {
+		log.Error("failed to generate password hash", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := e.edtStorage.UpdateEditorPass(ctx, id, passHash); err != nil {
+		if errors.Is(err, storage.ErrContextCancelled) {
+			log.Error("edtStorage.UpdateEditorPass timeout exceeded")
+			return service.ErrTimeout
+		}
+		log.Error("failed to update password", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := e.edtStorage.DeleteEditorRefreshTokens(ctx, id); err != nil {
+		if errors.Is(err, storage.ErrContextCancelled) {
+			log.Error("edtStorage.DeleteEditorRefreshTokens timeout exceeded")
+			return service.ErrTimeout
+		}
+		log.Error("failed to delete refresh tokens", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := e.revoker.RevokeEditor(ctx, id); err != nil {
+		log.Error("failed to revoke tokens", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("password changed")
+
+	return nil
+}
+
+// callerID returns id of the editor
+// the request is made by.
+func callerID(ctx context.Context) (int64, bool) {
+	c, ok := caller.From(ctx)
+	if !ok || c.Login == "" {
+		return 0, false
+	}
+	return c.ID, true
+}
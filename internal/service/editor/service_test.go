@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/GintGld/fizteh-radio/internal/lib/caller"
+	"github.com/GintGld/fizteh-radio/internal/lib/throttle"
+	"github.com/GintGld/fizteh-radio/internal/models"
+	"github.com/GintGld/fizteh-radio/internal/service"
+)
+
+type editorStorageMock struct {
+	EditorStorage
+	editor models.Editor
+}
+
+func (s *editorStorageMock) Editor(context.Context, int64) (models.Editor, error) {
+	return s.editor, nil
+}
+
+func (s *editorStorageMock) UpdateEditorPass(_ context.Context, _ int64, passHash []byte) error {
+	s.editor.PassHash = passHash
+	return nil
+}
+
+func (s *editorStorageMock) DeleteEditorRefreshTokens(context.Context, int64) error {
+	return nil
+}
+
+type revokerMock struct{}
+
+func (revokerMock) RevokeEditor(context.Context, int64) error {
+	return nil
+}
+
+func TestChangePassword(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("current-pass"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	st := &editorStorageMock{editor: models.Editor{ID: 3, Login: "editor", PassHash: hash}}
+	e := New(
+		slog.New(slog.NewTextHandler(io.Discard, nil)),
+		st, revokerMock{},
+		throttle.Policy{LockoutAttempts: 2, Lockout: time.Hour},
+	)
+	ctx := caller.WithEditor(context.Background(), 3, "editor")
+
+	// Empty and short passwords are rejected
+	// before current one is checked.
+	assert.ErrorIs(t, e.ChangePassword(ctx, "current-pass", ""), service.ErrPasswordTooShort)
+	assert.ErrorIs(t, e.ChangePassword(ctx, "current-pass", "short"), service.ErrPasswordTooShort)
+
+	// Wrong current password locks
+	// the editor out after limit.
+	for i := 0; i < 2; i++ {
+		assert.ErrorIs(t, e.ChangePassword(ctx, "wrong", "new-password"), service.ErrInvalidCredentials)
+	}
+	err = e.ChangePassword(ctx, "current-pass", "new-password")
+	var retryErr *service.RetryAfterError
+	assert.True(t, errors.As(err, &retryErr))
+	assert.NoError(t, bcrypt.CompareHashAndPassword(st.editor.PassHash, []byte("current-pass")))
+
+	// Other editor isn't affected.
+	other := caller.WithEditor(context.Background(), 4, "other")
+	require.NoError(t, e.ChangePassword(other, "current-pass", "new-password"))
+	assert.NoError(t, bcrypt.CompareHashAndPassword(st.editor.PassHash, []byte("new-password")))
+}
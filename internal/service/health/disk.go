@@ -0,0 +1,30 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"syscall"
+
+	"github.com/GintGld/fizteh-radio/internal/models"
+)
+
+// disk checks that usage of filesystems
+// with given paths is below the threshold.
+func (h *Health) disk(context.Context) models.ComponentHealth {
+	for _, path := range h.paths {
+		var st syscall.Statfs_t
+		if err := syscall.Statfs(path, &st); err != nil {
+			return fail(fmt.Sprintf("%s: %s", path, err))
+		}
+		if st.Blocks == 0 {
+			continue
+		}
+
+		usage := 1 - float64(st.Bavail)/float64(st.Blocks)
+		if usage > h.diskUsage {
+			return fail(fmt.Sprintf("%s: %.0f%% used", path, 100*usage))
+		}
+	}
+
+	return models.ComponentHealth{Status: models.HealthOK}
+}
@@ -0,0 +1,242 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/GintGld/fizteh-radio/internal/lib/heartbeat"
+	"github.com/GintGld/fizteh-radio/internal/models"
+)
+
+// Health checks state of radio components.
+type Health struct {
+	log          *slog.Logger
+	timeout      time.Duration
+	storage      Pinger
+	source       Pinger
+	dash         Loop
+	dj           Loop
+	manifest     Manifest
+	minContent   time.Duration
+	stallTimeout time.Duration
+	paths        []string
+	diskUsage    float64
+	events       Events
+}
+
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+type Loop interface {
+	Heartbeat() heartbeat.Status
+}
+
+type Manifest interface {
+	ContentEnd() time.Time
+}
+
+type Events interface {
+	Publish(e models.Event)
+}
+
+// alert is an event published
+// on failure of watched component.
+type alert struct {
+	event   models.EventType
+	message string
+}
+
+var watched = map[string]alert{
+	"source": {models.EventSourceUnreachable, "Source storage is unreachable"},
+	"disk":   {models.EventDiskFilling, "Disk is filling up"},
+}
+
+// check returns status of single component.
+type check func(ctx context.Context) models.ComponentHealth
+
+// New returns health service.
+//
+// Loop is considered stalled if it missed
+// expected tick by stallTimeout, manifest
+// should have at least minContent ahead,
+// filesystems of given paths should be used
+// less than diskUsage (fraction).
+func New(
+	log *slog.Logger,
+	timeout time.Duration,
+	storage Pinger,
+	source Pinger,
+	dash Loop,
+	dj Loop,
+	manifest Manifest,
+	minContent time.Duration,
+	stallTimeout time.Duration,
+	paths []string,
+	diskUsage float64,
+	events Events,
+) *Health {
+	return &Health{
+		log:          log,
+		timeout:      timeout,
+		storage:      storage,
+		source:       source,
+		dash:         dash,
+		dj:           dj,
+		manifest:     manifest,
+		minContent:   minContent,
+		stallTimeout: stallTimeout,
+		paths:        paths,
+		diskUsage:    diskUsage,
+		events:       events,
+	}
+}
+
+// Live reports if background loops are not stalled.
+// Failed liveness means process has to be restarted.
+func (h *Health) Live(ctx context.Context) models.Health {
+	return h.run(ctx, "Health.Live", map[string]check{
+		"dash":   h.loop(h.dash),
+		"autodj": h.loop(h.dj),
+	})
+}
+
+// Ready reports if radio is able to stream:
+// storages are available, loops are alive,
+// manifest has enough content and ffmpeg is installed.
+func (h *Health) Ready(ctx context.Context) models.Health {
+	return h.run(ctx, "Health.Ready", map[string]check{
+		"sqlite":   h.ping(h.storage),
+		"source":   h.ping(h.source),
+		"dash":     h.loop(h.dash),
+		"autodj":   h.loop(h.dj),
+		"manifest": h.content,
+		"disk":     h.disk,
+		"ffmpeg":   binary("ffmpeg"),
+		"ffprobe":  binary("ffprobe"),
+	})
+}
+
+// Watch checks watched components every
+// interval and publishes event when one fails.
+func (h *Health) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	failed := make(map[string]bool, len(watched))
+	for {
+		res := h.run(ctx, "Health.Watch", map[string]check{
+			"source": h.ping(h.source),
+			"disk":   h.disk,
+		})
+
+		for name, c := range res.Components {
+			isFailed := c.Status == models.HealthFail
+			if isFailed && !failed[name] {
+				h.events.Publish(models.Event{
+					Type:    watched[name].event,
+					Time:    time.Now(),
+					Message: watched[name].message,
+					Details: map[string]string{"error": c.Error},
+				})
+			}
+			failed[name] = isFailed
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// run runs checks concurrently,
+// health fails if any of checks fails.
+func (h *Health) run(ctx context.Context, op string, checks map[string]check) models.Health {
+	log := h.log.With(
+		slog.String("op", op),
+	)
+
+	res := models.Health{
+		Status:     models.HealthOK,
+		Components: make(map[string]models.ComponentHealth, len(checks)),
+	}
+
+	var (
+		wg    sync.WaitGroup
+		mutex sync.Mutex
+	)
+	for name, c := range checks {
+		wg.Add(1)
+		go func(name string, c check) {
+			defer wg.Done()
+
+			ctxCheck, cancel := context.WithTimeout(ctx, h.timeout)
+			defer cancel()
+			status := c(ctxCheck)
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			res.Components[name] = status
+			if status.Status == models.HealthFail {
+				log.Warn("component is unhealthy", slog.String("component", name), slog.String("error", status.Error))
+				res.Status = models.HealthFail
+			}
+		}(name, c)
+	}
+	wg.Wait()
+
+	return res
+}
+
+func (h *Health) ping(p Pinger) check {
+	return func(ctx context.Context) models.ComponentHealth {
+		if err := p.Ping(ctx); err != nil {
+			return fail(err.Error())
+		}
+		return models.ComponentHealth{Status: models.HealthOK}
+	}
+}
+
+func (h *Health) loop(l Loop) check {
+	return func(context.Context) models.ComponentHealth {
+		s := l.Heartbeat()
+		if !s.Running {
+			return models.ComponentHealth{Status: models.HealthStopped}
+		}
+		if s.Stalled(time.Now(), h.stallTimeout) {
+			return fail(fmt.Sprintf("stalled, last tick at %s", s.Last.Format(time.RFC3339)))
+		}
+		return models.ComponentHealth{Status: models.HealthOK}
+	}
+}
+
+func (h *Health) content(context.Context) models.ComponentHealth {
+	ahead := time.Until(h.manifest.ContentEnd())
+	if ahead < h.minContent {
+		return fail(fmt.Sprintf("%.0fs of content ahead, need %.0fs", max(ahead, 0).Seconds(), h.minContent.Seconds()))
+	}
+	return models.ComponentHealth{Status: models.HealthOK}
+}
+
+// binary checks that executable is installed.
+func binary(name string) check {
+	return func(context.Context) models.ComponentHealth {
+		if _, err := exec.LookPath(name); err != nil {
+			return fail(err.Error())
+		}
+		return models.ComponentHealth{Status: models.HealthOK}
+	}
+}
+
+func fail(msg string) models.ComponentHealth {
+	return models.ComponentHealth{
+		Status: models.HealthFail,
+		Error:  msg,
+	}
+}
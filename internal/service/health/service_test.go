@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/GintGld/fizteh-radio/internal/lib/heartbeat"
+	"github.com/GintGld/fizteh-radio/internal/models"
+)
+
+type pingerMock struct{ err error }
+
+func (p pingerMock) Ping(context.Context) error { return p.err }
+
+type loopMock struct{ status heartbeat.Status }
+
+func (l loopMock) Heartbeat() heartbeat.Status { return l.status }
+
+type manifestMock struct{ end time.Time }
+
+func (m manifestMock) ContentEnd() time.Time { return m.end }
+
+type eventsMock struct {
+	mutex  sync.Mutex
+	events []models.Event
+}
+
+func (e *eventsMock) Publish(event models.Event) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.events = append(e.events, event)
+}
+
+func TestHealth(t *testing.T) {
+	now := time.Now()
+
+	h := New(
+		slog.New(slog.NewTextHandler(io.Discard, nil)),
+		time.Second,
+		pingerMock{},
+		pingerMock{err: errors.New("connection refused")},
+		loopMock{heartbeat.Status{Running: true, Last: now, Next: now.Add(time.Second)}},
+		loopMock{},
+		manifestMock{end: now.Add(10 * time.Second)},
+		30*time.Second,
+		time.Minute,
+		[]string{t.TempDir()},
+		1,
+		&eventsMock{},
+	)
+
+	live := h.Live(context.Background())
+	assert.Equal(t, models.HealthOK, live.Status)
+	assert.Equal(t, models.HealthOK, live.Components["dash"].Status)
+	assert.Equal(t, models.HealthStopped, live.Components["autodj"].Status)
+
+	ready := h.Ready(context.Background())
+	assert.Equal(t, models.HealthFail, ready.Status)
+	assert.Equal(t, models.HealthOK, ready.Components["sqlite"].Status)
+	assert.Equal(t, models.HealthFail, ready.Components["source"].Status)
+	assert.Equal(t, "connection refused", ready.Components["source"].Error)
+	assert.Equal(t, models.HealthFail, ready.Components["manifest"].Status)
+	assert.Equal(t, models.HealthOK, ready.Components["disk"].Status)
+
+	// Dash missed its tick.
+	h.dash = loopMock{heartbeat.Status{Running: true, Last: now.Add(-5 * time.Minute), Next: now.Add(-2 * time.Minute)}}
+
+	live = h.Live(context.Background())
+	assert.Equal(t, models.HealthFail, live.Status)
+	assert.Equal(t, models.HealthFail, live.Components["dash"].Status)
+}
+
+func TestWatch(t *testing.T) {
+	events := &eventsMock{}
+
+	h := New(
+		slog.New(slog.NewTextHandler(io.Discard, nil)),
+		time.Second,
+		nil,
+		pingerMock{err: errors.New("connection refused")},
+		nil, nil, nil, 0, 0,
+		[]string{t.TempDir()},
+		// Any disk is "full".
+		-1,
+		events,
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	h.Watch(ctx, 10*time.Millisecond)
+
+	// Event is published once per failure.
+	events.mutex.Lock()
+	defer events.mutex.Unlock()
+	types := make([]models.EventType, 0)
+	for _, e := range events.events {
+		types = append(types, e.Type)
+	}
+	assert.ElementsMatch(t, []models.EventType{models.EventSourceUnreachable, models.EventDiskFilling}, types)
+}
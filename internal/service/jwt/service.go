@@ -1,6 +1,8 @@
 package jwtService
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"time"
 
@@ -22,12 +24,22 @@ func New(secret []byte) *JWT {
 func (jwtStruct *JWT) NewToken(editor models.Editor, duration time.Duration) (string, error) {
 	const op = "JWT.NewToken"
 
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	now := time.Now()
+
 	token := jwt.New(jwt.SigningMethodHS256)
 
 	claims := token.Claims.(jwt.MapClaims)
+	claims["jti"] = hex.EncodeToString(id)
 	claims["uid"] = editor.ID
 	claims["login"] = editor.Login
-	claims["exp"] = time.Now().Add(duration).Unix()
+	claims["roles"] = editor.Roles
+	claims["iat"] = now.Unix()
+	claims["exp"] = now.Add(duration).Unix()
 
 	tokenString, err := token.SignedString(jwtStruct.secret)
 	if err != nil {
@@ -7,6 +7,7 @@ import (
 	"log/slog"
 	"os"
 	"os/exec"
+	"regexp"
 	"strconv"
 	"strings"
 	"sync"
@@ -14,6 +15,7 @@ import (
 
 	"github.com/GintGld/fizteh-radio/internal/lib/ffmpeg"
 	"github.com/GintGld/fizteh-radio/internal/lib/logger/sl"
+	"github.com/GintGld/fizteh-radio/internal/lib/metrics"
 	chans "github.com/GintGld/fizteh-radio/internal/lib/utils/channels"
 	ptr "github.com/GintGld/fizteh-radio/internal/lib/utils/pointers"
 	"github.com/GintGld/fizteh-radio/internal/lib/utils/writer"
@@ -43,6 +45,8 @@ type Live struct {
 	live     models.Live
 	mutex    sync.Mutex
 	stopChan chan struct{}
+
+	events Events
 }
 
 type Schedule interface {
@@ -55,10 +59,15 @@ type Schedule interface {
 	ClearSchedule(ctx context.Context, from time.Time) error
 }
 
+type Events interface {
+	Publish(e models.Event)
+}
+
 func New(
 	log *slog.Logger,
 	timeout time.Duration,
 	sch Schedule,
+	events Events,
 	delay time.Duration,
 	stepDuration time.Duration,
 	sourceType string,
@@ -78,6 +87,7 @@ func New(
 		filters:      filters,
 		dir:          dir,
 		chunkLength:  chunkLength,
+		events:       events,
 
 		mutex:    sync.Mutex{},
 		stopChan: make(chan struct{}),
@@ -229,6 +239,14 @@ main_loop:
 		case <-time.After(l.stepDuration):
 		case err := <-errChan:
 			log.Error("cmd returned error, stop live.", sl.Err(err))
+			l.events.Publish(models.Event{
+				Type:    models.EventLiveLost,
+				Time:    time.Now(),
+				Message: fmt.Sprintf("Live %q lost its input", l.live.Name),
+				Details: map[string]string{
+					"stderr": l.stderrTail(),
+				},
+			})
 			break main_loop
 		case <-l.stopChan:
 			break main_loop
@@ -271,6 +289,37 @@ main_loop:
 	return nil
 }
 
+// stderrTailLines is a number of last ffmpeg
+// stderr lines sent in live lost alert.
+const stderrTailLines = 5
+
+// urlRegexp matches urls printed by ffmpeg.
+var urlRegexp = regexp.MustCompile(`[a-zA-Z][a-zA-Z0-9+.-]*://\S*[^\s:]`)
+
+// stderrTail returns last lines of ffmpeg stderr.
+// Source address is hidden, since it may carry
+// credentials (e.g. stream key) and alerts are
+// sent to external chats.
+func (l *Live) stderrTail() string {
+	return redactTail(l.errorWriter.String(), l.source, stderrTailLines)
+}
+
+// redactTail returns last n lines of
+// output with source and urls hidden.
+func redactTail(output, source string, n int) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+
+	tail := strings.Join(lines, "\n")
+	if source != "" {
+		tail = strings.ReplaceAll(tail, source, "<source>")
+	}
+
+	return urlRegexp.ReplaceAllString(tail, "<source>")
+}
+
 // runCmd runs cmd for ffmpeg recording source.
 func (l *Live) runCmd(ctx context.Context, id int64, errChan chan<- error) (errRes error) {
 	const op = "Live.runCmd"
@@ -347,6 +396,7 @@ func (l *Live) runCmd(ctx context.Context, id int64, errChan chan<- error) (errR
 	l.errorWriter = writer.New()
 	l.cmd.Stderr = l.errorWriter
 
+	metrics.LiveStarts.Inc()
 	if err := l.cmd.Run(); err != nil {
 		// Since cmd is being closed by context,
 		// the correct shutdown returns error code -1
@@ -355,6 +405,7 @@ func (l *Live) runCmd(ctx context.Context, id int64, errChan chan<- error) (errR
 		if errors.As(err, &exitErr) && exitErr.ExitCode() == -1 && exitErr.String() == "signal: killed" {
 			log.Debug("successfully killed process")
 		} else {
+			metrics.LiveFailures.Inc()
 			log.Error(
 				"failed to run live cmd",
 				slog.String("stderr", l.errorWriter.String()),
@@ -0,0 +1,19 @@
+package live
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactTail(t *testing.T) {
+	source := "rtmp://host/live/secret-key"
+	output := "line 1\nline 2\n[rtmp @ 0x1] " + source + ": Connection refused\n" +
+		"rtmp://host/live: I/O error\n"
+
+	assert.Equal(t,
+		"[rtmp @ 0x1] <source>: Connection refused\n<source>: I/O error",
+		redactTail(output, source, 2),
+	)
+	assert.Equal(t, "line 1", redactTail("line 1\n", source, 5))
+}
@@ -7,12 +7,14 @@ import (
 	"log/slog"
 	"os"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/zencoder/go-dash/v3/mpd"
 
 	"github.com/GintGld/fizteh-radio/internal/lib/ffmpeg"
 	"github.com/GintGld/fizteh-radio/internal/lib/logger/sl"
+	"github.com/GintGld/fizteh-radio/internal/lib/metrics"
 	ptr "github.com/GintGld/fizteh-radio/internal/lib/utils/pointers"
 	"github.com/GintGld/fizteh-radio/internal/models"
 )
@@ -33,6 +35,10 @@ type Manifest struct {
 
 	man              *mpd.MPD
 	lastPlayedPeriod int
+
+	// end of the last period
+	contentEnd time.Time
+	endMutex   sync.Mutex
 }
 
 type Live interface {
@@ -106,6 +112,7 @@ func (m *Manifest) SetSchedule(_ context.Context, schedule []models.Segment) err
 
 	// reset periods
 	m.man.Periods = make([]*mpd.Period, len(schedule))
+	var contentEnd time.Time
 
 main_loop:
 	for i, segment := range schedule {
@@ -114,19 +121,19 @@ main_loop:
 		// won't play remained chunks.
 		// Music stream may be raggy,
 		// but nor server nor client won't crash.
-		stopCut := *segment.StopCut // TODO: remove this variable after migrating from pointers
+		duration := segment.Duration()
 		if i < len(schedule)-1 {
 			next := schedule[i+1]
-			if segment.Start.Add(*segment.StopCut - *segment.BeginCut).After(*next.Start) {
+			if segment.End().After(*next.Start) {
 				log.Warn(
 					"segment intersection detected",
-					slog.Time("curr end", segment.Start.Add(*segment.StopCut-*segment.BeginCut)),
+					slog.Time("curr end", segment.End()),
 					slog.Time("next start", *next.Start),
 					slog.Float64("beginCut", segment.BeginCut.Seconds()),
 					slog.Float64("stop", segment.StopCut.Seconds()),
 				)
-				stopCut = next.Start.Sub(*segment.Start)
-				if stopCut <= 0 {
+				duration = next.Start.Sub(*segment.Start)
+				if duration <= 0 {
 					log.Warn("stopcut became negative, don't put this segment into manifest")
 					continue main_loop
 				}
@@ -144,9 +151,11 @@ main_loop:
 			chunkFile = ffmpeg.ChunkFileLive(*segment.ID)
 		}
 
+		contentEnd = segment.Start.Add(duration)
+
 		m.man.Periods[i] = &mpd.Period{
 			ID:       strconv.Itoa(i + 1 + m.lastPlayedPeriod),
-			Duration: mpd.Duration(stopCut - *segment.BeginCut),
+			Duration: mpd.Duration(duration),
 			Start:    ptr.Ptr(mpd.Duration(segment.Start.Sub(m.startTime))),
 			// BaseURL:  []string{m.baseUrl},
 			AdaptationSets: []*mpd.AdaptationSet{{
@@ -181,9 +190,24 @@ main_loop:
 		}
 	}
 
+	m.endMutex.Lock()
+	m.contentEnd = contentEnd
+	m.endMutex.Unlock()
+
+	metrics.ManifestUpdates.Inc()
+
 	return nil
 }
 
+// ContentEnd returns time
+// manifest has content until.
+func (m *Manifest) ContentEnd() time.Time {
+	m.endMutex.Lock()
+	defer m.endMutex.Unlock()
+
+	return m.contentEnd
+}
+
 // updateLastPlayedPeriod updates Manifest.lastPlayedPeriod.
 //
 // Implements correct period indexing.
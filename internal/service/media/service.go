@@ -7,6 +7,7 @@ import (
 	"log/slog"
 	"slices"
 
+	"github.com/GintGld/fizteh-radio/internal/lib/caller"
 	"github.com/GintGld/fizteh-radio/internal/lib/logger/sl"
 	chans "github.com/GintGld/fizteh-radio/internal/lib/utils/channels"
 	"github.com/GintGld/fizteh-radio/internal/models"
@@ -57,12 +58,14 @@ func New(
 ) *Media {
 	const op = "Media.New"
 
+	ctx := context.Background()
+
 	localLog := log.With(
 		slog.String("op", op),
-		slog.String("editorname", models.RootLogin),
+		caller.Attr(ctx),
 	)
 
-	tagTypes, err := mediaStorage.TagTypes(context.Background())
+	tagTypes, err := mediaStorage.TagTypes(ctx)
 	if err != nil {
 		localLog.Error("failed to get tag types", sl.Err(err))
 		return nil
@@ -77,14 +80,12 @@ func New(
 	}
 }
 
-// TODO: in logging save editor name (put in context)
-
 func (l *Media) SearchMedia(ctx context.Context, filter models.MediaFilter) ([]models.Media, error) {
 	const op = "Media.SearchMedia"
 
 	log := l.log.With(
 		slog.String("op", op),
-		slog.String("editorname", models.RootLogin),
+		caller.Attr(ctx),
 	)
 
 	log.Info(
@@ -194,7 +195,7 @@ func (l *Media) NewMedia(ctx context.Context, media models.Media) (int64, error)
 
 	log := l.log.With(
 		slog.String("op", op),
-		slog.String("editorname", models.RootLogin),
+		caller.Attr(ctx),
 	)
 
 	log.Info("registering new media")
@@ -250,7 +251,7 @@ func (l *Media) UpdateMedia(ctx context.Context, media models.Media) error {
 
 	log := l.log.With(
 		slog.String("op", op),
-		slog.String("editorname", models.RootLogin),
+		caller.Attr(ctx),
 	)
 
 	log.Info("updating media", slog.Int64("id", *media.ID))
@@ -348,7 +349,7 @@ func (l *Media) MultiTagMedia(ctx context.Context, tag models.Tag, mediaIds ...i
 
 	log := l.log.With(
 		slog.String("op", op),
-		slog.String("editorname", models.RootLogin),
+		caller.Attr(ctx),
 	)
 
 	log.Info("adding tag to several media", slog.Int64("tag id", tag.ID))
@@ -384,7 +385,7 @@ func (l *Media) Media(ctx context.Context, id int64) (models.Media, error) {
 
 	log := l.log.With(
 		slog.String("op", op),
-		slog.String("editorname", models.RootLogin),
+		caller.Attr(ctx),
 	)
 
 	media, err := l.mediaStorage.Media(ctx, id)
@@ -412,7 +413,7 @@ func (l *Media) DeleteMedia(ctx context.Context, id int64) error {
 
 	log := l.log.With(
 		slog.String("op", op),
-		slog.String("editorname", models.RootLogin),
+		caller.Attr(ctx),
 	)
 
 	log.Info("deleting media", slog.Int64("id", id))
@@ -441,7 +442,7 @@ func (l *Media) TagTypes(ctx context.Context) (models.TagTypes, error) {
 
 	log := l.log.With(
 		slog.String("op", op),
-		slog.String("editorname", models.RootLogin),
+		caller.Attr(ctx),
 	)
 
 	tagTypes, err := l.mediaStorage.TagTypes(ctx)
@@ -465,7 +466,7 @@ func (l *Media) AllTags(ctx context.Context) (models.TagList, error) {
 
 	log := l.log.With(
 		slog.String("op", op),
-		slog.String("editorname", models.RootLogin),
+		caller.Attr(ctx),
 	)
 
 	tagList, err := l.mediaStorage.AllTags(ctx)
@@ -502,7 +503,7 @@ func (l *Media) SaveTag(ctx context.Context, tag models.Tag) (int64, error) {
 
 	log := l.log.With(
 		slog.String("op", op),
-		slog.String("editorname", models.RootLogin),
+		caller.Attr(ctx),
 	)
 
 	log.Info("saving tag", slog.String("name", tag.Name))
@@ -561,7 +562,7 @@ func (l *Media) Tag(ctx context.Context, id int64) (models.Tag, error) {
 
 	log := l.log.With(
 		slog.String("op", op),
-		slog.String("editorname", models.RootLogin),
+		caller.Attr(ctx),
 	)
 
 	log.Info("getting tag", slog.Int64("id", id))
@@ -604,7 +605,7 @@ func (l *Media) UpdateTag(ctx context.Context, tag models.Tag) error {
 
 	log := l.log.With(
 		slog.String("op", op),
-		slog.String("editorname", models.RootLogin),
+		caller.Attr(ctx),
 		slog.Int64("id", tag.ID),
 	)
 
@@ -671,7 +672,7 @@ func (l *Media) DeleteTag(ctx context.Context, id int64) error {
 
 	log := l.log.With(
 		slog.String("op", op),
-		slog.String("editorname", models.RootLogin),
+		caller.Attr(ctx),
 	)
 
 	log.Info("deleting tag", slog.Int64("id", id))
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/GintGld/fizteh-radio/internal/lib/logger/sl"
+	"github.com/GintGld/fizteh-radio/internal/models"
+)
+
+// ErrPermanent marks sink error
+// that won't be fixed by retry.
+var ErrPermanent = errors.New("permanent error")
+
+// Notifier sends events to sinks
+// (webhooks, telegram chats).
+type Notifier struct {
+	log        *slog.Logger
+	sinks      []Sink
+	retries    int
+	backoff    time.Duration
+	maxBackoff time.Duration
+}
+
+// Sink delivers event to single destination.
+type Sink interface {
+	Name() string
+	Send(ctx context.Context, e models.Event) error
+}
+
+// New returns notifier. Failed delivery is retried
+// up to retries times with exponential backoff
+// starting from backoff up to maxBackoff.
+func New(
+	log *slog.Logger,
+	sinks []Sink,
+	retries int,
+	backoff time.Duration,
+	maxBackoff time.Duration,
+) *Notifier {
+	return &Notifier{
+		log:        log,
+		sinks:      sinks,
+		retries:    retries,
+		backoff:    backoff,
+		maxBackoff: maxBackoff,
+	}
+}
+
+// Notify sends event to all sinks
+// and returns when delivery is finished.
+func (n *Notifier) Notify(e models.Event) {
+	done := make(chan struct{}, len(n.sinks))
+	for _, s := range n.sinks {
+		go func(s Sink) {
+			n.send(context.Background(), s, e)
+			done <- struct{}{}
+		}(s)
+	}
+	for range n.sinks {
+		<-done
+	}
+}
+
+// send delivers event to the sink with retries.
+func (n *Notifier) send(ctx context.Context, s Sink, e models.Event) {
+	const op = "Notifier.send"
+
+	log := n.log.With(
+		slog.String("op", op),
+		slog.String("sink", s.Name()),
+		slog.String("event", string(e.Type)),
+	)
+
+	delay := n.backoff
+	for attempt := 0; ; attempt++ {
+		err := s.Send(ctx, e)
+		if err == nil {
+			log.Debug("event sent")
+			return
+		}
+		if errors.Is(err, ErrPermanent) || attempt >= n.retries {
+			log.Error("failed to send event", slog.Int("attempts", attempt+1), sl.Err(err))
+			return
+		}
+
+		log.Warn("failed to send event, retry", slog.Duration("delay", delay), sl.Err(err))
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return
+		}
+		delay = min(2*delay, n.maxBackoff)
+	}
+}
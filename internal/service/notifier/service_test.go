@@ -0,0 +1,81 @@
+package service
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/GintGld/fizteh-radio/internal/models"
+)
+
+var event = models.Event{
+	Type:    models.EventDeadAir,
+	Time:    time.Date(2024, 9, 1, 12, 0, 0, 0, time.UTC),
+	Message: "Dead air from 12:00:00",
+	Details: map[string]string{"stop": "12:01:00", "start": "12:00:00"},
+}
+
+func newNotifier(sinks ...Sink) *Notifier {
+	return New(slog.New(slog.NewTextHandler(io.Discard, nil)), sinks, 3, time.Millisecond, 4*time.Millisecond)
+}
+
+func TestWebhookRetry(t *testing.T) {
+	var calls atomic.Int32
+	var got models.Event
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+	}))
+	defer srv.Close()
+
+	newNotifier(NewWebhook(srv.URL, srv.Client())).Notify(event)
+
+	assert.Equal(t, int32(3), calls.Load())
+	assert.Equal(t, event.Type, got.Type)
+	assert.Equal(t, event.Details, got.Details)
+}
+
+func TestWebhookPermanent(t *testing.T) {
+	var calls atomic.Int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	newNotifier(NewWebhook(srv.URL, srv.Client())).Notify(event)
+
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestTelegram(t *testing.T) {
+	var (
+		path string
+		body map[string]string
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+	}))
+	defer srv.Close()
+
+	newNotifier(NewTelegram(srv.URL+"/", "123:token", "-100", srv.Client())).Notify(event)
+
+	assert.Equal(t, "/bot123:token/sendMessage", path)
+	assert.Equal(t, "-100", body["chat_id"])
+	assert.Equal(t, "[dead_air] Dead air from 12:00:00\nstart: 12:00:00\nstop: 12:01:00", body["text"])
+}
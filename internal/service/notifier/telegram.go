@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/GintGld/fizteh-radio/internal/models"
+)
+
+// Telegram sends event as a message
+// of the bot (Bot API sendMessage).
+type Telegram struct {
+	api    string
+	token  string
+	chatID string
+	client *http.Client
+}
+
+// NewTelegram returns sink sending to chat.
+// Any api compatible with Telegram Bot API
+// can be used (e.g. local bot api server).
+func NewTelegram(api, token, chatID string, client *http.Client) *Telegram {
+	return &Telegram{
+		api:    strings.TrimSuffix(api, "/"),
+		token:  token,
+		chatID: chatID,
+		client: client,
+	}
+}
+
+func (t *Telegram) Name() string {
+	return "telegram"
+}
+
+func (t *Telegram) Send(ctx context.Context, e models.Event) error {
+	body, err := json.Marshal(map[string]string{
+		"chat_id": t.chatID,
+		"text":    message(e),
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrPermanent, err)
+	}
+
+	return post(ctx, t.client, t.api+"/bot"+t.token+"/sendMessage", body)
+}
+
+// message returns human-readable event.
+func message(e models.Event) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "[%s] %s", e.Type, e.Message)
+
+	keys := make([]string, 0, len(e.Details))
+	for k := range e.Details {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		fmt.Fprintf(&b, "\n%s: %s", k, e.Details[k])
+	}
+
+	return b.String()
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/GintGld/fizteh-radio/internal/models"
+)
+
+// Webhook posts event as json.
+type Webhook struct {
+	url    string
+	client *http.Client
+}
+
+func NewWebhook(url string, client *http.Client) *Webhook {
+	return &Webhook{
+		url:    url,
+		client: client,
+	}
+}
+
+func (w *Webhook) Name() string {
+	return "webhook"
+}
+
+func (w *Webhook) Send(ctx context.Context, e models.Event) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrPermanent, err)
+	}
+
+	return post(ctx, w.client, w.url, body)
+}
+
+// post sends json body, client errors
+// except timeouts and rate limits are permanent.
+func post(ctx context.Context, client *http.Client, target string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrPermanent, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		// Don't put url to logs, since
+		// it may contain secrets (bot token).
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return urlErr.Err
+		}
+		return err
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusRequestTimeout, resp.StatusCode == http.StatusTooManyRequests:
+		return fmt.Errorf("status %d", resp.StatusCode)
+	case resp.StatusCode < 500:
+		return fmt.Errorf("%w: status %d", ErrPermanent, resp.StatusCode)
+	default:
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,306 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/GintGld/fizteh-radio/internal/lib/caller"
+	"github.com/GintGld/fizteh-radio/internal/lib/logger/sl"
+	"github.com/GintGld/fizteh-radio/internal/lib/oidc"
+	"github.com/GintGld/fizteh-radio/internal/models"
+	"github.com/GintGld/fizteh-radio/internal/service"
+	"github.com/GintGld/fizteh-radio/internal/storage"
+)
+
+// StateTTL is a time editor has
+// to authenticate at provider.
+const StateTTL = 10 * time.Minute
+
+// OIDC logs editors in with external
+// identity provider (university SSO).
+type OIDC struct {
+	log           *slog.Logger
+	provider      Provider
+	storage       IdentityStorage
+	auth          Authenticator
+	loginClaim    string
+	autoProvision bool
+	roles         []models.Role
+}
+
+// session is a started authentication.
+// It is kept by the browser started it,
+// so nothing is stored until callback.
+type session struct {
+	state    string
+	verifier string
+	nonce    string
+	expires  time.Time
+}
+
+// encode returns session as url-safe string.
+func (s session) encode() string {
+	return strings.Join([]string{
+		s.state,
+		s.verifier,
+		s.nonce,
+		strconv.FormatInt(s.expires.Unix(), 10),
+	}, ".")
+}
+
+// decodeSession parses session encoded.
+func decodeSession(str string) (session, bool) {
+	parts := strings.Split(str, ".")
+	if len(parts) != 4 {
+		return session{}, false
+	}
+	expires, err := strconv.ParseInt(parts[3], 10, 64)
+	if err != nil {
+		return session{}, false
+	}
+	return session{
+		state:    parts[0],
+		verifier: parts[1],
+		nonce:    parts[2],
+		expires:  time.Unix(expires, 0),
+	}, true
+}
+
+type Provider interface {
+	AuthURL(ctx context.Context, state, nonce, verifier string) (string, error)
+	Exchange(ctx context.Context, code, verifier, nonce string) (oidc.Claims, error)
+}
+
+type IdentityStorage interface {
+	EditorIdentity(ctx context.Context, issuer, subject string) (int64, error)
+	EditorByLogin(ctx context.Context, login string) (models.Editor, error)
+	ProvisionEditor(ctx context.Context, editor models.Editor, issuer, subject string) (int64, error)
+}
+
+type Authenticator interface {
+	LoginExternal(ctx context.Context, id int64, ip string) (models.TokenPair, error)
+}
+
+// New returns OIDC login service.
+// Login is disabled if provider is nil.
+//
+// Login of the editor is taken from loginClaim.
+// Unknown identities get new editors with given roles
+// if autoProvision is set, otherwise they are rejected.
+// Identities are never linked to existing editors
+// automatically, root has to link them explicitly.
+func New(
+	log *slog.Logger,
+	provider Provider,
+	storage IdentityStorage,
+	auth Authenticator,
+	loginClaim string,
+	autoProvision bool,
+	roles []models.Role,
+) *OIDC {
+	const op = "OIDC.New"
+
+	validRoles := make([]models.Role, 0, len(roles))
+	for _, r := range roles {
+		if !r.Valid() {
+			log.Warn("ignoring invalid role", slog.String("op", op), slog.String("role", string(r)))
+			continue
+		}
+		validRoles = append(validRoles, r)
+	}
+
+	return &OIDC{
+		log:           log,
+		provider:      provider,
+		storage:       storage,
+		auth:          auth,
+		loginClaim:    loginClaim,
+		autoProvision: autoProvision,
+		roles:         validRoles,
+	}
+}
+
+// Start begins authentication and returns url
+// of the provider to redirect editor to
+// and session, which must be kept by the browser
+// (e.g. in cookie) and passed to Callback.
+func (o *OIDC) Start(ctx context.Context) (string, string, error) {
+	const op = "OIDC.Start"
+
+	if o.provider == nil {
+		return "", "", fmt.Errorf("%s: %w", op, service.ErrOIDCDisabled)
+	}
+
+	log := o.log.With(
+		slog.String("op", op),
+		caller.Attr(ctx),
+	)
+
+	var (
+		s   session
+		err error
+	)
+	s.state, err = randomString()
+	if err == nil {
+		s.nonce, err = randomString()
+	}
+	if err == nil {
+		s.verifier, err = oidc.NewVerifier()
+	}
+	if err != nil {
+		log.Error("failed to generate state", sl.Err(err))
+		return "", "", fmt.Errorf("%s: %w", op, err)
+	}
+	s.expires = time.Now().Add(StateTTL)
+
+	url, err := o.provider.AuthURL(ctx, s.state, s.nonce, s.verifier)
+	if err != nil {
+		log.Error("failed to get auth url", sl.Err(err))
+		return "", "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return url, s.encode(), nil
+}
+
+// Callback finishes authentication started
+// in given session and returns editor's tokens.
+//
+// State must match the session, so the code
+// is accepted only in the browser started login.
+func (o *OIDC) Callback(ctx context.Context, sessionStr, state, code, ip string) (models.TokenPair, error) {
+	const op = "OIDC.Callback"
+
+	if o.provider == nil {
+		return models.TokenPair{}, fmt.Errorf("%s: %w", op, service.ErrOIDCDisabled)
+	}
+
+	log := o.log.With(
+		slog.String("op", op),
+		caller.Attr(ctx),
+	)
+
+	s, ok := decodeSession(sessionStr)
+	if !ok || subtle.ConstantTimeCompare([]byte(s.state), []byte(state)) != 1 || s.expires.Before(time.Now()) {
+		log.Warn("unknown or expired state")
+		return models.TokenPair{}, fmt.Errorf("%s: %w", op, service.ErrInvalidState)
+	}
+
+	claims, err := o.provider.Exchange(ctx, code, s.verifier, s.nonce)
+	if err != nil {
+		log.Warn("failed to exchange code", sl.Err(err))
+		return models.TokenPair{}, fmt.Errorf("%s: %w", op, service.ErrInvalidCredentials)
+	}
+
+	log = log.With(slog.String("subject", claims.Subject))
+
+	id, err := o.editor(ctx, log, claims)
+	if err != nil {
+		return models.TokenPair{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	tokens, err := o.auth.LoginExternal(ctx, id, ip)
+	if err != nil {
+		return models.TokenPair{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return tokens, nil
+}
+
+// editor returns id of the editor with given identity.
+//
+// Unknown identity gets new editor if auto-provisioning
+// is enabled. Identity whose login is taken by existing
+// editor is rejected, since anyone controlling the login
+// claim at provider would take over that editor.
+func (o *OIDC) editor(ctx context.Context, log *slog.Logger, claims oidc.Claims) (int64, error) {
+	id, err := o.storage.EditorIdentity(ctx, claims.Issuer, claims.Subject)
+	if err == nil {
+		return id, nil
+	}
+	if errors.Is(err, storage.ErrContextCancelled) {
+		log.Error("storage.EditorIdentity timeout exceeded")
+		return 0, service.ErrTimeout
+	}
+	if !errors.Is(err, storage.ErrIdentityNotFound) {
+		log.Error("failed to get identity", sl.Err(err))
+		return 0, err
+	}
+
+	login := claims.String(o.loginClaim)
+	if login == "" || login == models.RootLogin {
+		log.Warn("identity has no valid login", slog.String("claim", o.loginClaim))
+		return 0, service.ErrUnknownIdentity
+	}
+
+	log = log.With(slog.String("editorname", login))
+
+	_, err = o.storage.EditorByLogin(ctx, login)
+	switch {
+	case err == nil:
+		log.Warn("login is taken by existing editor, identity must be linked by root")
+		return 0, service.ErrUnknownIdentity
+	case errors.Is(err, storage.ErrContextCancelled):
+		log.Error("storage.EditorByLogin timeout exceeded")
+		return 0, service.ErrTimeout
+	case !errors.Is(err, storage.ErrEditorNotFound):
+		log.Error("failed to get editor", sl.Err(err))
+		return 0, err
+	}
+
+	if !o.autoProvision {
+		log.Warn("unknown identity rejected")
+		return 0, service.ErrUnknownIdentity
+	}
+
+	// Password is unknown to anyone, root
+	// can reset it to allow local login.
+	pass := make([]byte, 32)
+	if _, err := rand.Read(pass); err != nil {
+		log.Error("failed to generate password", sl.Err(err))
+		return 0, err
+	}
+	passHash, err := bcrypt.GenerateFromPassword(pass, bcrypt.DefaultCost)
+	if err != nil {
+		log.Error("failed to generate password hash", sl.Err(err))
+		return 0, err
+	}
+
+	id, err = o.storage.ProvisionEditor(ctx, models.Editor{
+		Login:       login,
+		PassHash:    passHash,
+		Roles:       o.roles,
+		DisplayName: claims.String("name"),
+		Contact:     claims.String("email"),
+	}, claims.Issuer, claims.Subject)
+	if err != nil {
+		if errors.Is(err, storage.ErrContextCancelled) {
+			log.Error("storage.ProvisionEditor timeout exceeded")
+			return 0, service.ErrTimeout
+		}
+		log.Error("failed to provision editor", sl.Err(err))
+		return 0, err
+	}
+
+	log.Info("editor provisioned", slog.Int64("id", id))
+
+	return id, nil
+}
+
+// randomString returns url-safe random string.
+func randomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
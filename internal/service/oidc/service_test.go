@@ -0,0 +1,193 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/GintGld/fizteh-radio/internal/lib/oidc"
+	"github.com/GintGld/fizteh-radio/internal/lib/oidc/oidctest"
+	"github.com/GintGld/fizteh-radio/internal/models"
+	"github.com/GintGld/fizteh-radio/internal/service"
+	"github.com/GintGld/fizteh-radio/internal/storage"
+)
+
+type storageMock struct {
+	editors    []models.Editor
+	identities map[string]int64
+}
+
+func (s *storageMock) EditorIdentity(_ context.Context, issuer, subject string) (int64, error) {
+	id, ok := s.identities[issuer+subject]
+	if !ok {
+		return 0, storage.ErrIdentityNotFound
+	}
+	return id, nil
+}
+
+func (s *storageMock) EditorByLogin(_ context.Context, login string) (models.Editor, error) {
+	for _, e := range s.editors {
+		if e.Login == login {
+			return e, nil
+		}
+	}
+	return models.Editor{}, storage.ErrEditorNotFound
+}
+
+func (s *storageMock) ProvisionEditor(_ context.Context, editor models.Editor, issuer, subject string) (int64, error) {
+	editor.ID = int64(len(s.editors) + 1)
+	s.editors = append(s.editors, editor)
+	s.identities[issuer+subject] = editor.ID
+	return editor.ID, nil
+}
+
+type authMock struct {
+	logged []int64
+}
+
+func (a *authMock) LoginExternal(_ context.Context, id int64, _ string) (models.TokenPair, error) {
+	a.logged = append(a.logged, id)
+	return models.TokenPair{Access: "access", Refresh: "refresh"}, nil
+}
+
+func setup(t *testing.T, autoProvision bool) (*OIDC, *oidctest.Server, *storageMock, *authMock) {
+	srv := oidctest.NewServer("radio")
+	t.Cleanup(srv.Close)
+
+	provider := oidc.New(oidc.Config{
+		Issuer:      srv.URL,
+		ClientID:    "radio",
+		RedirectURL: "http://radio.test/login/oidc/callback",
+	}, nil)
+
+	st := &storageMock{identities: make(map[string]int64)}
+	auth := &authMock{}
+
+	o := New(
+		slog.New(slog.NewTextHandler(io.Discard, nil)),
+		provider, st, auth,
+		"preferred_username", autoProvision,
+		[]models.Role{models.RoleViewer, "unknown"},
+	)
+
+	return o, srv, st, auth
+}
+
+// login goes through the whole flow with
+// user currently set at mock provider.
+func login(t *testing.T, o *OIDC) (models.TokenPair, error) {
+	ctx := context.Background()
+
+	authURL, session, err := o.Start(ctx)
+	require.NoError(t, err)
+
+	client := &http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	resp, err := client.Get(authURL)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	loc, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+
+	return o.Callback(ctx, session, loc.Query().Get("state"), loc.Query().Get("code"), "127.0.0.1")
+}
+
+func TestProvision(t *testing.T) {
+	o, srv, st, auth := setup(t, true)
+
+	srv.SetClaims(map[string]any{
+		"sub":                "42",
+		"preferred_username": "ivanov.ii",
+		"name":               "Ivan Ivanov",
+		"email":              "ivanov.ii@phystech.edu",
+	})
+
+	tokens, err := login(t, o)
+	require.NoError(t, err)
+	assert.Equal(t, "access", tokens.Access)
+
+	require.Len(t, st.editors, 1)
+	e := st.editors[0]
+	assert.Equal(t, "ivanov.ii", e.Login)
+	assert.Equal(t, "Ivan Ivanov", e.DisplayName)
+	assert.Equal(t, "ivanov.ii@phystech.edu", e.Contact)
+	assert.Equal(t, []models.Role{models.RoleViewer}, e.Roles)
+	assert.NotEmpty(t, e.PassHash)
+
+	// Second login uses the same editor.
+	_, err = login(t, o)
+	require.NoError(t, err)
+	assert.Len(t, st.editors, 1)
+	assert.Equal(t, []int64{e.ID, e.ID}, auth.logged)
+}
+
+func TestReject(t *testing.T) {
+	o, srv, st, auth := setup(t, false)
+
+	srv.SetClaims(map[string]any{"sub": "42", "preferred_username": "stranger"})
+
+	_, err := login(t, o)
+	assert.True(t, errors.Is(err, service.ErrUnknownIdentity))
+	assert.Empty(t, st.editors)
+
+	// Existing editor isn't linked by login.
+	st.editors = append(st.editors, models.Editor{ID: 7, Login: "stranger"})
+
+	_, err = login(t, o)
+	assert.True(t, errors.Is(err, service.ErrUnknownIdentity))
+	assert.Empty(t, st.identities)
+
+	// Identity linked by root is accepted.
+	st.identities[srv.URL+"42"] = 7
+
+	_, err = login(t, o)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{7}, auth.logged)
+
+	// Root can't be logged in with external identity.
+	srv.SetClaims(map[string]any{"sub": "43", "preferred_username": models.RootLogin})
+
+	_, err = login(t, o)
+	assert.True(t, errors.Is(err, service.ErrUnknownIdentity))
+}
+
+func TestInvalidState(t *testing.T) {
+	o, _, _, _ := setup(t, true)
+	ctx := context.Background()
+
+	_, err := o.Callback(ctx, "", "unknown", "code", "127.0.0.1")
+	assert.True(t, errors.Is(err, service.ErrInvalidState))
+
+	// State of login started in another browser.
+	_, session, err := o.Start(ctx)
+	require.NoError(t, err)
+	_, other, err := o.Start(ctx)
+	require.NoError(t, err)
+	s, ok := decodeSession(other)
+	require.True(t, ok)
+
+	_, err = o.Callback(ctx, session, s.state, "code", "127.0.0.1")
+	assert.True(t, errors.Is(err, service.ErrInvalidState))
+
+	// Expired session.
+	s.expires = time.Now().Add(-time.Second)
+	_, err = o.Callback(ctx, s.encode(), s.state, "code", "127.0.0.1")
+	assert.True(t, errors.Is(err, service.ErrInvalidState))
+
+	disabled := New(slog.New(slog.NewTextHandler(io.Discard, nil)), nil, nil, nil, "", false, nil)
+
+	_, _, err = disabled.Start(ctx)
+	assert.True(t, errors.Is(err, service.ErrOIDCDisabled))
+}
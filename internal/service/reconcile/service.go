@@ -0,0 +1,372 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/GintGld/fizteh-radio/internal/lib/caller"
+	"github.com/GintGld/fizteh-radio/internal/lib/logger/sl"
+	chans "github.com/GintGld/fizteh-radio/internal/lib/utils/channels"
+	"github.com/GintGld/fizteh-radio/internal/models"
+	"github.com/GintGld/fizteh-radio/internal/service"
+	"github.com/GintGld/fizteh-radio/internal/storage"
+)
+
+// pageSize is a number of media
+// read from library at once.
+const pageSize = 100
+
+var errChecksum = errors.New("checksum mismatch")
+
+// warnNoList is reported if source
+// storage can't enumerate its sources.
+const warnNoList = "source storage can't list sources, orphans aren't checked"
+
+// Reconcile checks that every library
+// entry has downloadable source with
+// matching checksum.
+//
+// Media with broken source is marked unplayable,
+// it's playable again when source is restored.
+// Sources without media (orphans) are only
+// reported, since storage may be shared.
+type Reconcile struct {
+	log        *slog.Logger
+	timeout    time.Duration
+	tmpDir     string
+	library    Library
+	source     Source
+	events     Events
+	updateChan chan<- struct{}
+
+	running sync.Mutex
+
+	mutex  sync.Mutex
+	report *models.ReconcileReport
+}
+
+type Library interface {
+	AllMedia(ctx context.Context, limit, offset int) ([]models.Media, error)
+	SetSourceState(ctx context.Context, id int64, checksum string, playable bool) error
+}
+
+type Source interface {
+	Download(ctx context.Context, id int, dst string) error
+	Ping(ctx context.Context) error
+}
+
+// Lister is a source storage
+// able to enumerate its sources.
+type Lister interface {
+	List(ctx context.Context) ([]int, error)
+}
+
+type Events interface {
+	Publish(e models.Event)
+}
+
+// New returns reconciliation service.
+//
+// Every request to storages is limited by timeout,
+// sources are downloaded to tmpDir. Library
+// changes are sent to updateChan.
+func New(
+	log *slog.Logger,
+	timeout time.Duration,
+	tmpDir string,
+	library Library,
+	source Source,
+	events Events,
+	updateChan chan<- struct{},
+) *Reconcile {
+	return &Reconcile{
+		log:        log,
+		timeout:    timeout,
+		tmpDir:     tmpDir,
+		library:    library,
+		source:     source,
+		events:     events,
+		updateChan: updateChan,
+	}
+}
+
+// Run checks library every interval.
+func (r *Reconcile) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.Reconcile(ctx)
+		}
+	}
+}
+
+// Start runs check in background.
+func (r *Reconcile) Start(ctx context.Context) error {
+	if !r.running.TryLock() {
+		return service.ErrReconcileRunning
+	}
+
+	go func() {
+		defer r.running.Unlock()
+		r.reconcile(context.WithoutCancel(ctx))
+	}()
+
+	return nil
+}
+
+// Reconcile checks library and returns report.
+func (r *Reconcile) Reconcile(ctx context.Context) (models.ReconcileReport, error) {
+	if !r.running.TryLock() {
+		return models.ReconcileReport{}, service.ErrReconcileRunning
+	}
+	defer r.running.Unlock()
+
+	return r.reconcile(ctx)
+}
+
+// Report returns report of the last check,
+// false if library wasn't checked yet.
+func (r *Reconcile) Report() (models.ReconcileReport, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.report == nil {
+		return models.ReconcileReport{}, false
+	}
+	return *r.report, true
+}
+
+// reconcile checks library,
+// must be called with running locked.
+func (r *Reconcile) reconcile(ctx context.Context) (models.ReconcileReport, error) {
+	const op = "Reconcile.reconcile"
+
+	log := r.log.With(slog.String("op", op), caller.Attr(ctx))
+
+	report := models.ReconcileReport{
+		Start:     time.Now(),
+		Broken:    make([]models.BrokenMedia, 0),
+		Recovered: make([]int64, 0),
+	}
+
+	changed, err := r.check(ctx, log, &report)
+	if err != nil {
+		report.Error = err.Error()
+		log.Error("reconciliation interrupted", slog.Int("checked", report.Checked), sl.Err(err))
+	}
+	report.Stop = time.Now()
+
+	r.mutex.Lock()
+	r.report = &report
+	r.mutex.Unlock()
+
+	// AutoDJ reloads playable media.
+	if changed {
+		chans.Notify(r.updateChan)
+	}
+
+	if err != nil {
+		if errors.Is(err, storage.ErrContextCancelled) {
+			return report, service.ErrTimeout
+		}
+		return report, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info(
+		"library reconciled",
+		slog.Int("checked", report.Checked),
+		slog.Int("broken", len(report.Broken)),
+		slog.Int("recovered", len(report.Recovered)),
+		slog.Int("orphans", len(report.Orphans)),
+	)
+
+	return report, nil
+}
+
+// check fills report and reports if any media
+// changed playability, it's stopped if storage
+// is unavailable.
+func (r *Reconcile) check(ctx context.Context, log *slog.Logger, report *models.ReconcileReport) (changed bool, err error) {
+	// Unavailable storage
+	// doesn't break sources.
+	if err := r.ping(ctx); err != nil {
+		return false, err
+	}
+
+	sources := make(map[int64]struct{})
+	newlyBroken := make([]string, 0)
+	// Published even if check is interrupted.
+	defer func() {
+		if len(newlyBroken) > 0 {
+			r.events.Publish(models.Event{
+				Type:    models.EventSourceBroken,
+				Time:    time.Now(),
+				Message: "Media sources are broken, media won't be played by AutoDJ",
+				Details: map[string]string{"media": strings.Join(newlyBroken, ",")},
+			})
+		}
+	}()
+
+	for offset := 0; ; offset += pageSize {
+		ctxPage, cancel := context.WithTimeout(ctx, r.timeout)
+		lib, err := r.library.AllMedia(ctxPage, pageSize, offset)
+		cancel()
+		if err != nil {
+			return changed, err
+		}
+		if len(lib) == 0 {
+			break
+		}
+
+		for _, media := range lib {
+			sources[*media.SourceID] = struct{}{}
+
+			checksum, downloadErr, err := r.checksum(ctx, int(*media.SourceID))
+			if err != nil {
+				return changed, err
+			}
+			if downloadErr != nil {
+				if ctx.Err() != nil {
+					return changed, ctx.Err()
+				}
+				// Failed download is
+				// caused by storage itself.
+				if err := r.ping(ctx); err != nil {
+					return changed, err
+				}
+			}
+
+			wasPlayable := media.Playable == nil || *media.Playable
+			stored := ""
+			if media.Checksum != nil {
+				stored = *media.Checksum
+			}
+
+			reason := ""
+			switch {
+			case downloadErr != nil:
+				reason = downloadErr.Error()
+			case stored == "":
+				// Checksum of sources uploaded
+				// before it was saved.
+				stored = checksum
+			case stored != checksum:
+				reason = errChecksum.Error()
+			}
+			playable := reason == ""
+			report.Checked++
+
+			if !playable {
+				report.Broken = append(report.Broken, models.BrokenMedia{
+					ID:       *media.ID,
+					SourceID: *media.SourceID,
+					Reason:   reason,
+				})
+				log.Warn("broken source", slog.Int64("id", *media.ID), slog.Int64("sourceID", *media.SourceID), slog.String("reason", reason))
+			}
+			if playable && !wasPlayable {
+				report.Recovered = append(report.Recovered, *media.ID)
+			}
+			if !playable && wasPlayable {
+				newlyBroken = append(newlyBroken, strconv.FormatInt(*media.ID, 10))
+			}
+
+			if playable == wasPlayable && media.Checksum != nil && *media.Checksum == stored {
+				continue
+			}
+			ctxSet, cancel := context.WithTimeout(ctx, r.timeout)
+			err = r.library.SetSourceState(ctxSet, *media.ID, stored, playable)
+			cancel()
+			// Media may be deleted during check.
+			if err != nil && !errors.Is(err, storage.ErrMediaNotFound) {
+				return changed, err
+			}
+			changed = changed || playable != wasPlayable
+		}
+	}
+
+	lister, ok := r.source.(Lister)
+	if !ok {
+		log.Warn(warnNoList)
+		report.Warnings = append(report.Warnings, warnNoList)
+		return changed, nil
+	}
+
+	ctxList, cancel := context.WithTimeout(ctx, r.timeout)
+	ids, err := lister.List(ctxList)
+	cancel()
+	if err != nil {
+		return changed, err
+	}
+
+	report.Orphans = make([]int64, 0)
+	for _, id := range ids {
+		if _, ok := sources[int64(id)]; !ok {
+			report.Orphans = append(report.Orphans, int64(id))
+		}
+	}
+	slices.Sort(report.Orphans)
+	if len(report.Orphans) > 0 {
+		log.Warn("orphan sources in storage", slog.Any("ids", report.Orphans))
+	}
+
+	return changed, nil
+}
+
+// checksum downloads source and returns its sha256.
+// Failed download is returned separately
+// from errors of local filesystem.
+func (r *Reconcile) checksum(ctx context.Context, id int) (sum string, downloadErr, err error) {
+	tmp, err := os.CreateTemp(r.tmpDir, "reconcile-*")
+	if err != nil {
+		return "", nil, err
+	}
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	if err := r.source.Download(ctx, id, tmp.Name()); err != nil {
+		return "", err, nil
+	}
+
+	f, err := os.Open(tmp.Name())
+	if err != nil {
+		return "", nil, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", nil, err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil, nil
+}
+
+func (r *Reconcile) ping(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	if err := r.source.Ping(ctx); err != nil {
+		return fmt.Errorf("source storage is unavailable: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,205 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ptr "github.com/GintGld/fizteh-radio/internal/lib/utils/pointers"
+	"github.com/GintGld/fizteh-radio/internal/models"
+	"github.com/GintGld/fizteh-radio/internal/service"
+)
+
+type fakeLibrary struct {
+	media []models.Media
+}
+
+func (l *fakeLibrary) AllMedia(_ context.Context, limit, offset int) ([]models.Media, error) {
+	if offset >= len(l.media) {
+		return nil, nil
+	}
+	return l.media[offset:min(offset+limit, len(l.media))], nil
+}
+
+func (l *fakeLibrary) SetSourceState(_ context.Context, id int64, checksum string, playable bool) error {
+	for i := range l.media {
+		if *l.media[i].ID == id {
+			l.media[i].Checksum = ptr.Ptr(checksum)
+			l.media[i].Playable = ptr.Ptr(playable)
+		}
+	}
+	return nil
+}
+
+type fakeSource struct {
+	files   map[int]string
+	pingErr error
+}
+
+func (s *fakeSource) Download(_ context.Context, id int, dst string) error {
+	data, ok := s.files[id]
+	if !ok {
+		return errors.New("source not found")
+	}
+	return os.WriteFile(dst, []byte(data), 0644)
+}
+
+func (s *fakeSource) Ping(context.Context) error {
+	return s.pingErr
+}
+
+// listSource is able to list its sources.
+type listSource struct {
+	*fakeSource
+}
+
+func (s listSource) List(context.Context) ([]int, error) {
+	ids := make([]int, 0, len(s.files))
+	for id := range s.files {
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+type fakeEvents struct {
+	mutex  sync.Mutex
+	events []models.Event
+}
+
+func (e *fakeEvents) Publish(event models.Event) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.events = append(e.events, event)
+}
+
+func sum(data string) string {
+	h := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(h[:])
+}
+
+func media(id, sourceID int64, checksum string, playable bool) models.Media {
+	return models.Media{
+		ID:       ptr.Ptr(id),
+		SourceID: ptr.Ptr(sourceID),
+		Checksum: ptr.Ptr(checksum),
+		Playable: ptr.Ptr(playable),
+	}
+}
+
+func newTestReconcile(t *testing.T, lib *fakeLibrary, source Source) (*Reconcile, *fakeEvents, chan struct{}) {
+	events := &fakeEvents{}
+	updateChan := make(chan struct{}, 1)
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return New(log, time.Second, t.TempDir(), lib, source, events, updateChan), events, updateChan
+}
+
+func TestReconcile(t *testing.T) {
+	lib := &fakeLibrary{media: []models.Media{
+		media(1, 10, sum("ok"), true),
+		media(2, 20, "", true),           // checksum is unknown
+		media(3, 30, sum("old"), true),   // corrupted
+		media(4, 40, sum("lost"), true),  // deleted
+		media(5, 50, sum("back"), false), // restored
+	}}
+	source := listSource{&fakeSource{files: map[int]string{
+		10: "ok",
+		20: "new",
+		30: "corrupted",
+		50: "back",
+		60: "orphan",
+	}}}
+	r, events, updateChan := newTestReconcile(t, lib, source)
+
+	_, ok := r.Report()
+	assert.False(t, ok)
+
+	report, err := r.Reconcile(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, 5, report.Checked)
+	require.Len(t, report.Broken, 2)
+	assert.Equal(t, int64(3), report.Broken[0].ID)
+	assert.Equal(t, errChecksum.Error(), report.Broken[0].Reason)
+	assert.Equal(t, int64(4), report.Broken[1].ID)
+	assert.Equal(t, []int64{5}, report.Recovered)
+	assert.Equal(t, []int64{60}, report.Orphans)
+	assert.Empty(t, report.Warnings)
+
+	assert.Equal(t, sum("new"), *lib.media[1].Checksum)
+	assert.True(t, *lib.media[1].Playable)
+	assert.False(t, *lib.media[2].Playable)
+	assert.False(t, *lib.media[3].Playable)
+	assert.True(t, *lib.media[4].Playable)
+
+	saved, ok := r.Report()
+	assert.True(t, ok)
+	assert.Equal(t, report, saved)
+
+	select {
+	case <-updateChan:
+	case <-time.After(time.Second):
+		t.Fatal("library update isn't sent")
+	}
+
+	events.mutex.Lock()
+	require.Len(t, events.events, 1)
+	assert.Equal(t, models.EventSourceBroken, events.events[0].Type)
+	assert.Equal(t, "3,4", events.events[0].Details["media"])
+	events.mutex.Unlock()
+
+	// Already broken media isn't reported again.
+	_, err = r.Reconcile(context.Background())
+	require.NoError(t, err)
+	events.mutex.Lock()
+	assert.Len(t, events.events, 1)
+	events.mutex.Unlock()
+}
+
+func TestReconcileUnavailable(t *testing.T) {
+	lib := &fakeLibrary{media: []models.Media{media(1, 10, sum("ok"), true)}}
+	source := &fakeSource{pingErr: errors.New("connection refused")}
+	r, _, _ := newTestReconcile(t, lib, source)
+
+	report, err := r.Reconcile(context.Background())
+	require.Error(t, err)
+	assert.NotEmpty(t, report.Error)
+	assert.Nil(t, report.Orphans)
+
+	// Unavailable storage doesn't break media.
+	assert.True(t, *lib.media[0].Playable)
+}
+
+func TestReconcileRunning(t *testing.T) {
+	r, _, _ := newTestReconcile(t, &fakeLibrary{}, &fakeSource{})
+
+	r.running.Lock()
+	assert.ErrorIs(t, r.Start(context.Background()), service.ErrReconcileRunning)
+	r.running.Unlock()
+
+	require.NoError(t, r.Start(context.Background()))
+	require.Eventually(t, func() bool {
+		_, ok := r.Report()
+		return ok
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestReconcileNoList(t *testing.T) {
+	lib := &fakeLibrary{media: []models.Media{media(1, 10, sum("ok"), true)}}
+	source := &fakeSource{files: map[int]string{10: "ok"}}
+	r, _, _ := newTestReconcile(t, lib, source)
+
+	report, err := r.Reconcile(context.Background())
+	require.NoError(t, err)
+	assert.Nil(t, report.Orphans)
+	assert.Equal(t, []string{warnNoList}, report.Warnings)
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/GintGld/fizteh-radio/internal/lib/xlsx"
+	"github.com/GintGld/fizteh-radio/internal/models"
+)
+
+var ErrInvalidPeriod = errors.New("invalid period")
+
+// ParsePeriod returns bounds of period
+// given as year ("2024"), quarter ("2024Q1")
+// or month ("2024-03").
+func ParsePeriod(period string, loc *time.Location) (time.Time, time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01", period, loc); err == nil {
+		return t, t.AddDate(0, 1, 0), nil
+	}
+
+	if t, err := time.ParseInLocation("2006", period, loc); err == nil {
+		return t, t.AddDate(1, 0, 0), nil
+	}
+
+	var year, quarter int
+	if _, err := fmt.Sscanf(period, "%4dQ%1d", &year, &quarter); err == nil &&
+		len(period) == 6 && quarter >= 1 && quarter <= 4 {
+		start := time.Date(year, time.Month(3*(quarter-1)+1), 1, 0, 0, 0, 0, loc)
+		return start, start.AddDate(0, 3, 0), nil
+	}
+
+	return time.Time{}, time.Time{}, ErrInvalidPeriod
+}
+
+var header = []string{
+	"media_id", "name", "author", "plays", "aired_hours", "listener_hours",
+}
+
+// WriteCSV writes report in csv format.
+func WriteCSV(w io.Writer, report models.Report) error {
+	cw := csv.NewWriter(w)
+
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	for _, r := range report.Rows {
+		if err := cw.Write([]string{
+			strconv.FormatInt(r.MediaID, 10),
+			r.Name,
+			r.Author,
+			strconv.Itoa(r.Plays),
+			strconv.FormatFloat(r.AiredTime.Hours(), 'f', 3, 64),
+			strconv.FormatFloat(r.ListenerHours, 'f', 3, 64),
+		}); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+
+	return cw.Error()
+}
+
+// WriteXLSX writes report as spreadsheet.
+func WriteXLSX(w io.Writer, report models.Report) error {
+	rows := make([][]any, 0, len(report.Rows)+1)
+
+	head := make([]any, 0, len(header))
+	for _, h := range header {
+		head = append(head, h)
+	}
+	rows = append(rows, head)
+
+	for _, r := range report.Rows {
+		rows = append(rows, []any{
+			r.MediaID,
+			r.Name,
+			r.Author,
+			r.Plays,
+			r.AiredTime.Hours(),
+			r.ListenerHours,
+		})
+	}
+
+	return xlsx.Write(w, "report", rows)
+}
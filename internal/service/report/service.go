@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/GintGld/fizteh-radio/internal/lib/caller"
+	"github.com/GintGld/fizteh-radio/internal/lib/logger/sl"
+	"github.com/GintGld/fizteh-radio/internal/models"
+	"github.com/GintGld/fizteh-radio/internal/service"
+	"github.com/GintGld/fizteh-radio/internal/storage"
+)
+
+type Report struct {
+	log     *slog.Logger
+	storage ReportStorage
+}
+
+type ReportStorage interface {
+	AsRun(ctx context.Context, start, stop time.Time) ([]models.AsRunEntry, error)
+	Listeners(ctx context.Context, start, stop time.Time) ([]models.Listener, error)
+}
+
+func New(
+	log *slog.Logger,
+	storage ReportStorage,
+) *Report {
+	return &Report{
+		log:     log,
+		storage: storage,
+	}
+}
+
+// Report returns airplay statistics
+// of tracks aired in given period.
+func (r *Report) Report(ctx context.Context, start, stop time.Time) (models.Report, error) {
+	const op = "Report.Report"
+
+	log := r.log.With(
+		slog.String("op", op),
+		caller.Attr(ctx),
+	)
+
+	entries, err := r.storage.AsRun(ctx, start, stop)
+	if err != nil {
+		if errors.Is(err, storage.ErrContextCancelled) {
+			log.Error("storage.AsRun timeout exceeded")
+			return models.Report{}, service.ErrTimeout
+		}
+		log.Error("failed to get as-run entries", sl.Err(err))
+		return models.Report{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	listeners, err := r.storage.Listeners(ctx, start, stop)
+	if err != nil {
+		if errors.Is(err, storage.ErrContextCancelled) {
+			log.Error("storage.Listeners timeout exceeded")
+			return models.Report{}, service.ErrTimeout
+		}
+		log.Error("failed to get listeners", sl.Err(err))
+		return models.Report{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return models.Report{
+		Start: start,
+		Stop:  stop,
+		Rows:  aggregate(entries, listeners),
+	}, nil
+}
+
+// aggregate computes play counts and
+// listener-hours for every aired track.
+// Live segments are skipped.
+func aggregate(entries []models.AsRunEntry, listeners []models.Listener) []models.ReportRow {
+	listeners = slices.Clone(listeners)
+	slices.SortFunc(listeners, func(a, b models.Listener) int {
+		return a.Start.Compare(b.Start)
+	})
+
+	index := make(map[int64]int)
+	rows := make([]models.ReportRow, 0)
+
+	for _, e := range entries {
+		if e.MediaID == 0 {
+			continue
+		}
+
+		i, ok := index[e.MediaID]
+		if !ok {
+			i = len(rows)
+			index[e.MediaID] = i
+			rows = append(rows, models.ReportRow{
+				MediaID: e.MediaID,
+				Name:    e.Name,
+				Author:  e.Author,
+			})
+		}
+
+		rows[i].Plays++
+		rows[i].AiredTime += e.Duration
+		rows[i].ListenerHours += tuning(listeners, e.ActualStart, e.ActualStart.Add(e.Duration)).Hours()
+	}
+
+	slices.SortStableFunc(rows, func(a, b models.ReportRow) int {
+		return b.Plays - a.Plays
+	})
+
+	return rows
+}
+
+// tuning returns total listening time
+// of listeners during given interval.
+// Listeners must be sorted by start.
+func tuning(listeners []models.Listener, start, stop time.Time) time.Duration {
+	var res time.Duration
+	for _, l := range listeners {
+		if !l.Start.Before(stop) {
+			break
+		}
+		from := l.Start
+		if from.Before(start) {
+			from = start
+		}
+		to := l.Stop
+		if to.After(stop) {
+			to = stop
+		}
+		if to.After(from) {
+			res += to.Sub(from)
+		}
+	}
+	return res
+}
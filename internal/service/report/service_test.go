@@ -0,0 +1,77 @@
+package service
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/GintGld/fizteh-radio/internal/models"
+)
+
+func TestAggregate(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	entries := []models.AsRunEntry{
+		{MediaID: 1, Name: "a", ActualStart: start, Duration: time.Hour},
+		{LiveID: 3, ActualStart: start.Add(time.Hour), Duration: time.Hour},
+		{MediaID: 2, Name: "b", ActualStart: start.Add(2 * time.Hour), Duration: time.Hour},
+		{MediaID: 1, Name: "a", ActualStart: start.Add(3 * time.Hour), Duration: time.Hour},
+	}
+	listeners := []models.Listener{
+		// Listens whole period.
+		{Start: start.Add(-time.Hour), Stop: start.Add(5 * time.Hour)},
+		// Listens half of the second play of "b".
+		{Start: start.Add(2*time.Hour + 30*time.Minute), Stop: start.Add(3 * time.Hour)},
+	}
+
+	rows := aggregate(entries, listeners)
+	require.Len(t, rows, 2)
+
+	assert.Equal(t, int64(1), rows[0].MediaID)
+	assert.Equal(t, 2, rows[0].Plays)
+	assert.Equal(t, 2*time.Hour, rows[0].AiredTime)
+	assert.InDelta(t, 2, rows[0].ListenerHours, 1e-9)
+
+	assert.Equal(t, int64(2), rows[1].MediaID)
+	assert.Equal(t, 1, rows[1].Plays)
+	assert.InDelta(t, 1.5, rows[1].ListenerHours, 1e-9)
+}
+
+func TestParsePeriod(t *testing.T) {
+	testCases := []struct {
+		period string
+		start  time.Time
+		stop   time.Time
+		err    error
+	}{
+		{"2024", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), nil},
+		{"2024Q2", time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC), nil},
+		{"2024-12", time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), nil},
+		{"2024Q5", time.Time{}, time.Time{}, ErrInvalidPeriod},
+		{"last year", time.Time{}, time.Time{}, ErrInvalidPeriod},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.period, func(t *testing.T) {
+			start, stop, err := ParsePeriod(tc.period, time.UTC)
+			assert.ErrorIs(t, err, tc.err)
+			assert.Equal(t, tc.start, start)
+			assert.Equal(t, tc.stop, stop)
+		})
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteCSV(&buf, models.Report{Rows: []models.ReportRow{
+		{MediaID: 1, Name: "a, b", Author: "c", Plays: 2, AiredTime: 90 * time.Minute, ListenerHours: 3},
+	}}))
+
+	assert.Equal(t,
+		"media_id,name,author,plays,aired_hours,listener_hours\n1,\"a, b\",c,2,1.500,3.000\n",
+		buf.String(),
+	)
+}
@@ -0,0 +1,171 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/GintGld/fizteh-radio/internal/lib/caller"
+	"github.com/GintGld/fizteh-radio/internal/lib/logger/sl"
+	"github.com/GintGld/fizteh-radio/internal/models"
+	"github.com/GintGld/fizteh-radio/internal/service"
+	"github.com/GintGld/fizteh-radio/internal/storage"
+)
+
+// Revocation keeps list of revoked
+// access tokens. List is cached in memory,
+// since it is checked on every request.
+type Revocation struct {
+	log      *slog.Logger
+	storage  RevocationStorage
+	timeout  time.Duration
+	tokenTTL time.Duration
+
+	mutex   sync.RWMutex
+	tokens  map[string]time.Time
+	editors map[int64]models.Revocation
+}
+
+type RevocationStorage interface {
+	SaveRevocation(ctx context.Context, rev models.Revocation) error
+	Revocations(ctx context.Context, now time.Time) ([]models.Revocation, error)
+}
+
+func New(
+	log *slog.Logger,
+	storage RevocationStorage,
+	timeout time.Duration,
+	tokenTTL time.Duration,
+) *Revocation {
+	r := &Revocation{
+		log:      log,
+		storage:  storage,
+		timeout:  timeout,
+		tokenTTL: tokenTTL,
+		tokens:   make(map[string]time.Time),
+		editors:  make(map[int64]models.Revocation),
+	}
+
+	r.load()
+
+	return r
+}
+
+// load reads saved revocations.
+func (r *Revocation) load() {
+	const op = "Revocation.load"
+
+	log := r.log.With(
+		slog.String("op", op),
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+
+	revs, err := r.storage.Revocations(ctx, time.Now())
+	if err != nil {
+		log.Error("failed to get revocations", sl.Err(err))
+		return
+	}
+
+	for _, rev := range revs {
+		r.add(rev)
+	}
+}
+
+// RevokeToken revokes access token by its id.
+func (r *Revocation) RevokeToken(ctx context.Context, id string, expires time.Time) error {
+	const op = "Revocation.RevokeToken"
+
+	return r.revoke(ctx, op, models.Revocation{
+		TokenID: id,
+		Expires: expires,
+	})
+}
+
+// RevokeEditor revokes all access
+// tokens issued to editor so far.
+func (r *Revocation) RevokeEditor(ctx context.Context, id int64) error {
+	const op = "Revocation.RevokeEditor"
+
+	now := time.Now()
+
+	return r.revoke(ctx, op, models.Revocation{
+		EditorID: id,
+		Before:   now,
+		Expires:  now.Add(r.tokenTTL),
+	})
+}
+
+func (r *Revocation) revoke(ctx context.Context, op string, rev models.Revocation) error {
+	log := r.log.With(
+		slog.String("op", op),
+		caller.Attr(ctx),
+	)
+
+	if err := r.storage.SaveRevocation(ctx, rev); err != nil {
+		if errors.Is(err, storage.ErrContextCancelled) {
+			log.Error("storage.SaveRevocation timeout exceeded")
+			return service.ErrTimeout
+		}
+		log.Error("failed to save revocation", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	r.add(rev)
+
+	return nil
+}
+
+// add puts revocation to cache
+// and drops expired ones.
+func (r *Revocation) add(rev models.Revocation) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	now := time.Now()
+	for id, exp := range r.tokens {
+		if exp.Before(now) {
+			delete(r.tokens, id)
+		}
+	}
+	for id, e := range r.editors {
+		if e.Expires.Before(now) {
+			delete(r.editors, id)
+		}
+	}
+
+	if rev.TokenID != "" {
+		r.tokens[rev.TokenID] = rev.Expires
+		return
+	}
+
+	if old, ok := r.editors[rev.EditorID]; !ok || old.Before.Before(rev.Before) {
+		r.editors[rev.EditorID] = rev
+	}
+}
+
+// Revoked reports if token with given id,
+// owner and issue time was revoked.
+//
+// Issue time has second precision, so tokens issued
+// in the same second as editor revocation aren't revoked,
+// otherwise tokens issued right after it (e.g. on login
+// with new password) would be rejected.
+func (r *Revocation) Revoked(id string, editorId int64, issued time.Time) bool {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	if _, ok := r.tokens[id]; ok {
+		return true
+	}
+
+	if rev, ok := r.editors[editorId]; ok && issued.Before(rev.Before.Truncate(time.Second)) {
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/GintGld/fizteh-radio/internal/models"
+)
+
+type storageMock struct {
+	revs []models.Revocation
+}
+
+func (s *storageMock) SaveRevocation(_ context.Context, rev models.Revocation) error {
+	s.revs = append(s.revs, rev)
+	return nil
+}
+
+func (s *storageMock) Revocations(_ context.Context, now time.Time) ([]models.Revocation, error) {
+	res := make([]models.Revocation, 0)
+	for _, r := range s.revs {
+		if !r.Expires.Before(now) {
+			res = append(res, r)
+		}
+	}
+	return res, nil
+}
+
+func TestRevoked(t *testing.T) {
+	st := &storageMock{}
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	r := New(log, st, time.Second, time.Hour)
+
+	issued := time.Now().Truncate(time.Second)
+
+	assert.False(t, r.Revoked("a", 1, issued))
+
+	require.NoError(t, r.RevokeToken(context.Background(), "a", time.Now().Add(time.Hour)))
+	assert.True(t, r.Revoked("a", 1, issued))
+	assert.False(t, r.Revoked("b", 1, issued))
+
+	revoked := time.Now().Truncate(time.Second)
+	require.NoError(t, r.RevokeEditor(context.Background(), 2))
+	assert.True(t, r.Revoked("c", 2, revoked.Add(-time.Second)))
+	// Issued in the same second.
+	assert.False(t, r.Revoked("c", 2, time.Now().Truncate(time.Second)))
+	assert.False(t, r.Revoked("c", 2, time.Now().Truncate(time.Second).Add(time.Second)))
+
+	// revocations survive restart
+	r = New(log, st, time.Second, time.Hour)
+	assert.True(t, r.Revoked("a", 1, issued))
+	assert.True(t, r.Revoked("c", 2, revoked.Add(-time.Second)))
+}
@@ -5,7 +5,9 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"time"
 
+	"github.com/GintGld/fizteh-radio/internal/lib/caller"
 	"github.com/GintGld/fizteh-radio/internal/lib/logger/sl"
 	"github.com/GintGld/fizteh-radio/internal/models"
 	"github.com/GintGld/fizteh-radio/internal/service"
@@ -14,8 +16,10 @@ import (
 )
 
 type Root struct {
-	log        *slog.Logger
-	edtStorage EditorStorage
+	log          *slog.Logger
+	edtStorage   EditorStorage
+	auditStorage AuditStorage
+	revoker      Revoker
 }
 
 type EditorStorage interface {
@@ -27,15 +31,33 @@ type EditorStorage interface {
 	Editor(ctx context.Context, id int64) (models.Editor, error)
 	DeleteEditor(ctx context.Context, id int64) error
 	AllEditors(ctx context.Context) ([]models.Editor, error)
+	EditorRoles(ctx context.Context, id int64) ([]models.Role, error)
+	SetEditorRoles(ctx context.Context, id int64, roles []models.Role) error
+	UpdateEditorProfile(ctx context.Context, id int64, profile models.EditorProfile) error
+	UpdateEditorPass(ctx context.Context, id int64, passHash []byte) error
+	DeleteEditorRefreshTokens(ctx context.Context, editorId int64) error
+	SaveEditorIdentity(ctx context.Context, issuer, subject string, editorId int64) error
+}
+
+type AuditStorage interface {
+	LoginAttempts(ctx context.Context, start, stop time.Time, login string) ([]models.LoginAttempt, error)
+}
+
+type Revoker interface {
+	RevokeEditor(ctx context.Context, id int64) error
 }
 
 func New(
 	log *slog.Logger,
 	edtStorage EditorStorage,
+	auditStorage AuditStorage,
+	revoker Revoker,
 ) *Root {
 	return &Root{
-		log:        log,
-		edtStorage: edtStorage,
+		log:          log,
+		edtStorage:   edtStorage,
+		auditStorage: auditStorage,
+		revoker:      revoker,
 	}
 }
 
@@ -47,11 +69,20 @@ func (r *Root) RegisterNewEditor(ctx context.Context, form models.EditorIn) (int
 
 	log := r.log.With(
 		slog.String("op", op),
-		slog.String("editorname", models.RootLogin),
+		caller.Attr(ctx),
 	)
 
 	log.Info("registering editor")
 
+	roles := form.Roles
+	if len(roles) == 0 {
+		roles = models.DefaultRoles
+	}
+	if !validRoles(roles) {
+		log.Warn("invalid roles", slog.Any("roles", roles))
+		return models.ErrEditorID, fmt.Errorf("%s: %w", op, service.ErrInvalidRole)
+	}
+
 	passHash, err := bcrypt.GenerateFromPassword([]byte(form.Pass), bcrypt.DefaultCost)
 	if err != nil {
 		log.Error("failed to generate password hash", sl.Err(err))
@@ -74,6 +105,31 @@ func (r *Root) RegisterNewEditor(ctx context.Context, form models.EditorIn) (int
 		return models.ErrEditorID, fmt.Errorf("%s: %w", op, err)
 	}
 
+	if err := r.edtStorage.SetEditorRoles(ctx, id, roles); err != nil {
+		if errors.Is(err, storage.ErrContextCancelled) {
+			log.Error("edtStorage.SetEditorRoles timeout exceeded")
+			return models.ErrEditorID, service.ErrTimeout
+		}
+		log.Error("failed to save editor roles", sl.Err(err))
+
+		return models.ErrEditorID, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if form.DisplayName != "" || form.Contact != "" {
+		if err := r.edtStorage.UpdateEditorProfile(ctx, id, models.EditorProfile{
+			DisplayName: form.DisplayName,
+			Contact:     form.Contact,
+		}); err != nil {
+			if errors.Is(err, storage.ErrContextCancelled) {
+				log.Error("edtStorage.UpdateEditorProfile timeout exceeded")
+				return models.ErrEditorID, service.ErrTimeout
+			}
+			log.Error("failed to save editor profile", sl.Err(err))
+
+			return models.ErrEditorID, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
 	log.Info("registered editor", slog.String("login", form.Login), slog.Int64("id", id))
 
 	return id, nil
@@ -87,7 +143,7 @@ func (r *Root) DeleteEditor(ctx context.Context, id int64) error {
 
 	log := r.log.With(
 		slog.String("op", op),
-		slog.String("editorname", models.RootLogin),
+		caller.Attr(ctx),
 	)
 
 	log.Info("deleting editor", slog.Int64("id", id))
@@ -105,6 +161,12 @@ func (r *Root) DeleteEditor(ctx context.Context, id int64) error {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
+	// tokens already issued to editor must not outlive it
+	if err := r.revoker.RevokeEditor(ctx, id); err != nil {
+		log.Error("failed to revoke editor tokens", slog.Int64("id", id), sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
 	return nil
 }
 
@@ -116,7 +178,7 @@ func (r *Root) Editor(ctx context.Context, id int64) (models.EditorOut, error) {
 
 	log := r.log.With(
 		slog.String("op", op),
-		slog.String("editorname", models.RootLogin),
+		caller.Attr(ctx),
 	)
 
 	log.Info("getting editor", slog.Int64("id", id))
@@ -135,10 +197,23 @@ func (r *Root) Editor(ctx context.Context, id int64) (models.EditorOut, error) {
 		return models.EditorOut{}, fmt.Errorf("%s: %w", op, err)
 	}
 
+	roles, err := r.edtStorage.EditorRoles(ctx, id)
+	if err != nil {
+		if errors.Is(err, storage.ErrContextCancelled) {
+			log.Error("edtStorage.EditorRoles timeout exceeded")
+			return models.EditorOut{}, service.ErrTimeout
+		}
+		log.Error("failed to get editor roles", sl.Err(err))
+		return models.EditorOut{}, fmt.Errorf("%s: %w", op, err)
+	}
+
 	log.Info("found editor", slog.Int64("id", id))
 	return models.EditorOut{
-		ID:    editor.ID,
-		Login: editor.Login,
+		ID:          editor.ID,
+		Login:       editor.Login,
+		Roles:       roles,
+		DisplayName: editor.DisplayName,
+		Contact:     editor.Contact,
 	}, nil
 }
 
@@ -150,7 +225,7 @@ func (r *Root) AllEditors(ctx context.Context) ([]models.EditorOut, error) {
 
 	log := r.log.With(
 		slog.String("op", op),
-		slog.String("editorname", models.RootLogin),
+		caller.Attr(ctx),
 	)
 
 	log.Info("getting all editors")
@@ -168,11 +243,224 @@ func (r *Root) AllEditors(ctx context.Context) ([]models.EditorOut, error) {
 	editorsOut := make([]models.EditorOut, 0, len(editors))
 
 	for _, ed := range editors {
+		roles, err := r.edtStorage.EditorRoles(ctx, ed.ID)
+		if err != nil {
+			if errors.Is(err, storage.ErrContextCancelled) {
+				log.Error("edtStorage.EditorRoles timeout exceeded")
+				return []models.EditorOut{}, service.ErrTimeout
+			}
+			log.Error("failed to get editor roles", slog.Int64("id", ed.ID), sl.Err(err))
+			return []models.EditorOut{}, fmt.Errorf("%s: %w", op, err)
+		}
+
 		editorsOut = append(editorsOut, models.EditorOut{
-			ID:    ed.ID,
-			Login: ed.Login,
+			ID:          ed.ID,
+			Login:       ed.Login,
+			Roles:       roles,
+			DisplayName: ed.DisplayName,
+			Contact:     ed.Contact,
 		})
 	}
 
 	return editorsOut, nil
 }
+
+// SetEditorRoles replaces roles of editor.
+//
+// If editor with given id does not exist, returns error.
+// If any of the roles is unknown, returns error.
+func (r *Root) SetEditorRoles(ctx context.Context, id int64, roles []models.Role) error {
+	const op = "Root.SetEditorRoles"
+
+	log := r.log.With(
+		slog.String("op", op),
+		caller.Attr(ctx),
+	)
+
+	log.Info("setting editor roles", slog.Int64("id", id), slog.Any("roles", roles))
+
+	if !validRoles(roles) {
+		log.Warn("invalid roles", slog.Any("roles", roles))
+		return fmt.Errorf("%s: %w", op, service.ErrInvalidRole)
+	}
+
+	if err := r.edtStorage.SetEditorRoles(ctx, id, roles); err != nil {
+		if errors.Is(err, storage.ErrEditorNotFound) {
+			log.Warn("editor not found", slog.Int64("id", id))
+			return fmt.Errorf("%s: %w", op, service.ErrEditorNotFound)
+		}
+		if errors.Is(err, storage.ErrContextCancelled) {
+			log.Error("edtStorage.SetEditorRoles timeout exceeded")
+			return service.ErrTimeout
+		}
+		log.Error("failed to set editor roles", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	// issued tokens carry old roles, so editor
+	// has to refresh them to get new ones
+	if err := r.revoker.RevokeEditor(ctx, id); err != nil {
+		log.Error("failed to revoke editor tokens", slog.Int64("id", id), sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// UpdateEditor updates editor profile.
+//
+// If editor with given id does not exist, returns error.
+func (r *Root) UpdateEditor(ctx context.Context, id int64, profile models.EditorProfile) error {
+	const op = "Root.UpdateEditor"
+
+	log := r.log.With(
+		slog.String("op", op),
+		caller.Attr(ctx),
+	)
+
+	log.Info("updating editor", slog.Int64("id", id))
+
+	if err := r.edtStorage.UpdateEditorProfile(ctx, id, profile); err != nil {
+		if errors.Is(err, storage.ErrEditorNotFound) {
+			log.Warn("editor not found", slog.Int64("id", id))
+			return fmt.Errorf("%s: %w", op, service.ErrEditorNotFound)
+		}
+		if errors.Is(err, storage.ErrContextCancelled) {
+			log.Error("edtStorage.UpdateEditorProfile timeout exceeded")
+			return service.ErrTimeout
+		}
+		log.Error("failed to update editor", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// LinkIdentity links external identity to editor,
+// so editor can log in with identity provider.
+// Identity linked to another editor is relinked.
+//
+// If editor with given id does not exist, returns error.
+func (r *Root) LinkIdentity(ctx context.Context, id int64, identity models.Identity) error {
+	const op = "Root.LinkIdentity"
+
+	log := r.log.With(
+		slog.String("op", op),
+		caller.Attr(ctx),
+	)
+
+	log.Info(
+		"linking identity",
+		slog.Int64("id", id),
+		slog.String("issuer", identity.Issuer),
+		slog.String("subject", identity.Subject),
+	)
+
+	if _, err := r.edtStorage.Editor(ctx, id); err != nil {
+		if errors.Is(err, storage.ErrEditorNotFound) {
+			log.Warn("editor not found", slog.Int64("id", id))
+			return fmt.Errorf("%s: %w", op, service.ErrEditorNotFound)
+		}
+		if errors.Is(err, storage.ErrContextCancelled) {
+			log.Error("edtStorage.Editor timeout exceeded")
+			return service.ErrTimeout
+		}
+		log.Error("failed to get editor", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := r.edtStorage.SaveEditorIdentity(ctx, identity.Issuer, identity.Subject, id); err != nil {
+		if errors.Is(err, storage.ErrContextCancelled) {
+			log.Error("edtStorage.SaveEditorIdentity timeout exceeded")
+			return service.ErrTimeout
+		}
+		log.Error("failed to save identity", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// ResetPassword sets new password of the editor
+// and ends all editor's sessions.
+//
+// If editor with given id does not exist, returns error.
+func (r *Root) ResetPassword(ctx context.Context, id int64, pass string) error {
+	const op = "Root.ResetPassword"
+
+	log := r.log.With(
+		slog.String("op", op),
+		caller.Attr(ctx),
+	)
+
+	log.Info("resetting editor password", slog.Int64("id", id))
+
+	passHash, err := bcrypt.GenerateFromPassword([]byte(pass), bcrypt.DefaultCost)
+	if err != nil {
+		log.Error("failed to generate password hash", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := r.edtStorage.UpdateEditorPass(ctx, id, passHash); err != nil {
+		if errors.Is(err, storage.ErrEditorNotFound) {
+			log.Warn("editor not found", slog.Int64("id", id))
+			return fmt.Errorf("%s: %w", op, service.ErrEditorNotFound)
+		}
+		if errors.Is(err, storage.ErrContextCancelled) {
+			log.Error("edtStorage.UpdateEditorPass timeout exceeded")
+			return service.ErrTimeout
+		}
+		log.Error("failed to update password", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := r.edtStorage.DeleteEditorRefreshTokens(ctx, id); err != nil {
+		if errors.Is(err, storage.ErrContextCancelled) {
+			log.Error("edtStorage.DeleteEditorRefreshTokens timeout exceeded")
+			return service.ErrTimeout
+		}
+		log.Error("failed to delete refresh tokens", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := r.revoker.RevokeEditor(ctx, id); err != nil {
+		log.Error("failed to revoke editor tokens", slog.Int64("id", id), sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// LoginAttempts returns login audit records
+// of given interval. If login is not empty,
+// only its attempts are returned.
+func (r *Root) LoginAttempts(ctx context.Context, start, stop time.Time, login string) ([]models.LoginAttempt, error) {
+	const op = "Root.LoginAttempts"
+
+	log := r.log.With(
+		slog.String("op", op),
+		caller.Attr(ctx),
+	)
+
+	attempts, err := r.auditStorage.LoginAttempts(ctx, start, stop, login)
+	if err != nil {
+		if errors.Is(err, storage.ErrContextCancelled) {
+			log.Error("auditStorage.LoginAttempts timeout exceeded")
+			return []models.LoginAttempt{}, service.ErrTimeout
+		}
+		log.Error("failed to get login attempts", sl.Err(err))
+		return []models.LoginAttempt{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return attempts, nil
+}
+
+// validRoles reports if all roles can be granted to editor.
+func validRoles(roles []models.Role) bool {
+	for _, role := range roles {
+		if !role.Valid() {
+			return false
+		}
+	}
+	return true
+}
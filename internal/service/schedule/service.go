@@ -8,6 +8,7 @@ import (
 	"slices"
 	"time"
 
+	"github.com/GintGld/fizteh-radio/internal/lib/caller"
 	"github.com/GintGld/fizteh-radio/internal/lib/logger/sl"
 	chans "github.com/GintGld/fizteh-radio/internal/lib/utils/channels"
 	"github.com/GintGld/fizteh-radio/internal/models"
@@ -70,7 +71,7 @@ func (s *Schedule) ScheduleCut(ctx context.Context, start time.Time, stop time.T
 
 	log := s.log.With(
 		slog.String("op", op),
-		slog.String("editorname", models.RootLogin),
+		caller.Attr(ctx),
 	)
 
 	segments, err := s.schStorage.ScheduleCut(ctx, start, stop)
@@ -116,7 +117,7 @@ func (s *Schedule) Lives(ctx context.Context, start time.Time) ([]models.Live, e
 
 	log := s.log.With(
 		slog.String("op", op),
-		slog.String("editorname", models.RootLogin),
+		caller.Attr(ctx),
 	)
 
 	res, err := s.schStorage.GetLive(ctx, start)
@@ -138,7 +139,7 @@ func (s *Schedule) NewLive(ctx context.Context, live models.Live) (int64, error)
 
 	log := s.log.With(
 		slog.String("op", op),
-		slog.String("editorname", models.RootLogin),
+		caller.Attr(ctx),
 	)
 
 	id, err := s.schStorage.NewLive(ctx, live)
@@ -159,7 +160,7 @@ func (s *Schedule) SetLiveStop(ctx context.Context, live models.Live) error {
 
 	log := s.log.With(
 		slog.String("op", op),
-		slog.String("editorname", models.RootLogin),
+		caller.Attr(ctx),
 	)
 
 	if err := s.schStorage.SetLiveStop(ctx, live); err != nil {
@@ -181,7 +182,7 @@ func (s *Schedule) NewSegment(ctx context.Context, segment models.Segment) (int6
 
 	log := s.log.With(
 		slog.String("op", op),
-		slog.String("editorname", models.RootLogin),
+		caller.Attr(ctx),
 	)
 
 	// Check cut correctness
@@ -270,12 +271,19 @@ func (s *Schedule) NewSegment(ctx context.Context, segment models.Segment) (int6
 	}
 
 	// All intersected segments are
-	// not protected. Delete them all.
-	for _, segm := range res {
-		if segm.End() == *segment.Start || *segm.Start == segm.End() {
-			continue
+	// not protected. Delete them all
+	// if caller owns every one.
+	overlapped := slices.DeleteFunc(res, func(segm models.Segment) bool {
+		return segm.End() == *segment.Start || *segm.Start == segm.End()
+	})
+	for _, segm := range overlapped {
+		if !caller.Owns(ctx, segm.CreatedBy) {
+			log.Warn("intersected segment is owned by another editor", slog.Int64("segmId", *segm.ID), slog.Int64("owner", segm.CreatedBy))
+			return 0, service.ErrSegmentNotOwned
 		}
-		if err := s.DeleteSegment(ctx, *segm.ID); err != nil {
+	}
+	for _, segm := range overlapped {
+		if err := s.deleteSegment(ctx, *segm.ID); err != nil {
 			if errors.Is(err, service.ErrSegmentNotFound) {
 				log.Warn("did not found segment to delete", slog.Int64("segmId", *segm.ID))
 				continue
@@ -345,7 +353,7 @@ func (s *Schedule) UpdateSegmentTiming(ctx context.Context, segment models.Segme
 
 	log := s.log.With(
 		slog.String("op", op),
-		slog.String("editorname", models.RootLogin),
+		caller.Attr(ctx),
 	)
 
 	if err := s.schStorage.UpdateSegmenTiming(ctx, segment); err != nil {
@@ -393,7 +401,7 @@ func (s *Schedule) Segment(ctx context.Context, id int64) (models.Segment, error
 
 	log := s.log.With(
 		slog.String("op", op),
-		slog.String("editorname", models.RootLogin),
+		caller.Attr(ctx),
 	)
 
 	segment, err := s.schStorage.Segment(ctx, id)
@@ -445,12 +453,46 @@ func (s *Schedule) Segment(ctx context.Context, id int64) (models.Segment, error
 }
 
 // DeleteSegment deletes segment by id.
+// Editor may delete only segments created
+// by themselves or by system, root may delete any.
 func (s *Schedule) DeleteSegment(ctx context.Context, id int64) error {
 	const op = "Schedule.DeleteSegment"
 
 	log := s.log.With(
 		slog.String("op", op),
-		slog.String("editorname", models.RootLogin),
+		caller.Attr(ctx),
+	)
+
+	segment, err := s.schStorage.Segment(ctx, id)
+	if err != nil {
+		if errors.Is(err, storage.ErrSegmentNotFound) {
+			log.Warn("segment not found", slog.Int64("id", id))
+			return service.ErrSegmentNotFound
+		}
+		if errors.Is(err, storage.ErrContextCancelled) {
+			log.Error("schStorage.Segment timeout exceeded")
+			return service.ErrTimeout
+		}
+		log.Error("failed to get segment", slog.Int64("id", id), sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !caller.Owns(ctx, segment.CreatedBy) {
+		log.Warn("segment is owned by another editor", slog.Int64("id", id), slog.Int64("owner", segment.CreatedBy))
+		return service.ErrSegmentNotOwned
+	}
+
+	return s.deleteSegment(ctx, id)
+}
+
+// deleteSegment deletes segment
+// by id without ownership check.
+func (s *Schedule) deleteSegment(ctx context.Context, id int64) error {
+	const op = "Schedule.deleteSegment"
+
+	log := s.log.With(
+		slog.String("op", op),
+		caller.Attr(ctx),
 	)
 
 	isProt, err := s.schStorage.IsSegmentProtected(ctx, id)
@@ -493,7 +535,7 @@ func (s *Schedule) ClearSchedule(ctx context.Context, from time.Time) error {
 
 	log := s.log.With(
 		slog.String("op", op),
-		slog.String("editorname", models.RootLogin),
+		caller.Attr(ctx),
 	)
 
 	log.Info("clearing segments", slog.Time("from", from))
@@ -1,17 +1,39 @@
 package service
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"time"
+)
 
 var (
 	ErrInvalidCredentials = errors.New("invalid credentials")
 	ErrEditorNotFound     = errors.New("editor not found")
 	ErrEditorExists       = errors.New("editor exists")
+	ErrInvalidRole        = errors.New("invalid role")
+	ErrRootReadOnly       = errors.New("root account is read-only")
+	ErrPasswordTooShort   = errors.New("password is too short")
 
 	ErrInvalidToken = errors.New("invalid token")
 	ErrTimeoutToken = errors.New("timeout token")
 
+	ErrOIDCDisabled    = errors.New("oidc login is disabled")
+	ErrInvalidState    = errors.New("invalid state")
+	ErrUnknownIdentity = errors.New("unknown identity")
+
+	ErrAPIKeyExists      = errors.New("api key exists")
+	ErrAPIKeyNotFound    = errors.New("api key not found")
+	ErrInvalidPermission = errors.New("invalid permission")
+
 	ErrMediaNotFound = errors.New("media not found")
 
+	ErrUploadNotFound = errors.New("upload not found")
+	ErrUploadTooLarge = errors.New("upload is too large")
+	ErrUploadOffset   = errors.New("upload offset mismatch")
+	ErrUploadLocked   = errors.New("upload is in progress")
+
+	ErrReconcileRunning = errors.New("reconciliation is running")
+
 	ErrTagExists   = errors.New("tag exists")
 	ErrTagNotFound = errors.New("tag not found")
 
@@ -23,6 +45,23 @@ var (
 	ErrCutOutOfBounds        = errors.New("cuts out of bounds")
 	ErrBeginAfterStop        = errors.New("begin cut is after stop cut")
 	ErrSegmentIntersection   = errors.New("intersection between segments")
+	ErrSegmentNotOwned       = errors.New("segment is owned by another editor")
+
+	ErrTooManyAttempts = errors.New("too many attempts")
 
 	ErrTimeout = errors.New("timeout exceeded")
 )
+
+// RetryAfterError is ErrTooManyAttempts
+// with time left until next attempt.
+type RetryAfterError struct {
+	After time.Duration
+}
+
+func (e *RetryAfterError) Error() string {
+	return fmt.Sprintf("%s, retry after %s", ErrTooManyAttempts, e.After)
+}
+
+func (e *RetryAfterError) Unwrap() error {
+	return ErrTooManyAttempts
+}
@@ -2,17 +2,20 @@ package service
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"log/slog"
-	"os"
-	"strconv"
 	"time"
 
+	"github.com/GintGld/fizteh-radio/internal/lib/caller"
 	"github.com/GintGld/fizteh-radio/internal/lib/ffmpeg"
 	"github.com/GintGld/fizteh-radio/internal/lib/logger/sl"
+	"github.com/GintGld/fizteh-radio/internal/lib/tracing"
 	ptr "github.com/GintGld/fizteh-radio/internal/lib/utils/pointers"
 	"github.com/GintGld/fizteh-radio/internal/models"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type Client interface {
@@ -36,14 +39,19 @@ func New(
 	}
 }
 
-// UploadSource moves source to given directory.
+// UploadSource sends source read from r to storage,
+// source is probed for duration at the same time.
 //
-// After uploading media.SourceID and media.Duration
-// will be fulfilled (must be undefined when calling function).
-func (s *Source) UploadSource(ctx context.Context, path string, media *models.Media) error {
+// After uploading media.SourceID, media.Duration and
+// media.Checksum will be fulfilled (SourceID and Duration
+// must be undefined when calling function).
+func (s *Source) UploadSource(ctx context.Context, r io.Reader, media *models.Media) (err error) {
 	const op = "Source.UploadSource"
 
-	log := s.log.With(slog.String("op", op), slog.String("editorname", models.RootLogin))
+	ctx, span := tracing.Start(ctx, op)
+	defer func() { tracing.End(span, err) }()
+
+	log := s.log.With(slog.String("op", op), caller.Attr(ctx))
 
 	if media.SourceID != nil {
 		log.Error("media source id already set")
@@ -54,68 +62,85 @@ func (s *Source) UploadSource(ctx context.Context, path string, media *models.Me
 		return fmt.Errorf("%s: media duration already set", op)
 	}
 
-	// Open file to send.
-	source, err := os.Open(path)
-	if err != nil {
-		log.Error("failed to open input file", slog.String("file", path), sl.Err(err))
-		return err
-	}
-	defer source.Close()
-
-	// Upload data.
-	sourceID, err := s.client.Upload(ctx, source)
+	// Source is read once and
+	// teed into ffprobe.
+	pr, pw := io.Pipe()
+	probed := make(chan struct{})
+	var (
+		duration time.Duration
+		probeErr error
+	)
+	go func() {
+		defer close(probed)
+		ctxProbe, probe := tracing.Start(ctx, "ffprobe")
+		duration, probeErr = ffmpeg.ProbeDuration(ctxProbe, pr)
+		tracing.End(probe, probeErr)
+		// Upload isn't blocked if
+		// ffprobe exited early.
+		io.Copy(io.Discard, pr)
+	}()
+
+	hash := sha256.New()
+	sourceID, err := s.client.Upload(ctx, io.TeeReader(r, io.MultiWriter(pw, hash)))
+	pw.CloseWithError(err)
+	<-probed
 	if err != nil {
 		log.Error("failed to send data", sl.Err(err))
 		return fmt.Errorf("%s: %w", op, err)
 	}
-	media.SourceID = ptr.Ptr(int64(sourceID))
-
-	// Get metadata.
-	durationString, err := ffmpeg.GetMeta(&path, "duration")
-	if err != nil {
-		log.Error("failed to get source duration", slog.String("file", path), sl.Err(err))
-		return fmt.Errorf("%s: %w", op, err)
-	}
-	durationSec, err := strconv.ParseFloat(durationString, 64)
-	if err != nil {
-		log.Error("got invalid duration from metadata", slog.String("value", durationString), sl.Err(err))
-		return fmt.Errorf("%s: %w", op, err)
+	span.SetAttributes(attribute.Int("source.id", sourceID))
+
+	if probeErr != nil {
+		log.Error("failed to get source duration", sl.Err(probeErr))
+		// Source without media is useless.
+		if err := s.client.Delete(ctx, sourceID); err != nil {
+			log.Error("failed to delete source", slog.Int("id", sourceID), sl.Err(err))
+		}
+		return fmt.Errorf("%s: %w", op, probeErr)
 	}
-	media.Duration = ptr.Ptr(time.Microsecond * time.Duration(durationSec*1000000))
+
+	media.SourceID = ptr.Ptr(int64(sourceID))
+	media.Duration = ptr.Ptr(duration)
+	media.Checksum = ptr.Ptr(hex.EncodeToString(hash.Sum(nil)))
 
 	return nil
 }
 
-// LoadSource moves source file related to media
-// to destDir.
-func (s *Source) LoadSource(ctx context.Context, destDir string, media models.Media) (string, error) {
+// LoadSource downloads source file
+// related to media to dst.
+func (s *Source) LoadSource(ctx context.Context, dst string, media models.Media) (err error) {
 	const op = "Source.LoadSource"
 
-	log := s.log.With(slog.String("op", op), slog.String("editorname", models.RootLogin))
+	ctx, span := tracing.Start(ctx, op)
+	defer func() { tracing.End(span, err) }()
+
+	log := s.log.With(slog.String("op", op), caller.Attr(ctx))
 
 	if media.SourceID == nil {
 		log.Error("media source is not defined")
-		return "", fmt.Errorf("%s: media source is not defined", op)
+		return fmt.Errorf("%s: media source is not defined", op)
 	}
-
-	file := fmt.Sprintf("%s/%d.mp3", destDir, *media.SourceID)
+	span.SetAttributes(attribute.Int64("source.id", *media.SourceID))
 
 	// Download file.
-	if err := s.client.Download(ctx, int(*media.SourceID), file); err != nil {
-		log.Error("failed to download file", slog.String("dst", destDir), slog.Int64("id", *media.SourceID), sl.Err(err))
-		return "", fmt.Errorf("%s: %w", op, err)
+	if err := s.client.Download(ctx, int(*media.SourceID), dst); err != nil {
+		log.Error("failed to download file", slog.String("dst", dst), slog.Int64("id", *media.SourceID), sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
 	}
 
-	return file, nil
+	return nil
 }
 
 // DeleteSource deletes source related to given media.
-func (s *Source) DeleteSource(ctx context.Context, media models.Media) error {
+func (s *Source) DeleteSource(ctx context.Context, media models.Media) (err error) {
 	const op = "Source.DeleteSource"
 
+	ctx, span := tracing.Start(ctx, op)
+	defer func() { tracing.End(span, err) }()
+
 	log := s.log.With(
 		slog.String("op", op),
-		slog.String("editorname", models.RootLogin),
+		caller.Attr(ctx),
 	)
 
 	if media.SourceID == nil {
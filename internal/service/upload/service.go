@@ -0,0 +1,367 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/GintGld/fizteh-radio/internal/lib/caller"
+	"github.com/GintGld/fizteh-radio/internal/lib/logger/sl"
+	"github.com/GintGld/fizteh-radio/internal/models"
+	"github.com/GintGld/fizteh-radio/internal/service"
+)
+
+const infoSuffix = ".json"
+
+// Upload keeps resumable uploads of media sources.
+//
+// Received bytes are stored in a file named by
+// upload id, upload information is stored next to it,
+// so uploads survive restarts.
+type Upload struct {
+	log       *slog.Logger
+	dir       string
+	ttl       time.Duration
+	maxLength int64
+	source    Source
+	media     Media
+
+	mutex  sync.Mutex
+	active map[string]struct{}
+}
+
+type Source interface {
+	UploadSource(ctx context.Context, r io.Reader, media *models.Media) error
+	DeleteSource(ctx context.Context, media models.Media) error
+}
+
+type Media interface {
+	NewMedia(ctx context.Context, media models.Media) (int64, error)
+}
+
+// New returns upload service.
+//
+// Uploads not finished within ttl are
+// deleted, upload can't exceed maxLength bytes.
+// Complete upload is sent to source storage
+// and registered in media library.
+func New(
+	log *slog.Logger,
+	dir string,
+	ttl time.Duration,
+	maxLength int64,
+	source Source,
+	media Media,
+) *Upload {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		log.Error("failed to create uploads dir", slog.String("dir", dir), sl.Err(err))
+	}
+
+	return &Upload{
+		log:       log,
+		dir:       dir,
+		ttl:       ttl,
+		maxLength: maxLength,
+		source:    source,
+		media:     media,
+		active:    make(map[string]struct{}),
+	}
+}
+
+// Create starts upload of source
+// of given length for media.
+func (u *Upload) Create(ctx context.Context, length int64, media models.Media) (models.Upload, error) {
+	const op = "Upload.Create"
+
+	log := u.log.With(slog.String("op", op), caller.Attr(ctx))
+
+	if length <= 0 || length > u.maxLength {
+		return models.Upload{}, service.ErrUploadTooLarge
+	}
+
+	u.expire(log)
+
+	id, err := newID()
+	if err != nil {
+		log.Error("failed to generate id", sl.Err(err))
+		return models.Upload{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	upload := models.Upload{
+		ID:      id,
+		Length:  length,
+		Media:   media,
+		Created: time.Now(),
+	}
+
+	info, err := json.Marshal(upload)
+	if err != nil {
+		log.Error("failed to marshal upload", sl.Err(err))
+		return models.Upload{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := os.WriteFile(u.path(id), nil, 0644); err != nil {
+		log.Error("failed to create upload file", sl.Err(err))
+		return models.Upload{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := os.WriteFile(u.path(id)+infoSuffix, info, 0644); err != nil {
+		log.Error("failed to save upload", sl.Err(err))
+		os.Remove(u.path(id))
+		return models.Upload{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("upload created", slog.String("id", id), slog.Int64("length", length))
+
+	return upload, nil
+}
+
+// Upload returns upload by id.
+func (u *Upload) Upload(ctx context.Context, id string) (models.Upload, error) {
+	const op = "Upload.Upload"
+
+	log := u.log.With(slog.String("op", op), caller.Attr(ctx))
+
+	upload, err := u.load(id)
+	if err != nil {
+		if errors.Is(err, service.ErrUploadNotFound) {
+			return models.Upload{}, service.ErrUploadNotFound
+		}
+		log.Error("failed to load upload", slog.String("id", id), sl.Err(err))
+		return models.Upload{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return upload, nil
+}
+
+// Append writes data from r starting at offset,
+// it must be equal to number of bytes already received.
+//
+// Received bytes are kept if r fails,
+// so upload can be resumed from new offset.
+// When all bytes are received media is created
+// (upload.MediaID is set) and upload is deleted.
+func (u *Upload) Append(ctx context.Context, id string, offset int64, r io.Reader) (models.Upload, error) {
+	const op = "Upload.Append"
+
+	log := u.log.With(slog.String("op", op), caller.Attr(ctx))
+
+	// Only one request may write to upload.
+	u.mutex.Lock()
+	if _, ok := u.active[id]; ok {
+		u.mutex.Unlock()
+		return models.Upload{}, service.ErrUploadLocked
+	}
+	u.active[id] = struct{}{}
+	u.mutex.Unlock()
+	defer func() {
+		u.mutex.Lock()
+		delete(u.active, id)
+		u.mutex.Unlock()
+	}()
+
+	upload, err := u.load(id)
+	if err != nil {
+		if errors.Is(err, service.ErrUploadNotFound) {
+			return models.Upload{}, service.ErrUploadNotFound
+		}
+		log.Error("failed to load upload", slog.String("id", id), sl.Err(err))
+		return models.Upload{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if upload.Offset != offset {
+		return upload, service.ErrUploadOffset
+	}
+
+	f, err := os.OpenFile(u.path(id), os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		log.Error("failed to open upload file", slog.String("id", id), sl.Err(err))
+		return upload, fmt.Errorf("%s: %w", op, err)
+	}
+	defer f.Close()
+
+	n, err := io.Copy(f, io.LimitReader(r, upload.Length-upload.Offset))
+	upload.Offset += n
+	if err != nil {
+		log.Warn("upload interrupted", slog.String("id", id), slog.Int64("offset", upload.Offset), sl.Err(err))
+		return upload, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if upload.Offset < upload.Length {
+		return upload, nil
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		log.Error("failed to rewind upload file", slog.String("id", id), sl.Err(err))
+		return upload, fmt.Errorf("%s: %w", op, err)
+	}
+	mediaID, err := u.complete(ctx, f, upload.Media)
+	if err != nil {
+		if errors.Is(err, service.ErrTagNotFound) {
+			// Upload can't be completed.
+			u.remove(log, id)
+		}
+		log.Error("failed to complete upload", slog.String("id", id), sl.Err(err))
+		return upload, fmt.Errorf("%s: %w", op, err)
+	}
+	upload.MediaID = &mediaID
+
+	u.remove(log, id)
+	log.Info("upload completed", slog.String("id", id), slog.Int64("mediaID", mediaID))
+
+	return upload, nil
+}
+
+// complete sends source to storage and creates media.
+func (u *Upload) complete(ctx context.Context, r io.Reader, media models.Media) (int64, error) {
+	if err := u.source.UploadSource(ctx, r, &media); err != nil {
+		return 0, err
+	}
+
+	id, err := u.media.NewMedia(ctx, media)
+	if err != nil {
+		// Source without media is useless.
+		if err := u.source.DeleteSource(ctx, media); err != nil {
+			u.log.Error("failed to delete source", slog.Int64("sourceID", *media.SourceID), sl.Err(err))
+		}
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// Delete deletes upload with received data.
+func (u *Upload) Delete(ctx context.Context, id string) error {
+	const op = "Upload.Delete"
+
+	log := u.log.With(slog.String("op", op), caller.Attr(ctx))
+
+	if !validID(id) {
+		return service.ErrUploadNotFound
+	}
+
+	u.mutex.Lock()
+	_, busy := u.active[id]
+	u.mutex.Unlock()
+	if busy {
+		return service.ErrUploadLocked
+	}
+
+	if err := u.delete(id); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return service.ErrUploadNotFound
+		}
+		log.Error("failed to delete upload", slog.String("id", id), sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// delete removes upload files,
+// os.ErrNotExist is returned for unknown upload.
+func (u *Upload) delete(id string) error {
+	if err := os.Remove(u.path(id) + infoSuffix); err != nil {
+		return err
+	}
+	if err := os.Remove(u.path(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// remove deletes upload, error is only logged.
+func (u *Upload) remove(log *slog.Logger, id string) {
+	if err := u.delete(id); err != nil {
+		log.Error("failed to delete upload", slog.String("id", id), sl.Err(err))
+	}
+}
+
+// load reads upload information,
+// offset is a size of received data.
+func (u *Upload) load(id string) (models.Upload, error) {
+	if !validID(id) {
+		return models.Upload{}, service.ErrUploadNotFound
+	}
+
+	info, err := os.ReadFile(u.path(id) + infoSuffix)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return models.Upload{}, service.ErrUploadNotFound
+		}
+		return models.Upload{}, err
+	}
+
+	var upload models.Upload
+	if err := json.Unmarshal(info, &upload); err != nil {
+		return models.Upload{}, err
+	}
+
+	stat, err := os.Stat(u.path(id))
+	if err != nil {
+		return models.Upload{}, err
+	}
+	upload.Offset = stat.Size()
+
+	return upload, nil
+}
+
+// expire deletes uploads older than ttl.
+func (u *Upload) expire(log *slog.Logger) {
+	entries, err := os.ReadDir(u.dir)
+	if err != nil {
+		log.Error("failed to read uploads dir", sl.Err(err))
+		return
+	}
+
+	for _, e := range entries {
+		id, ok := strings.CutSuffix(e.Name(), infoSuffix)
+		if !ok {
+			continue
+		}
+		u.mutex.Lock()
+		_, busy := u.active[id]
+		u.mutex.Unlock()
+		if busy {
+			continue
+		}
+
+		upload, err := u.load(id)
+		if err != nil || time.Since(upload.Created) < u.ttl {
+			continue
+		}
+		if err := u.delete(id); err != nil {
+			log.Error("failed to delete expired upload", slog.String("id", id), sl.Err(err))
+			continue
+		}
+		log.Info("expired upload deleted", slog.String("id", id))
+	}
+}
+
+func (u *Upload) path(id string) string {
+	return filepath.Join(u.dir, id)
+}
+
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// validID prevents paths outside uploads dir.
+func validID(id string) bool {
+	if len(id) != 32 {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil
+}
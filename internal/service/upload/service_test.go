@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/GintGld/fizteh-radio/internal/models"
+	"github.com/GintGld/fizteh-radio/internal/service"
+)
+
+type fakeSource struct {
+	data    string
+	deleted bool
+}
+
+func (s *fakeSource) UploadSource(_ context.Context, r io.Reader, media *models.Media) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	s.data = string(data)
+	id, duration := int64(7), time.Second
+	media.SourceID, media.Duration = &id, &duration
+	return nil
+}
+
+func (s *fakeSource) DeleteSource(context.Context, models.Media) error {
+	s.deleted = true
+	return nil
+}
+
+type fakeMedia struct {
+	err   error
+	media models.Media
+}
+
+func (m *fakeMedia) NewMedia(_ context.Context, media models.Media) (int64, error) {
+	m.media = media
+	return 3, m.err
+}
+
+// failingReader returns error after data.
+type failingReader struct{ r io.Reader }
+
+func (f failingReader) Read(p []byte) (int, error) {
+	n, err := f.r.Read(p)
+	if errors.Is(err, io.EOF) {
+		return n, io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+func newTestUpload(t *testing.T, media *fakeMedia) (*Upload, *fakeSource) {
+	src := &fakeSource{}
+	return New(slog.New(slog.NewTextHandler(io.Discard, nil)), t.TempDir(), time.Hour, 10, src, media), src
+}
+
+func TestUploadResume(t *testing.T) {
+	media := &fakeMedia{}
+	u, src := newTestUpload(t, media)
+	ctx := context.Background()
+	name := "podcast"
+
+	upload, err := u.Create(ctx, 10, models.Media{Name: &name})
+	require.NoError(t, err)
+
+	// Interrupted chunk is kept.
+	upload, err = u.Append(ctx, upload.ID, 0, failingReader{strings.NewReader("0123")})
+	require.Error(t, err)
+	assert.Equal(t, int64(4), upload.Offset)
+
+	upload, err = u.Upload(ctx, upload.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), upload.Offset)
+
+	_, err = u.Append(ctx, upload.ID, 2, strings.NewReader("23"))
+	assert.ErrorIs(t, err, service.ErrUploadOffset)
+
+	upload, err = u.Append(ctx, upload.ID, 4, strings.NewReader("4567"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(8), upload.Offset)
+	assert.Nil(t, upload.MediaID)
+
+	// Extra bytes are ignored.
+	upload, err = u.Append(ctx, upload.ID, 8, strings.NewReader("89ab"))
+	require.NoError(t, err)
+	require.NotNil(t, upload.MediaID)
+	assert.Equal(t, int64(3), *upload.MediaID)
+	assert.Equal(t, "0123456789", src.data)
+	assert.Equal(t, name, *media.media.Name)
+	assert.Equal(t, int64(7), *media.media.SourceID)
+
+	_, err = u.Upload(ctx, upload.ID)
+	assert.ErrorIs(t, err, service.ErrUploadNotFound)
+}
+
+func TestUploadInvalid(t *testing.T) {
+	u, _ := newTestUpload(t, &fakeMedia{})
+	ctx := context.Background()
+
+	_, err := u.Create(ctx, 11, models.Media{})
+	assert.ErrorIs(t, err, service.ErrUploadTooLarge)
+
+	_, err = u.Upload(ctx, "../../etc/passwd")
+	assert.ErrorIs(t, err, service.ErrUploadNotFound)
+
+	err = u.Delete(ctx, "0123456789abcdef0123456789abcdef")
+	assert.ErrorIs(t, err, service.ErrUploadNotFound)
+}
+
+func TestUploadTagNotFound(t *testing.T) {
+	u, src := newTestUpload(t, &fakeMedia{err: service.ErrTagNotFound})
+	ctx := context.Background()
+
+	upload, err := u.Create(ctx, 2, models.Media{})
+	require.NoError(t, err)
+
+	_, err = u.Append(ctx, upload.ID, 0, strings.NewReader("01"))
+	assert.ErrorIs(t, err, service.ErrTagNotFound)
+	assert.True(t, src.deleted)
+
+	// Upload can't be completed, so it's deleted.
+	_, err = u.Upload(ctx, upload.ID)
+	assert.ErrorIs(t, err, service.ErrUploadNotFound)
+}
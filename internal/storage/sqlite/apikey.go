@@ -0,0 +1,173 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+
+	"github.com/GintGld/fizteh-radio/internal/models"
+	"github.com/GintGld/fizteh-radio/internal/storage"
+)
+
+// SaveAPIKey saves api key with its permissions.
+func (s *Storage) SaveAPIKey(ctx context.Context, key models.APIKey) (int64, error) {
+	const op = "storage.sqlite.SaveAPIKey"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return 0, storage.ErrContextCancelled
+		}
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	var expires sql.NullInt64
+	if key.Expires != nil {
+		expires = sql.NullInt64{Int64: key.Expires.UnixMicro(), Valid: true}
+	}
+
+	res, err := tx.ExecContext(ctx,
+		"INSERT INTO api_key(name, prefix, key_hash, created, expires) VALUES(?, ?, ?, ?, ?)",
+		key.Name, key.Prefix, key.Hash, key.Created.UnixMicro(), expires,
+	)
+	if err != nil {
+		var sqliteErr sqlite3.Error
+		if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+			return 0, fmt.Errorf("%s: %w", op, storage.ErrAPIKeyExists)
+		}
+		if errors.Is(err, context.DeadlineExceeded) {
+			return 0, storage.ErrContextCancelled
+		}
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	for _, perm := range key.Permissions {
+		if _, err := tx.ExecContext(ctx,
+			"INSERT OR IGNORE INTO api_key_permission(key_id, permission) VALUES(?, ?)",
+			id, perm,
+		); err != nil {
+			if errors.Is(err, context.DeadlineExceeded) {
+				return 0, storage.ErrContextCancelled
+			}
+			return 0, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return 0, storage.ErrContextCancelled
+		}
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
+
+// APIKeys returns all api keys.
+func (s *Storage) APIKeys(ctx context.Context) ([]models.APIKey, error) {
+	const op = "storage.sqlite.APIKeys"
+
+	stmt, err := s.db.PrepareContext(ctx, `
+		SELECT k.id, k.name, k.prefix, k.key_hash, k.created, k.expires, p.permission
+		FROM api_key k
+		LEFT JOIN api_key_permission p ON p.key_id = k.id
+		ORDER BY k.id`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return nil, storage.ErrContextCancelled
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	keys := make([]models.APIKey, 0)
+
+	for rows.Next() {
+		var (
+			key        models.APIKey
+			createdMuS int64
+			expiresMuS sql.NullInt64
+			perm       sql.NullString
+		)
+		if err := rows.Scan(
+			&key.ID, &key.Name, &key.Prefix, &key.Hash,
+			&createdMuS, &expiresMuS, &perm,
+		); err != nil {
+			if errors.Is(err, context.DeadlineExceeded) {
+				return nil, storage.ErrContextCancelled
+			}
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		// Rows of the same key go one after another.
+		if n := len(keys); n > 0 && keys[n-1].ID == key.ID {
+			if perm.Valid {
+				keys[n-1].Permissions = append(keys[n-1].Permissions, models.Permission(perm.String))
+			}
+			continue
+		}
+
+		key.Created = time.UnixMicro(createdMuS)
+		if expiresMuS.Valid {
+			expires := time.UnixMicro(expiresMuS.Int64)
+			key.Expires = &expires
+		}
+		key.Permissions = make([]models.Permission, 0)
+		if perm.Valid {
+			key.Permissions = append(key.Permissions, models.Permission(perm.String))
+		}
+
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return keys, nil
+}
+
+// DeleteAPIKey deletes api key.
+func (s *Storage) DeleteAPIKey(ctx context.Context, id int64) error {
+	const op = "storage.sqlite.DeleteAPIKey"
+
+	stmt, err := s.db.PrepareContext(ctx, "DELETE FROM api_key WHERE id = ?")
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, id)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return storage.ErrContextCancelled
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affectedRows, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affectedRows == 0 {
+		return storage.ErrAPIKeyNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,150 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/GintGld/fizteh-radio/internal/models"
+	"github.com/GintGld/fizteh-radio/internal/storage"
+)
+
+// SaveAsRun saves as-run entry.
+// Entry for already recorded segment is ignored.
+func (s *Storage) SaveAsRun(ctx context.Context, entry models.AsRunEntry) error {
+	const op = "storage.sqlite.SaveAsRun"
+
+	stmt, err := s.db.PrepareContext(ctx, `
+		INSERT OR IGNORE INTO asrun(
+			segment_id, media_id, live_id, name, author,
+			planned_start, actual_start, duration, source
+		)
+		VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	if _, err := stmt.ExecContext(ctx,
+		entry.SegmentID,
+		entry.MediaID,
+		entry.LiveID,
+		entry.Name,
+		entry.Author,
+		entry.PlannedStart.UnixMicro(),
+		entry.ActualStart.UnixMicro(),
+		entry.Duration.Microseconds(),
+		entry.Source,
+	); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return storage.ErrContextCancelled
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// UpdateAsRunStop sets stop of as-run entry
+// for segment with given planned start.
+func (s *Storage) UpdateAsRunStop(ctx context.Context, segmentId int64, plannedStart, stop time.Time) error {
+	const op = "storage.sqlite.UpdateAsRunStop"
+
+	stmt, err := s.db.PrepareContext(ctx, `
+		UPDATE asrun
+		SET duration = ? - actual_start
+		WHERE segment_id = ? AND planned_start = ?
+	`)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	if _, err := stmt.ExecContext(ctx,
+		stop.UnixMicro(),
+		segmentId,
+		plannedStart.UnixMicro(),
+	); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return storage.ErrContextCancelled
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// AsRun returns as-run entries
+// actually started in given interval.
+func (s *Storage) AsRun(ctx context.Context, start, stop time.Time) ([]models.AsRunEntry, error) {
+	const op = "storage.sqlite.AsRun"
+
+	stmt, err := s.db.PrepareContext(ctx, `
+		SELECT
+			id, segment_id, media_id, live_id, name, author,
+			planned_start, actual_start, duration, source
+		FROM asrun
+		WHERE actual_start >= ? AND actual_start < ?
+		ORDER BY actual_start
+	`)
+	if err != nil {
+		return []models.AsRunEntry{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, start.UnixMicro(), stop.UnixMicro())
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return []models.AsRunEntry{}, storage.ErrContextCancelled
+		}
+		return []models.AsRunEntry{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	res := make([]models.AsRunEntry, 0)
+	for rows.Next() {
+		var (
+			e                             models.AsRunEntry
+			plannedMuS, actualMuS, durMuS int64
+		)
+		if err := rows.Scan(
+			&e.ID, &e.SegmentID, &e.MediaID, &e.LiveID, &e.Name, &e.Author,
+			&plannedMuS, &actualMuS, &durMuS, &e.Source,
+		); err != nil {
+			if errors.Is(err, context.DeadlineExceeded) {
+				return []models.AsRunEntry{}, storage.ErrContextCancelled
+			}
+			return []models.AsRunEntry{}, fmt.Errorf("%s: %w", op, err)
+		}
+		e.PlannedStart = time.UnixMicro(plannedMuS)
+		e.ActualStart = time.UnixMicro(actualMuS)
+		e.Duration = time.Duration(durMuS) * time.Microsecond
+		res = append(res, e)
+	}
+
+	return res, nil
+}
+
+// IsAutoDJSegment reports if segment
+// starting at given time was added by AutoDJ.
+func (s *Storage) IsAutoDJSegment(ctx context.Context, id int64, start time.Time) (bool, error) {
+	const op = "storage.sqlite.IsAutoDJSegment"
+
+	stmt, err := s.db.PrepareContext(ctx, "SELECT EXISTS (SELECT id FROM autodj_history WHERE segment_id=? AND start=?)")
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	var res bool
+	if err := stmt.QueryRowContext(ctx, id, start.UnixMicro()).Scan(&res); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return false, storage.ErrContextCancelled
+		}
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return res, nil
+}
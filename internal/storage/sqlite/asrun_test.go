@@ -0,0 +1,40 @@
+package sqlite_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/GintGld/fizteh-radio/internal/models"
+)
+
+func TestAsRunStop(t *testing.T) {
+	s := newStorage(t)
+	ctx := context.Background()
+
+	planned := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	entry := models.AsRunEntry{
+		SegmentID:    1,
+		MediaID:      2,
+		PlannedStart: planned,
+		ActualStart:  planned.Add(time.Second),
+		Duration:     time.Minute,
+		Source:       models.AsRunManual,
+	}
+	require.NoError(t, s.SaveAsRun(ctx, entry))
+
+	// Entry recorded again after restart is ignored.
+	entry.ActualStart = planned.Add(10 * time.Second)
+	require.NoError(t, s.SaveAsRun(ctx, entry))
+
+	require.NoError(t, s.UpdateAsRunStop(ctx, 1, planned, planned.Add(31*time.Second)))
+
+	entries, err := s.AsRun(ctx, planned, planned.Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.True(t, planned.Add(time.Second).Equal(entries[0].ActualStart))
+	assert.Equal(t, 30*time.Second, entries[0].Duration)
+}
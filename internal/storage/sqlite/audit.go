@@ -0,0 +1,139 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/GintGld/fizteh-radio/internal/models"
+	"github.com/GintGld/fizteh-radio/internal/storage"
+)
+
+// SaveAudit saves audit entry.
+func (s *Storage) SaveAudit(ctx context.Context, entry models.AuditEntry) (int64, error) {
+	const op = "storage.sqlite.SaveAudit"
+
+	stmt, err := s.db.PrepareContext(ctx, `
+		INSERT INTO audit(editor_id, login, action, entity, entity_id, before, after, time)
+		VALUES(?, ?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx,
+		entry.EditorID,
+		entry.Login,
+		entry.Action,
+		entry.Entity,
+		entry.EntityID,
+		nullJSON(entry.Before),
+		nullJSON(entry.After),
+		entry.Time.UnixMicro(),
+	)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return 0, storage.ErrContextCancelled
+		}
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
+
+// Audit returns audit entries
+// matching filter, latest first.
+func (s *Storage) Audit(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error) {
+	const op = "storage.sqlite.Audit"
+
+	conds := make([]string, 0)
+	args := make([]any, 0)
+
+	if !filter.Start.IsZero() {
+		conds = append(conds, "time >= ?")
+		args = append(args, filter.Start.UnixMicro())
+	}
+	if !filter.Stop.IsZero() {
+		conds = append(conds, "time < ?")
+		args = append(args, filter.Stop.UnixMicro())
+	}
+	if filter.Login != "" {
+		conds = append(conds, "login = ?")
+		args = append(args, filter.Login)
+	}
+	if filter.Entity != "" {
+		conds = append(conds, "entity = ?")
+		args = append(args, filter.Entity)
+	}
+	if filter.EntityID != 0 {
+		conds = append(conds, "entity_id = ?")
+		args = append(args, filter.EntityID)
+	}
+
+	query := "SELECT id, editor_id, login, action, entity, entity_id, before, after, time FROM audit"
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+	query += " ORDER BY time DESC, id DESC"
+	if filter.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, filter.Limit)
+	}
+
+	stmt, err := s.db.PrepareContext(ctx, query)
+	if err != nil {
+		return []models.AuditEntry{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, args...)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return []models.AuditEntry{}, storage.ErrContextCancelled
+		}
+		return []models.AuditEntry{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	res := make([]models.AuditEntry, 0)
+	for rows.Next() {
+		var (
+			e             models.AuditEntry
+			before, after sql.NullString
+			timeMuS       int64
+		)
+		if err := rows.Scan(
+			&e.ID, &e.EditorID, &e.Login, &e.Action, &e.Entity, &e.EntityID,
+			&before, &after, &timeMuS,
+		); err != nil {
+			if errors.Is(err, context.DeadlineExceeded) {
+				return []models.AuditEntry{}, storage.ErrContextCancelled
+			}
+			return []models.AuditEntry{}, fmt.Errorf("%s: %w", op, err)
+		}
+		if before.Valid {
+			e.Before = []byte(before.String)
+		}
+		if after.Valid {
+			e.After = []byte(after.String)
+		}
+		e.Time = time.UnixMicro(timeMuS)
+		res = append(res, e)
+	}
+
+	return res, nil
+}
+
+// nullJSON returns NULL for empty json.
+func nullJSON(b []byte) sql.NullString {
+	return sql.NullString{String: string(b), Valid: len(b) > 0}
+}
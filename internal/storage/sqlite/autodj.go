@@ -0,0 +1,197 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/GintGld/fizteh-radio/internal/models"
+	"github.com/GintGld/fizteh-radio/internal/storage"
+)
+
+// AutoDJConfig returns saved AutoDJ config.
+func (s *Storage) AutoDJConfig(ctx context.Context) (models.AutoDJConfig, error) {
+	const op = "storage.sqlite.AutoDJConfig"
+
+	stmt, err := s.db.PrepareContext(ctx, "SELECT config FROM autodj_config WHERE id = 1")
+	if err != nil {
+		return models.AutoDJConfig{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	var body string
+	if err := stmt.QueryRowContext(ctx).Scan(&body); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.AutoDJConfig{}, storage.ErrAutoDJConfigNotFound
+		}
+		if errors.Is(err, context.DeadlineExceeded) {
+			return models.AutoDJConfig{}, storage.ErrContextCancelled
+		}
+		return models.AutoDJConfig{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var conf models.AutoDJConfig
+	if err := json.Unmarshal([]byte(body), &conf); err != nil {
+		return models.AutoDJConfig{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return conf, nil
+}
+
+// SaveAutoDJConfig saves AutoDJ config.
+func (s *Storage) SaveAutoDJConfig(ctx context.Context, conf models.AutoDJConfig) error {
+	const op = "storage.sqlite.SaveAutoDJConfig"
+
+	body, err := json.Marshal(conf)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	stmt, err := s.db.PrepareContext(ctx, `
+		INSERT INTO autodj_config(id, config) VALUES(1, ?)
+		ON CONFLICT(id) DO UPDATE SET config=excluded.config
+	`)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	if _, err := stmt.ExecContext(ctx, string(body)); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return storage.ErrContextCancelled
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// AutoDJRotation returns saved format clock
+// position and rotation state of categories.
+func (s *Storage) AutoDJRotation(ctx context.Context) (int, []models.AutoDJRotation, error) {
+	const op = "storage.sqlite.AutoDJRotation"
+
+	var clockPos int
+	if err := s.db.QueryRowContext(ctx, "SELECT clock_pos FROM autodj_clock WHERE id = 1").Scan(&clockPos); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return 0, nil, storage.ErrContextCancelled
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return 0, nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	rows, err := s.db.QueryContext(ctx, "SELECT category, media_ids, position FROM autodj_rotation")
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return 0, nil, storage.ErrContextCancelled
+		}
+		return 0, nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	res := make([]models.AutoDJRotation, 0)
+	for rows.Next() {
+		var (
+			r   models.AutoDJRotation
+			ids string
+		)
+		if err := rows.Scan(&r.Category, &ids, &r.Position); err != nil {
+			if errors.Is(err, context.DeadlineExceeded) {
+				return 0, nil, storage.ErrContextCancelled
+			}
+			return 0, nil, fmt.Errorf("%s: %w", op, err)
+		}
+		if err := json.Unmarshal([]byte(ids), &r.MediaIDs); err != nil {
+			return 0, nil, fmt.Errorf("%s: %w", op, err)
+		}
+		res = append(res, r)
+	}
+
+	return clockPos, res, nil
+}
+
+// SaveAutoDJRotation replaces saved format clock
+// position and rotation state of categories.
+func (s *Storage) SaveAutoDJRotation(ctx context.Context, clockPos int, rotation []models.AutoDJRotation) error {
+	const op = "storage.sqlite.SaveAutoDJRotation"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return storage.ErrContextCancelled
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO autodj_clock(id, clock_pos) VALUES(1, ?)
+		ON CONFLICT(id) DO UPDATE SET clock_pos=excluded.clock_pos
+	`, clockPos); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return storage.ErrContextCancelled
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM autodj_rotation"); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return storage.ErrContextCancelled
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	for _, r := range rotation {
+		ids, err := json.Marshal(r.MediaIDs)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		if _, err := tx.ExecContext(ctx,
+			"INSERT INTO autodj_rotation(category, media_ids, position) VALUES(?, ?, ?)",
+			r.Category, string(ids), r.Position,
+		); err != nil {
+			if errors.Is(err, context.DeadlineExceeded) {
+				return storage.ErrContextCancelled
+			}
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// SaveAutoDJPlay records segment put in schedule by AutoDJ.
+func (s *Storage) SaveAutoDJPlay(ctx context.Context, play models.AutoDJPlay) (int64, error) {
+	const op = "storage.sqlite.SaveAutoDJPlay"
+
+	stmt, err := s.db.PrepareContext(ctx, `
+		INSERT INTO autodj_history(segment_id, media_id, start, category)
+		VALUES(?, ?, ?, ?)
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, play.SegmentID, play.MediaID, play.Start.UnixMicro(), play.Category)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return 0, storage.ErrContextCancelled
+		}
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
@@ -0,0 +1,126 @@
+package sqlite_test
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/sqlite3"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/GintGld/fizteh-radio/internal/models"
+	"github.com/GintGld/fizteh-radio/internal/storage"
+	"github.com/GintGld/fizteh-radio/internal/storage/sqlite"
+)
+
+// newStorage returns storage over
+// fresh database with all migrations applied.
+func newStorage(t *testing.T) *sqlite.Storage {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "radio.sqlite")
+
+	m, err := migrate.New(
+		"file://../../../migrations",
+		fmt.Sprintf("sqlite3://%s?x-migrations-table=migrations", path),
+	)
+	require.NoError(t, err)
+	require.NoError(t, m.Up())
+	srcErr, dbErr := m.Close()
+	require.NoError(t, srcErr)
+	require.NoError(t, dbErr)
+
+	s, err := sqlite.New(path)
+	require.NoError(t, err)
+	t.Cleanup(func() { s.Stop() })
+
+	return s
+}
+
+func TestAutoDJConfig(t *testing.T) {
+	s := newStorage(t)
+	ctx := context.Background()
+
+	_, err := s.AutoDJConfig(ctx)
+	assert.ErrorIs(t, err, storage.ErrAutoDJConfigNotFound)
+
+	conf := models.AutoDJConfig{
+		Tags:     models.TagList{{ID: 1, Name: "rock"}},
+		Sequence: []string{"new", "old"},
+		Stub:     models.AutoDJStub{Threshold: time.Minute, MediaID: 3},
+	}
+	require.NoError(t, s.SaveAutoDJConfig(ctx, conf))
+
+	got, err := s.AutoDJConfig(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, conf.Sequence, got.Sequence)
+	assert.Equal(t, conf.Stub, got.Stub)
+	assert.Equal(t, "rock", got.Tags[0].Name)
+
+	// Saving rotation doesn't touch config.
+	require.NoError(t, s.SaveAutoDJRotation(ctx, 1, nil))
+	got, err = s.AutoDJConfig(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, conf.Sequence, got.Sequence)
+}
+
+func TestAutoDJRotation(t *testing.T) {
+	s := newStorage(t)
+	ctx := context.Background()
+
+	clockPos, rotation, err := s.AutoDJRotation(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, clockPos)
+	assert.Empty(t, rotation)
+
+	saved := []models.AutoDJRotation{
+		{Category: "new", MediaIDs: []int64{3, 1, 2}, Position: 1},
+		{Category: "old", MediaIDs: []int64{5, 4}, Position: 2},
+	}
+	require.NoError(t, s.SaveAutoDJRotation(ctx, 3, saved))
+
+	// Rotation saved before any config
+	// doesn't hide absence of config.
+	_, err = s.AutoDJConfig(ctx)
+	assert.ErrorIs(t, err, storage.ErrAutoDJConfigNotFound)
+
+	clockPos, rotation, err = s.AutoDJRotation(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, clockPos)
+	assert.ElementsMatch(t, saved, rotation)
+
+	// Rotation is replaced entirely.
+	require.NoError(t, s.SaveAutoDJRotation(ctx, 0, saved[:1]))
+	clockPos, rotation, err = s.AutoDJRotation(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, clockPos)
+	assert.Equal(t, saved[:1], rotation)
+}
+
+func TestSaveAutoDJPlay(t *testing.T) {
+	s := newStorage(t)
+	ctx := context.Background()
+
+	start := time.UnixMicro(time.Now().UnixMicro())
+
+	_, err := s.SaveAutoDJPlay(ctx, models.AutoDJPlay{
+		SegmentID: 7,
+		MediaID:   2,
+		Start:     start,
+		Category:  "new",
+	})
+	require.NoError(t, err)
+
+	ok, err := s.IsAutoDJSegment(ctx, 7, start)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = s.IsAutoDJSegment(ctx, 7, start.Add(time.Second))
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
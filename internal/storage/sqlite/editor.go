@@ -47,7 +47,7 @@ func (s *Storage) SaveEditor(ctx context.Context, login string, passHash []byte)
 func (s *Storage) Editor(ctx context.Context, id int64) (models.Editor, error) {
 	const op = "storage.sqlite.Editor"
 
-	stmt, err := s.db.Prepare("SELECT id, login, pass_hash FROM editors WHERE id = ?")
+	stmt, err := s.db.Prepare("SELECT id, login, pass_hash, display_name, contact FROM editors WHERE id = ?")
 	if err != nil {
 		return models.Editor{}, fmt.Errorf("%s: %w", op, err)
 	}
@@ -56,7 +56,7 @@ func (s *Storage) Editor(ctx context.Context, id int64) (models.Editor, error) {
 	row := stmt.QueryRowContext(ctx, id)
 
 	var editor models.Editor
-	err = row.Scan(&editor.ID, &editor.Login, &editor.PassHash)
+	err = row.Scan(&editor.ID, &editor.Login, &editor.PassHash, &editor.DisplayName, &editor.Contact)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return models.Editor{}, fmt.Errorf("%s: %w", op, storage.ErrEditorNotFound)
@@ -74,7 +74,7 @@ func (s *Storage) Editor(ctx context.Context, id int64) (models.Editor, error) {
 func (s *Storage) EditorByLogin(ctx context.Context, login string) (models.Editor, error) {
 	const op = "storage.sqlite.EditorByLogin"
 
-	stmt, err := s.db.Prepare("SELECT id, login, pass_hash FROM editors WHERE login = ?")
+	stmt, err := s.db.Prepare("SELECT id, login, pass_hash, display_name, contact FROM editors WHERE login = ?")
 	if err != nil {
 		return models.Editor{}, fmt.Errorf("%s: %w", op, err)
 	}
@@ -83,7 +83,7 @@ func (s *Storage) EditorByLogin(ctx context.Context, login string) (models.Edito
 	row := stmt.QueryRowContext(ctx, login)
 
 	var editor models.Editor
-	err = row.Scan(&editor.ID, &editor.Login, &editor.PassHash)
+	err = row.Scan(&editor.ID, &editor.Login, &editor.PassHash, &editor.DisplayName, &editor.Contact)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return models.Editor{}, fmt.Errorf("%s: %w", op, storage.ErrEditorNotFound)
@@ -104,7 +104,7 @@ func (s *Storage) EditorByLogin(ctx context.Context, login string) (models.Edito
 func (s *Storage) AllEditors(ctx context.Context) ([]models.Editor, error) {
 	const op = "storage.sqlite.AllEditors"
 
-	stmt, err := s.db.Prepare("SELECT id, login, pass_hash FROM editors")
+	stmt, err := s.db.Prepare("SELECT id, login, pass_hash, display_name, contact FROM editors")
 	if err != nil {
 		return []models.Editor{}, fmt.Errorf("%s: %w", op, err)
 	}
@@ -119,7 +119,7 @@ func (s *Storage) AllEditors(ctx context.Context) ([]models.Editor, error) {
 	editors := make([]models.Editor, 0)
 	var editor models.Editor
 	for rows.Next() {
-		if err = rows.Scan(&editor.ID, &editor.Login, &editor.PassHash); err != nil {
+		if err = rows.Scan(&editor.ID, &editor.Login, &editor.PassHash, &editor.DisplayName, &editor.Contact); err != nil {
 			if errors.Is(err, context.DeadlineExceeded) {
 				return []models.Editor{}, storage.ErrContextCancelled
 			}
@@ -158,3 +158,138 @@ func (s *Storage) DeleteEditor(ctx context.Context, id int64) error {
 
 	return nil
 }
+
+// UpdateEditorProfile updates editor profile.
+func (s *Storage) UpdateEditorProfile(ctx context.Context, id int64, profile models.EditorProfile) error {
+	const op = "storage.sqlite.UpdateEditorProfile"
+
+	stmt, err := s.db.PrepareContext(ctx, "UPDATE editors SET display_name = ?, contact = ? WHERE id = ?")
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	return s.updateEditor(ctx, op, stmt, profile.DisplayName, profile.Contact, id)
+}
+
+// UpdateEditorPass updates editor password hash.
+func (s *Storage) UpdateEditorPass(ctx context.Context, id int64, passHash []byte) error {
+	const op = "storage.sqlite.UpdateEditorPass"
+
+	stmt, err := s.db.PrepareContext(ctx, "UPDATE editors SET pass_hash = ? WHERE id = ?")
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	return s.updateEditor(ctx, op, stmt, passHash, id)
+}
+
+// updateEditor executes update statement and
+// returns ErrEditorNotFound if nothing was updated.
+func (s *Storage) updateEditor(ctx context.Context, op string, stmt *sql.Stmt, args ...any) error {
+	res, err := stmt.ExecContext(ctx, args...)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return storage.ErrContextCancelled
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affectedRows, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affectedRows == 0 {
+		return storage.ErrEditorNotFound
+	}
+
+	return nil
+}
+
+// EditorRoles returns roles of editor.
+func (s *Storage) EditorRoles(ctx context.Context, id int64) ([]models.Role, error) {
+	const op = "storage.sqlite.EditorRoles"
+
+	stmt, err := s.db.PrepareContext(ctx, "SELECT role FROM editor_role WHERE editor_id = ? ORDER BY role")
+	if err != nil {
+		return []models.Role{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, id)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return []models.Role{}, storage.ErrContextCancelled
+		}
+		return []models.Role{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	roles := make([]models.Role, 0)
+	for rows.Next() {
+		var role models.Role
+		if err := rows.Scan(&role); err != nil {
+			if errors.Is(err, context.DeadlineExceeded) {
+				return []models.Role{}, storage.ErrContextCancelled
+			}
+			return []models.Role{}, fmt.Errorf("%s: %w", op, err)
+		}
+		roles = append(roles, role)
+	}
+
+	return roles, nil
+}
+
+// SetEditorRoles replaces roles of editor.
+func (s *Storage) SetEditorRoles(ctx context.Context, id int64, roles []models.Role) error {
+	const op = "storage.sqlite.SetEditorRoles"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return storage.ErrContextCancelled
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err := tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT id FROM editors WHERE id = ?)", id).Scan(&exists); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return storage.ErrContextCancelled
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if !exists {
+		return storage.ErrEditorNotFound
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM editor_role WHERE editor_id = ?", id); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return storage.ErrContextCancelled
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	for _, role := range roles {
+		if _, err := tx.ExecContext(ctx,
+			"INSERT OR IGNORE INTO editor_role(editor_id, role) VALUES(?, ?)",
+			id, role,
+		); err != nil {
+			if errors.Is(err, context.DeadlineExceeded) {
+				return storage.ErrContextCancelled
+			}
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return storage.ErrContextCancelled
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
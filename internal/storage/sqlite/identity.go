@@ -0,0 +1,124 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/mattn/go-sqlite3"
+
+	"github.com/GintGld/fizteh-radio/internal/models"
+	"github.com/GintGld/fizteh-radio/internal/storage"
+)
+
+// EditorIdentity returns id of the editor
+// linked to external identity.
+func (s *Storage) EditorIdentity(ctx context.Context, issuer, subject string) (int64, error) {
+	const op = "storage.sqlite.EditorIdentity"
+
+	stmt, err := s.db.PrepareContext(ctx, "SELECT editor_id FROM editor_identity WHERE issuer = ? AND subject = ?")
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	var id int64
+	if err := stmt.QueryRowContext(ctx, issuer, subject).Scan(&id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, storage.ErrIdentityNotFound
+		}
+		if errors.Is(err, context.DeadlineExceeded) {
+			return 0, storage.ErrContextCancelled
+		}
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
+
+// SaveEditorIdentity links external identity to editor.
+func (s *Storage) SaveEditorIdentity(ctx context.Context, issuer, subject string, editorId int64) error {
+	const op = "storage.sqlite.SaveEditorIdentity"
+
+	stmt, err := s.db.PrepareContext(ctx, "INSERT OR REPLACE INTO editor_identity(issuer, subject, editor_id) VALUES(?, ?, ?)")
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	if _, err := stmt.ExecContext(ctx, issuer, subject, editorId); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return storage.ErrContextCancelled
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// ProvisionEditor creates editor with given
+// roles and links external identity to it.
+func (s *Storage) ProvisionEditor(ctx context.Context, editor models.Editor, issuer, subject string) (int64, error) {
+	const op = "storage.sqlite.ProvisionEditor"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return models.ErrEditorID, storage.ErrContextCancelled
+		}
+		return models.ErrEditorID, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx,
+		"INSERT INTO editors(login, pass_hash, display_name, contact) VALUES(?, ?, ?, ?)",
+		editor.Login, editor.PassHash, editor.DisplayName, editor.Contact,
+	)
+	if err != nil {
+		var sqliteErr sqlite3.Error
+		if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+			return models.ErrEditorID, fmt.Errorf("%s: %w", op, storage.ErrEditorExists)
+		}
+		if errors.Is(err, context.DeadlineExceeded) {
+			return models.ErrEditorID, storage.ErrContextCancelled
+		}
+		return models.ErrEditorID, fmt.Errorf("%s: %w", op, err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return models.ErrEditorID, fmt.Errorf("%s: %w", op, err)
+	}
+
+	for _, role := range editor.Roles {
+		if _, err := tx.ExecContext(ctx,
+			"INSERT OR IGNORE INTO editor_role(editor_id, role) VALUES(?, ?)",
+			id, role,
+		); err != nil {
+			if errors.Is(err, context.DeadlineExceeded) {
+				return models.ErrEditorID, storage.ErrContextCancelled
+			}
+			return models.ErrEditorID, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		"INSERT OR REPLACE INTO editor_identity(issuer, subject, editor_id) VALUES(?, ?, ?)",
+		issuer, subject, id,
+	); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return models.ErrEditorID, storage.ErrContextCancelled
+		}
+		return models.ErrEditorID, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return models.ErrEditorID, storage.ErrContextCancelled
+		}
+		return models.ErrEditorID, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
@@ -0,0 +1,118 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/GintGld/fizteh-radio/internal/models"
+	"github.com/GintGld/fizteh-radio/internal/storage"
+)
+
+// SaveIncident saves incident
+// and returns its id.
+func (s *Storage) SaveIncident(ctx context.Context, incident models.Incident) (int64, error) {
+	const op = "storage.sqlite.SaveIncident"
+
+	stmt, err := s.db.PrepareContext(ctx, `
+		INSERT INTO incident(type, detected, start, stop, resolution)
+		VALUES(?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx,
+		incident.Type,
+		incident.Detected.UnixMicro(),
+		incident.Start.UnixMicro(),
+		incident.Stop.UnixMicro(),
+		incident.Resolution,
+	)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return 0, storage.ErrContextCancelled
+		}
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
+
+// UpdateIncident updates stop
+// and resolution of the incident.
+func (s *Storage) UpdateIncident(ctx context.Context, incident models.Incident) error {
+	const op = "storage.sqlite.UpdateIncident"
+
+	stmt, err := s.db.PrepareContext(ctx, "UPDATE incident SET stop=?, resolution=? WHERE id=?")
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	if _, err := stmt.ExecContext(ctx,
+		incident.Stop.UnixMicro(),
+		incident.Resolution,
+		incident.ID,
+	); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return storage.ErrContextCancelled
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Incidents returns incidents
+// started in given interval.
+func (s *Storage) Incidents(ctx context.Context, start, stop time.Time) ([]models.Incident, error) {
+	const op = "storage.sqlite.Incidents"
+
+	stmt, err := s.db.PrepareContext(ctx, `
+		SELECT id, type, detected, start, stop, resolution
+		FROM incident
+		WHERE start >= ? AND start < ?
+		ORDER BY start
+	`)
+	if err != nil {
+		return []models.Incident{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, start.UnixMicro(), stop.UnixMicro())
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return []models.Incident{}, storage.ErrContextCancelled
+		}
+		return []models.Incident{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	res := make([]models.Incident, 0)
+	for rows.Next() {
+		var (
+			i                              models.Incident
+			detectedMuS, startMuS, stopMuS int64
+		)
+		if err := rows.Scan(&i.ID, &i.Type, &detectedMuS, &startMuS, &stopMuS, &i.Resolution); err != nil {
+			if errors.Is(err, context.DeadlineExceeded) {
+				return []models.Incident{}, storage.ErrContextCancelled
+			}
+			return []models.Incident{}, fmt.Errorf("%s: %w", op, err)
+		}
+		i.Detected = time.UnixMicro(detectedMuS)
+		i.Start = time.UnixMicro(startMuS)
+		i.Stop = time.UnixMicro(stopMuS)
+		res = append(res, i)
+	}
+
+	return res, nil
+}
@@ -0,0 +1,82 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/GintGld/fizteh-radio/internal/models"
+	"github.com/GintGld/fizteh-radio/internal/storage"
+)
+
+// SaveLoginAttempt saves login attempt.
+func (s *Storage) SaveLoginAttempt(ctx context.Context, attempt models.LoginAttempt) error {
+	const op = "storage.sqlite.SaveLoginAttempt"
+
+	stmt, err := s.db.PrepareContext(ctx, "INSERT INTO login_audit(login, ip, success, reason, time) VALUES(?, ?, ?, ?, ?)")
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	if _, err := stmt.ExecContext(ctx,
+		attempt.Login,
+		attempt.IP,
+		attempt.Success,
+		attempt.Reason,
+		attempt.Time.UnixMicro(),
+	); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return storage.ErrContextCancelled
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// LoginAttempts returns login attempts made in
+// given interval, latest first. If login is not
+// empty, only attempts with this login are returned.
+func (s *Storage) LoginAttempts(ctx context.Context, start, stop time.Time, login string) ([]models.LoginAttempt, error) {
+	const op = "storage.sqlite.LoginAttempts"
+
+	stmt, err := s.db.PrepareContext(ctx, `
+		SELECT id, login, ip, success, reason, time
+		FROM login_audit
+		WHERE time >= ? AND time < ? AND (? = '' OR login = ?)
+		ORDER BY time DESC
+	`)
+	if err != nil {
+		return []models.LoginAttempt{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, start.UnixMicro(), stop.UnixMicro(), login, login)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return []models.LoginAttempt{}, storage.ErrContextCancelled
+		}
+		return []models.LoginAttempt{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	res := make([]models.LoginAttempt, 0)
+	for rows.Next() {
+		var (
+			a       models.LoginAttempt
+			timeMuS int64
+		)
+		if err := rows.Scan(&a.ID, &a.Login, &a.IP, &a.Success, &a.Reason, &timeMuS); err != nil {
+			if errors.Is(err, context.DeadlineExceeded) {
+				return []models.LoginAttempt{}, storage.ErrContextCancelled
+			}
+			return []models.LoginAttempt{}, fmt.Errorf("%s: %w", op, err)
+		}
+		a.Time = time.UnixMicro(timeMuS)
+		res = append(res, a)
+	}
+
+	return res, nil
+}
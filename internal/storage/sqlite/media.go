@@ -22,7 +22,7 @@ func (s *Storage) AllMedia(ctx context.Context, limit, offset int) ([]models.Med
 	const op = "storage.sqlite.MediaSearch"
 
 	stmt, err := s.db.PrepareContext(ctx, `
-		SELECT id, name, author, duration, source_id
+		SELECT id, name, author, duration, source_id, checksum, playable
 		FROM library
 		LIMIT ? OFFSET ?
 	`)
@@ -46,10 +46,12 @@ func (s *Storage) AllMedia(ctx context.Context, limit, offset int) ([]models.Med
 		id, sourceID int64
 		name, author string
 		durationMs   int64
+		checksum     string
+		playable     bool
 	)
 
 	for rows.Next() {
-		if err = rows.Scan(&id, &name, &author, &durationMs, &sourceID); err != nil {
+		if err = rows.Scan(&id, &name, &author, &durationMs, &sourceID, &checksum, &playable); err != nil {
 			if errors.Is(err, context.DeadlineExceeded) {
 				return nil, storage.ErrContextCancelled
 			}
@@ -62,6 +64,8 @@ func (s *Storage) AllMedia(ctx context.Context, limit, offset int) ([]models.Med
 			Name:     ptr.Ptr(name),
 			Author:   ptr.Ptr(author),
 			Duration: ptr.Ptr(time.Duration(durationMs) * time.Microsecond),
+			Checksum: ptr.Ptr(checksum),
+			Playable: ptr.Ptr(playable),
 		})
 	}
 
@@ -73,13 +77,20 @@ func (s *Storage) AllMedia(ctx context.Context, limit, offset int) ([]models.Med
 func (s *Storage) SaveMedia(ctx context.Context, media models.Media) (int64, error) {
 	const op = "storage.sqlite.SaveMedia"
 
-	stmt, err := s.db.PrepareContext(ctx, "INSERT INTO library(name, author, duration, source_id) VALUES(?, ?, ?, ?)")
+	stmt, err := s.db.PrepareContext(ctx, "INSERT INTO library(name, author, duration, source_id, checksum) VALUES(?, ?, ?, ?, ?)")
 	if err != nil {
 		return 0, fmt.Errorf("%s: %w", op, err)
 	}
 	defer stmt.Close()
 
-	res, err := stmt.ExecContext(ctx, *media.Name, *media.Author, media.Duration.Microseconds(), *media.SourceID)
+	// Checksum of older sources is
+	// computed by reconciliation.
+	var checksum string
+	if media.Checksum != nil {
+		checksum = *media.Checksum
+	}
+
+	res, err := stmt.ExecContext(ctx, *media.Name, *media.Author, media.Duration.Microseconds(), *media.SourceID, checksum)
 	if err != nil {
 		var sqliteErr sqlite3.Error
 		if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
@@ -160,7 +171,7 @@ func (s *Storage) Media(ctx context.Context, id int64) (models.Media, error) {
 func (s *Storage) mediaSubBasicInfo(ctx context.Context, id int64) (models.Media, error) {
 	const op = "storage.sqlite.mediaSubBasicInfo"
 
-	stmt, err := s.db.PrepareContext(ctx, "SELECT name, author, duration, source_id FROM library WHERE id = ?")
+	stmt, err := s.db.PrepareContext(ctx, "SELECT name, author, duration, source_id, checksum, playable FROM library WHERE id = ?")
 	if err != nil {
 		return models.Media{}, fmt.Errorf("%s: %w", op, err)
 	}
@@ -172,9 +183,11 @@ func (s *Storage) mediaSubBasicInfo(ctx context.Context, id int64) (models.Media
 		sourceID     int64
 		name, author string
 		durationMuS  int64
+		checksum     string
+		playable     bool
 	)
 
-	err = row.Scan(&name, &author, &durationMuS, &sourceID)
+	err = row.Scan(&name, &author, &durationMuS, &sourceID, &checksum, &playable)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return models.Media{}, fmt.Errorf("%s: %w", op, storage.ErrMediaNotFound)
@@ -191,6 +204,8 @@ func (s *Storage) mediaSubBasicInfo(ctx context.Context, id int64) (models.Media
 		Name:     &name,
 		Author:   &author,
 		Duration: ptr.Ptr(time.Duration(durationMuS) * time.Microsecond),
+		Checksum: &checksum,
+		Playable: &playable,
 	}, nil
 }
 
@@ -277,6 +292,35 @@ func (s *Storage) MediaTags(ctx context.Context, id int64) (models.TagList, erro
 	return tags, nil
 }
 
+// SetSourceState saves checksum of media source
+// and marks media playable or not.
+func (s *Storage) SetSourceState(ctx context.Context, id int64, checksum string, playable bool) error {
+	const op = "storage.sqlite.SetSourceState"
+
+	stmt, err := s.db.PrepareContext(ctx, "UPDATE library SET checksum = ?, playable = ? WHERE id = ?")
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, checksum, playable, id)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return storage.ErrContextCancelled
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	affectedRows, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affectedRows == 0 {
+		return storage.ErrMediaNotFound
+	}
+
+	return nil
+}
+
 // DeleteMedia deletes media by id.
 func (s *Storage) DeleteMedia(ctx context.Context, id int64) error {
 	const op = "storage.sqlite.DeleteMedia"
@@ -9,6 +9,7 @@ import (
 
 	"github.com/mattn/go-sqlite3"
 
+	"github.com/GintGld/fizteh-radio/internal/lib/caller"
 	ptr "github.com/GintGld/fizteh-radio/internal/lib/utils/pointers"
 	"github.com/GintGld/fizteh-radio/internal/models"
 	"github.com/GintGld/fizteh-radio/internal/storage"
@@ -25,10 +26,10 @@ func (s *Storage) ScheduleCut(ctx context.Context, start time.Time, stop time.Ti
 
 	// Select segments intersecting diaposon [start, stop]
 	stmt, err := s.db.Prepare(`
-		SELECT id, media_id, start_mus, begin_cut, stop_cut 
+		SELECT id, media_id, start_mus, begin_cut, stop_cut, tempo, fade_out, created_by
 		FROM schedule
 		WHERE (
-			start_mus + (stop_cut - begin_cut) > ?
+			start_mus + (stop_cut - begin_cut) / tempo > ?
 			AND
 			start_mus < ?
 		)
@@ -52,10 +53,12 @@ func (s *Storage) ScheduleCut(ctx context.Context, start time.Time, stop time.Ti
 	var (
 		segment models.Segment
 		id, mediaID, startMs,
-		beginMuS, stopMuS int64
+		beginMuS, stopMuS, createdBy int64
+		tempo   float64
+		fadeOut bool
 	)
 	for rows.Next() {
-		if err = rows.Scan(&id, &mediaID, &startMs, &beginMuS, &stopMuS); err != nil {
+		if err = rows.Scan(&id, &mediaID, &startMs, &beginMuS, &stopMuS, &tempo, &fadeOut, &createdBy); err != nil {
 			if errors.Is(err, context.DeadlineExceeded) {
 				return []models.Segment{}, storage.ErrContextCancelled
 			}
@@ -66,6 +69,9 @@ func (s *Storage) ScheduleCut(ctx context.Context, start time.Time, stop time.Ti
 		segment.Start = ptr.Ptr(time.Unix(startMs/1000000, startMs%1000000*1000))
 		segment.BeginCut = ptr.Ptr(time.Duration(beginMuS) * time.Microsecond)
 		segment.StopCut = ptr.Ptr(time.Duration(stopMuS) * time.Microsecond)
+		segment.Tempo = scanTempo(tempo)
+		segment.FadeOut = fadeOut
+		segment.CreatedBy = createdBy
 
 		segments = append(segments, segment)
 
@@ -75,6 +81,7 @@ func (s *Storage) ScheduleCut(ctx context.Context, start time.Time, stop time.Ti
 }
 
 // SaveSegment saves segment to schedule.
+// Segment is owned by the caller from context.
 func (s *Storage) SaveSegment(ctx context.Context, segment models.Segment) (int64, error) {
 	const op = "storage.sqlite.SaveSegment"
 
@@ -91,7 +98,7 @@ func (s *Storage) SaveSegment(ctx context.Context, segment models.Segment) (int6
 		return 0, fmt.Errorf("%s: stop cut is not defined", op)
 	}
 
-	stmt, err := s.db.Prepare("INSERT INTO schedule(media_id, start_mus, begin_cut, stop_cut) VALUES(?, ?, ?, ?)")
+	stmt, err := s.db.Prepare("INSERT INTO schedule(media_id, start_mus, begin_cut, stop_cut, tempo, fade_out, created_by) VALUES(?, ?, ?, ?, ?, ?, ?)")
 	if err != nil {
 		return 0, fmt.Errorf("%s: %w", op, err)
 	}
@@ -103,6 +110,9 @@ func (s *Storage) SaveSegment(ctx context.Context, segment models.Segment) (int6
 		segment.Start.UnixMicro(),
 		segment.BeginCut.Microseconds(),
 		segment.StopCut.Microseconds(),
+		segment.Speed(),
+		segment.FadeOut,
+		caller.ID(ctx),
 	)
 	if err != nil {
 		var sqliteErr sqlite3.Error
@@ -128,19 +138,21 @@ func (s *Storage) SaveSegment(ctx context.Context, segment models.Segment) (int6
 func (s *Storage) Segment(ctx context.Context, id int64) (models.Segment, error) {
 	const op = "storage.sqlite.Segment"
 
-	stmt, err := s.db.Prepare("SELECT media_id, start_mus, begin_cut, stop_cut FROM schedule WHERE id = ?")
+	stmt, err := s.db.Prepare("SELECT media_id, start_mus, begin_cut, stop_cut, tempo, fade_out, created_by FROM schedule WHERE id = ?")
 	if err != nil {
 		return models.Segment{}, fmt.Errorf("%s: %w", op, err)
 	}
 	defer stmt.Close()
 
 	var (
-		segment                          models.Segment
-		mediaID, msec, beginMuS, stopMuS int64
+		segment                                     models.Segment
+		mediaID, msec, beginMuS, stopMuS, createdBy int64
+		tempo                                       float64
+		fadeOut                                     bool
 	)
 
 	row := stmt.QueryRowContext(ctx, id)
-	err = row.Scan(&mediaID, &msec, &beginMuS, &stopMuS)
+	err = row.Scan(&mediaID, &msec, &beginMuS, &stopMuS, &tempo, &fadeOut, &createdBy)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return models.Segment{}, fmt.Errorf("%s: %w", op, storage.ErrSegmentNotFound)
@@ -157,16 +169,28 @@ func (s *Storage) Segment(ctx context.Context, id int64) (models.Segment, error)
 	segment.Start = ptr.Ptr(time.Unix(msec/1000000, msec%1000000*1000))
 	segment.BeginCut = ptr.Ptr(time.Duration(beginMuS * 1000))
 	segment.StopCut = ptr.Ptr(time.Duration(stopMuS * 1000))
+	segment.Tempo = scanTempo(tempo)
+	segment.FadeOut = fadeOut
+	segment.CreatedBy = createdBy
 
 	return segment, nil
 }
 
+// scanTempo converts stored tempo
+// to segment one (0 for normal speed).
+func scanTempo(tempo float64) float64 {
+	if tempo == 1 {
+		return 0
+	}
+	return tempo
+}
+
 // UpdateSegmentTiming updates
 // all fields referred to time.
 func (s *Storage) UpdateSegmenTiming(ctx context.Context, segment models.Segment) error {
 	const op = "Storage.UpdateSegmentiming"
 
-	stmt, err := s.db.Prepare("UPDATE schedule SET start_mus=?, begin_cut=?, stop_cut=? WHERE id=?")
+	stmt, err := s.db.Prepare("UPDATE schedule SET start_mus=?, begin_cut=?, stop_cut=?, tempo=?, fade_out=? WHERE id=?")
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
@@ -176,6 +200,8 @@ func (s *Storage) UpdateSegmenTiming(ctx context.Context, segment models.Segment
 		segment.Start.UnixMicro(),
 		segment.BeginCut.Microseconds(),
 		segment.StopCut.Microseconds(),
+		segment.Speed(),
+		segment.FadeOut,
 		*segment.ID,
 	); err != nil {
 		if errors.Is(err, context.DeadlineExceeded) {
@@ -422,7 +448,7 @@ func (s *Storage) ClearSchedule(ctx context.Context, from time.Time) error {
 
 	stmt, err := s.db.Prepare(`
 		DELETE FROM schedule
-		WHERE start_mus + (stop_cut - begin_cut) >= ?
+		WHERE start_mus + (stop_cut - begin_cut) / tempo >= ?
 		AND
 		NOT EXISTS (SELECT * FROM schedule_protect WHERE schedule_protect.segment_id = schedule.id)
 	`)
@@ -0,0 +1,43 @@
+package sqlite_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/GintGld/fizteh-radio/internal/lib/caller"
+	ptr "github.com/GintGld/fizteh-radio/internal/lib/utils/pointers"
+	"github.com/GintGld/fizteh-radio/internal/models"
+)
+
+func TestSegmentOwner(t *testing.T) {
+	s := newStorage(t)
+	ctx := context.Background()
+
+	segment := models.Segment{
+		MediaID:  ptr.Ptr[int64](1),
+		Start:    ptr.Ptr(time.Now()),
+		BeginCut: ptr.Ptr[time.Duration](0),
+		StopCut:  ptr.Ptr(time.Minute),
+	}
+
+	// Created by editor.
+	id, err := s.SaveSegment(caller.WithEditor(ctx, 3, "editor"), segment)
+	require.NoError(t, err)
+
+	saved, err := s.Segment(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), saved.CreatedBy)
+
+	// Created by system.
+	segment.Start = ptr.Ptr(segment.Start.Add(time.Hour))
+	id, err = s.SaveSegment(ctx, segment)
+	require.NoError(t, err)
+
+	saved, err = s.Segment(ctx, id)
+	require.NoError(t, err)
+	assert.Zero(t, saved.CreatedBy)
+}
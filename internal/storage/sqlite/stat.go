@@ -38,7 +38,7 @@ func (s *Storage) SaveListener(ctx context.Context, listener models.Listener) (i
 func (s *Storage) Listeners(ctx context.Context, start, stop time.Time) ([]models.Listener, error) {
 	const op = "storage.sqlite.Listeners"
 
-	stmt, err := s.db.PrepareContext(ctx, "SELECT id, start, stop FROM listener WHERE stop >= ? OR start <= ?")
+	stmt, err := s.db.PrepareContext(ctx, "SELECT id, start, stop FROM listener WHERE stop >= ? AND start <= ? ORDER BY start")
 	if err != nil {
 		return []models.Listener{}, fmt.Errorf("%s: %w", op, err)
 	}
@@ -0,0 +1,42 @@
+package sqlite_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/GintGld/fizteh-radio/internal/models"
+)
+
+func TestListeners(t *testing.T) {
+	s := newStorage(t)
+	ctx := context.Background()
+
+	base := time.Unix(time.Now().Unix(), 0)
+	listener := func(from, to time.Duration) models.Listener {
+		return models.Listener{Start: base.Add(from), Stop: base.Add(to)}
+	}
+
+	for _, l := range []models.Listener{
+		listener(2*time.Hour, 3*time.Hour),
+		listener(0, time.Hour),
+		listener(30*time.Minute, 2*time.Hour),
+		listener(-2*time.Hour, -time.Hour),
+		listener(4*time.Hour, 5*time.Hour),
+	} {
+		_, err := s.SaveListener(ctx, l)
+		require.NoError(t, err)
+	}
+
+	res, err := s.Listeners(ctx, base.Add(30*time.Minute), base.Add(150*time.Minute))
+	require.NoError(t, err)
+
+	// Only intersecting listeners, sorted by start.
+	require.Len(t, res, 3)
+	assert.Equal(t, base, res[0].Start)
+	assert.Equal(t, base.Add(30*time.Minute), res[1].Start)
+	assert.Equal(t, base.Add(2*time.Hour), res[2].Start)
+}
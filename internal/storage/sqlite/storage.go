@@ -3,10 +3,12 @@ package sqlite
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"sync"
 
 	"github.com/GintGld/fizteh-radio/internal/models"
+	"github.com/GintGld/fizteh-radio/internal/storage"
 )
 
 type Storage struct {
@@ -57,6 +59,21 @@ func (s *Storage) recoverCaches() error {
 	return nil
 }
 
+// Ping checks that database file is readable.
+func (s *Storage) Ping(ctx context.Context) error {
+	const op = "storage.sqlite.Ping"
+
+	var n int
+	if err := s.db.QueryRowContext(ctx, "SELECT count(*) FROM sqlite_master").Scan(&n); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return storage.ErrContextCancelled
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
 func (s *Storage) Stop() error {
 	return s.db.Close()
 }
@@ -0,0 +1,240 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/GintGld/fizteh-radio/internal/models"
+	"github.com/GintGld/fizteh-radio/internal/storage"
+)
+
+// SaveRefreshToken saves refresh token.
+// Expired tokens are removed.
+func (s *Storage) SaveRefreshToken(ctx context.Context, token models.RefreshToken) error {
+	const op = "storage.sqlite.SaveRefreshToken"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return storage.ErrContextCancelled
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM refresh_token WHERE expires < ?", time.Now().UnixMicro()); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return storage.ErrContextCancelled
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		"INSERT INTO refresh_token(token_hash, family, editor_id, expires, used) VALUES(?, ?, ?, ?, ?)",
+		token.Hash, token.Family, token.EditorID, token.Expires.UnixMicro(), token.Used,
+	); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return storage.ErrContextCancelled
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return storage.ErrContextCancelled
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// RefreshToken returns refresh token by its hash.
+func (s *Storage) RefreshToken(ctx context.Context, hash string) (models.RefreshToken, error) {
+	const op = "storage.sqlite.RefreshToken"
+
+	stmt, err := s.db.PrepareContext(ctx, "SELECT token_hash, family, editor_id, expires, used FROM refresh_token WHERE token_hash = ?")
+	if err != nil {
+		return models.RefreshToken{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	var (
+		token      models.RefreshToken
+		expiresMuS int64
+	)
+	if err := stmt.QueryRowContext(ctx, hash).Scan(
+		&token.Hash, &token.Family, &token.EditorID, &expiresMuS, &token.Used,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.RefreshToken{}, storage.ErrRefreshTokenNotFound
+		}
+		if errors.Is(err, context.DeadlineExceeded) {
+			return models.RefreshToken{}, storage.ErrContextCancelled
+		}
+		return models.RefreshToken{}, fmt.Errorf("%s: %w", op, err)
+	}
+	token.Expires = time.UnixMicro(expiresMuS)
+
+	return token, nil
+}
+
+// UseRefreshToken marks refresh token as used.
+// Reports false if token was already used.
+func (s *Storage) UseRefreshToken(ctx context.Context, hash string) (bool, error) {
+	const op = "storage.sqlite.UseRefreshToken"
+
+	stmt, err := s.db.PrepareContext(ctx, "UPDATE refresh_token SET used = 1 WHERE token_hash = ? AND used = 0")
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, hash)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return false, storage.ErrContextCancelled
+		}
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	affectedRows, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return affectedRows == 1, nil
+}
+
+// DeleteRefreshFamily deletes all refresh
+// tokens of the given family.
+func (s *Storage) DeleteRefreshFamily(ctx context.Context, family string) error {
+	const op = "storage.sqlite.DeleteRefreshFamily"
+
+	stmt, err := s.db.PrepareContext(ctx, "DELETE FROM refresh_token WHERE family = ?")
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	if _, err := stmt.ExecContext(ctx, family); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return storage.ErrContextCancelled
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// DeleteEditorRefreshTokens deletes
+// all refresh tokens of the editor.
+func (s *Storage) DeleteEditorRefreshTokens(ctx context.Context, editorId int64) error {
+	const op = "storage.sqlite.DeleteEditorRefreshTokens"
+
+	stmt, err := s.db.PrepareContext(ctx, "DELETE FROM refresh_token WHERE editor_id = ?")
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	if _, err := stmt.ExecContext(ctx, editorId); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return storage.ErrContextCancelled
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// SaveRevocation saves revocation.
+// Expired revocations are removed.
+func (s *Storage) SaveRevocation(ctx context.Context, rev models.Revocation) error {
+	const op = "storage.sqlite.SaveRevocation"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return storage.ErrContextCancelled
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM revocation WHERE expires < ?", time.Now().UnixMicro()); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return storage.ErrContextCancelled
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	var beforeMuS int64
+	if !rev.Before.IsZero() {
+		beforeMuS = rev.Before.UnixMicro()
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		"INSERT INTO revocation(token_id, editor_id, issued_before, expires) VALUES(?, ?, ?, ?)",
+		rev.TokenID, rev.EditorID, beforeMuS, rev.Expires.UnixMicro(),
+	); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return storage.ErrContextCancelled
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return storage.ErrContextCancelled
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Revocations returns revocations
+// not expired at given time.
+func (s *Storage) Revocations(ctx context.Context, now time.Time) ([]models.Revocation, error) {
+	const op = "storage.sqlite.Revocations"
+
+	stmt, err := s.db.PrepareContext(ctx, "SELECT token_id, editor_id, issued_before, expires FROM revocation WHERE expires >= ?")
+	if err != nil {
+		return []models.Revocation{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, now.UnixMicro())
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return []models.Revocation{}, storage.ErrContextCancelled
+		}
+		return []models.Revocation{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	res := make([]models.Revocation, 0)
+	for rows.Next() {
+		var (
+			rev                   models.Revocation
+			beforeMuS, expiresMuS int64
+		)
+		if err := rows.Scan(&rev.TokenID, &rev.EditorID, &beforeMuS, &expiresMuS); err != nil {
+			if errors.Is(err, context.DeadlineExceeded) {
+				return []models.Revocation{}, storage.ErrContextCancelled
+			}
+			return []models.Revocation{}, fmt.Errorf("%s: %w", op, err)
+		}
+		if beforeMuS != 0 {
+			rev.Before = time.UnixMicro(beforeMuS)
+		}
+		rev.Expires = time.UnixMicro(expiresMuS)
+		res = append(res, rev)
+	}
+
+	return res, nil
+}
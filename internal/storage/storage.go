@@ -16,5 +16,14 @@ var (
 	ErrSegmentAlreadyProtected      = errors.New("segment already protected")
 	ErrSegmentAlreadyAttachedToLive = errors.New("segment already attach to live")
 
+	ErrAutoDJConfigNotFound = errors.New("autodj config not found")
+
+	ErrRefreshTokenNotFound = errors.New("refresh token not found")
+
+	ErrIdentityNotFound = errors.New("identity not found")
+
+	ErrAPIKeyExists   = errors.New("api key exists")
+	ErrAPIKeyNotFound = errors.New("api key not found")
+
 	ErrContextCancelled = errors.New("context cancelled")
 )
@@ -0,0 +1 @@
+ALTER TABLE schedule ADD COLUMN tempo REAL NOT NULL DEFAULT 1;
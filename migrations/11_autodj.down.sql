@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS autodj_config;
+DROP TABLE IF EXISTS autodj_rotation;
+DROP TABLE IF EXISTS autodj_history;
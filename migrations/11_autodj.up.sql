@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS autodj_config (
+    id INTEGER PRIMARY KEY CHECK (id = 1),
+    config TEXT NOT NULL,
+    clock_pos INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS autodj_rotation (
+    category TEXT PRIMARY KEY,
+    media_ids TEXT NOT NULL,
+    position INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS autodj_history (
+    id INTEGER PRIMARY KEY,
+    segment_id INTEGER NOT NULL,
+    media_id INTEGER NOT NULL,
+    start INTEGER NOT NULL,
+    category TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_autodj_history_segment ON autodj_history (segment_id);
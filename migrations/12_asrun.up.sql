@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS asrun (
+    id INTEGER PRIMARY KEY,
+    segment_id INTEGER NOT NULL,
+    media_id INTEGER NOT NULL,
+    live_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    author TEXT NOT NULL,
+    planned_start INTEGER NOT NULL,
+    actual_start INTEGER NOT NULL,
+    duration INTEGER NOT NULL,
+    source TEXT NOT NULL,
+    UNIQUE (segment_id, planned_start)
+);
+
+CREATE INDEX IF NOT EXISTS idx_asrun_actual_start ON asrun (actual_start);
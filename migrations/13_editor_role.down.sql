@@ -0,0 +1,2 @@
+DROP TRIGGER IF EXISTS editor_role_cleanup;
+DROP TABLE IF EXISTS editor_role;
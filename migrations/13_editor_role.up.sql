@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS editor_role (
+    editor_id INTEGER NOT NULL,
+    role TEXT NOT NULL,
+    PRIMARY KEY (editor_id, role)
+);
+
+INSERT INTO editor_role (editor_id, role)
+SELECT id, r.role FROM editors
+CROSS JOIN (SELECT 'librarian' AS role UNION SELECT 'scheduler' UNION SELECT 'live_host') r;
+
+CREATE TRIGGER IF NOT EXISTS editor_role_cleanup AFTER DELETE ON editors
+BEGIN
+    DELETE FROM editor_role WHERE editor_id = OLD.id;
+END;
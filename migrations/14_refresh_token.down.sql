@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS refresh_token_cleanup;
+DROP TABLE IF EXISTS revocation;
+DROP TABLE IF EXISTS refresh_token;
@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS refresh_token (
+    id INTEGER PRIMARY KEY,
+    token_hash TEXT NOT NULL UNIQUE,
+    family TEXT NOT NULL,
+    editor_id INTEGER NOT NULL,
+    expires INTEGER NOT NULL,
+    used INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_refresh_token_family ON refresh_token (family);
+
+CREATE TABLE IF NOT EXISTS revocation (
+    id INTEGER PRIMARY KEY,
+    token_id TEXT NOT NULL DEFAULT '',
+    editor_id INTEGER NOT NULL DEFAULT 0,
+    issued_before INTEGER NOT NULL DEFAULT 0,
+    expires INTEGER NOT NULL
+);
+
+CREATE TRIGGER IF NOT EXISTS refresh_token_cleanup AFTER DELETE ON editors
+BEGIN
+    DELETE FROM refresh_token WHERE editor_id = OLD.id;
+END;
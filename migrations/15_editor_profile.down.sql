@@ -0,0 +1,2 @@
+ALTER TABLE editors DROP COLUMN contact;
+ALTER TABLE editors DROP COLUMN display_name;
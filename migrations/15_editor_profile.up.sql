@@ -0,0 +1,2 @@
+ALTER TABLE editors ADD COLUMN display_name TEXT NOT NULL DEFAULT '';
+ALTER TABLE editors ADD COLUMN contact TEXT NOT NULL DEFAULT '';
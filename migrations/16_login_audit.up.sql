@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS login_audit (
+    id INTEGER PRIMARY KEY,
+    login TEXT NOT NULL,
+    ip TEXT NOT NULL,
+    success INTEGER NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    time INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_audit_time ON login_audit (time);
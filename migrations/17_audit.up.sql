@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS audit (
+    id INTEGER PRIMARY KEY,
+    editor_id INTEGER NOT NULL,
+    login TEXT NOT NULL,
+    action TEXT NOT NULL,
+    entity TEXT NOT NULL,
+    entity_id INTEGER NOT NULL DEFAULT 0,
+    before TEXT,
+    after TEXT,
+    time INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_time ON audit (time);
+CREATE INDEX IF NOT EXISTS idx_audit_entity ON audit (entity, entity_id);
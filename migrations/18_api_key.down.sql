@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS api_key_permission_cleanup;
+DROP TABLE IF EXISTS api_key_permission;
+DROP TABLE IF EXISTS api_key;
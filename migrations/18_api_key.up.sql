@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS api_key (
+    id INTEGER PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE,
+    prefix TEXT NOT NULL,
+    key_hash TEXT NOT NULL UNIQUE,
+    created INTEGER NOT NULL,
+    expires INTEGER
+);
+
+CREATE TABLE IF NOT EXISTS api_key_permission (
+    key_id INTEGER NOT NULL,
+    permission TEXT NOT NULL,
+    PRIMARY KEY (key_id, permission)
+);
+
+CREATE TRIGGER IF NOT EXISTS api_key_permission_cleanup AFTER DELETE ON api_key
+BEGIN
+    DELETE FROM api_key_permission WHERE key_id = OLD.id;
+END;
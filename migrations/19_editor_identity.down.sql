@@ -0,0 +1,2 @@
+DROP TRIGGER IF EXISTS editor_identity_cleanup;
+DROP TABLE IF EXISTS editor_identity;
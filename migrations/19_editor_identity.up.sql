@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS editor_identity (
+    issuer TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    editor_id INTEGER NOT NULL,
+    PRIMARY KEY (issuer, subject)
+);
+
+CREATE TRIGGER IF NOT EXISTS editor_identity_cleanup AFTER DELETE ON editors
+BEGIN
+    DELETE FROM editor_identity WHERE editor_id = OLD.id;
+END;
@@ -0,0 +1,2 @@
+ALTER TABLE library ADD COLUMN checksum TEXT NOT NULL DEFAULT '';
+ALTER TABLE library ADD COLUMN playable INTEGER NOT NULL DEFAULT 1;
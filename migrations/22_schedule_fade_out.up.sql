@@ -0,0 +1 @@
+ALTER TABLE schedule ADD COLUMN fade_out INTEGER NOT NULL DEFAULT 0;
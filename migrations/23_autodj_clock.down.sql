@@ -0,0 +1,5 @@
+ALTER TABLE autodj_config ADD COLUMN clock_pos INTEGER NOT NULL DEFAULT 0;
+
+UPDATE autodj_config SET clock_pos = (SELECT clock_pos FROM autodj_clock WHERE id = 1);
+
+DROP TABLE IF EXISTS autodj_clock;
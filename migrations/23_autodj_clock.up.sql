@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS autodj_clock (
+    id INTEGER PRIMARY KEY CHECK (id = 1),
+    clock_pos INTEGER NOT NULL DEFAULT 0
+);
+
+INSERT INTO autodj_clock(id, clock_pos) SELECT id, clock_pos FROM autodj_config;
+
+-- Rows created by rotation saving before any config was set.
+DELETE FROM autodj_config WHERE config = '{}';
+
+ALTER TABLE autodj_config DROP COLUMN clock_pos;
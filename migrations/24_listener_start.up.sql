@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS idx_listener_start ON listener (start);
@@ -0,0 +1 @@
+ALTER TABLE schedule ADD COLUMN created_by INTEGER NOT NULL DEFAULT 0;
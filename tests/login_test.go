@@ -37,8 +37,9 @@ func TestLoginRoot(t *testing.T) {
 
 	json := resp.JSON()
 
-	// response must be {"token" : "string"}
-	json.Object().Keys().ContainsOnly("token")
+	// response must be {"token" : "string", "refresh": "string"}
+	json.Object().Keys().ContainsOnly("token", "refresh")
+	json.Path("$.refresh").String().NotEmpty()
 
 	// extract token value as string
 	tokenString := json.Path("$.token").String().Raw()
@@ -54,8 +55,8 @@ func TestLoginRoot(t *testing.T) {
 	require.Truef(t, token.Valid, "Invalid token")
 	require.NoError(t, err, "Unrecognized error during token parsing %w", err)
 
-	// token must be {"uid": "int64", login : "string", exp: "int64"}
-	expectedKeys := []string{"uid", "login", "exp"}
+	// token must be {"jti": "string", "uid": "int64", login : "string", roles: ["string"], iat: "int64", exp: "int64"}
+	expectedKeys := []string{"jti", "uid", "login", "roles", "iat", "exp"}
 	keys := make([]string, 0, len(claims))
 	for k := range claims {
 		keys = append(keys, k)
@@ -134,8 +135,9 @@ func TestLoginEditor(t *testing.T) {
 		Status(200).
 		JSON()
 
-	// response must be {"token" : "string"}
-	json.Object().Keys().ContainsOnly("token")
+	// response must be {"token" : "string", "refresh": "string"}
+	json.Object().Keys().ContainsOnly("token", "refresh")
+	json.Path("$.refresh").String().NotEmpty()
 	tokenString := json.Path("$.token").String().Raw()
 
 	claims := jwt.MapClaims{}
@@ -149,8 +151,8 @@ func TestLoginEditor(t *testing.T) {
 	require.Truef(t, token.Valid, "Invalid token")
 	require.NoError(t, err, "Unrecognized error during token parsing %w", err)
 
-	// token must be {"uid": "int64", login : "string", exp: "int64"}
-	expectedKeys := []string{"uid", "login", "exp"}
+	// token must be {"jti": "string", "uid": "int64", login : "string", roles: ["string"], iat: "int64", exp: "int64"}
+	expectedKeys := []string{"jti", "uid", "login", "roles", "iat", "exp"}
 	keys := make([]string, 0, len(claims))
 	for k := range claims {
 		keys = append(keys, k)
@@ -173,7 +173,7 @@ func TestGetMedia(t *testing.T) {
 
 	// Check response structure
 	json.Object().Keys().ContainsOnly("media")
-	json.Path("$.media").Object().Keys().ContainsOnly("id", "name", "author", "duration", "tags")
+	json.Path("$.media").Object().Keys().ContainsOnly("id", "name", "author", "duration", "tags", "playable")
 	for _, value := range json.Path("$.media.tags").Array().Iter() {
 		value.Object().Keys().ContainsOnly("id", "name", "type")
 		value.Path("$.type").Object().Keys().ContainsOnly("id", "name")
@@ -234,7 +234,7 @@ func TestGetMediaWithoutTags(t *testing.T) {
 
 	// Check response structure
 	json.Object().Keys().ContainsOnly("media")
-	json.Path("$.media").Object().Keys().ContainsOnly("id", "name", "author", "duration", "tags")
+	json.Path("$.media").Object().Keys().ContainsOnly("id", "name", "author", "duration", "tags", "playable")
 	for _, value := range json.Path("$.media.tags").Array().Iter() {
 		value.Object().Keys().ContainsOnly("id", "name", "type")
 		value.Path("$.type").Object().Keys().ContainsOnly("id", "name")
@@ -705,7 +705,7 @@ func TestSearchMediaWithoutQuery(t *testing.T) {
 
 	json.Object().Keys().ContainsOnly("library")
 	for _, value := range json.Path("$.library").Array().Iter() {
-		value.Object().Keys().ContainsOnly("id", "name", "author", "duration", "tags")
+		value.Object().Keys().ContainsOnly("id", "name", "author", "duration", "tags", "playable")
 	}
 }
 
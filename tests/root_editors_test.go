@@ -135,7 +135,7 @@ func TestGetEditor(t *testing.T) {
 
 	// Check response
 	json.Object().Keys().ContainsOnly("editor")
-	json.Path("$.editor").Object().Keys().ContainsOnly("login", "id")
+	json.Path("$.editor").Object().Keys().ContainsOnly("login", "id", "roles", "displayName", "contact")
 	json.Path("$.editor.login").String().IsEqual(login)
 	json.Path("$.editor.id").Number().IsEqual(id)
 }